@@ -2,16 +2,28 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/lsdopen/archy/internal/credentials"
+	"github.com/lsdopen/archy/internal/health"
+	"github.com/lsdopen/archy/internal/registry"
+	"github.com/lsdopen/archy/pkg/credentials/cloud"
 	"github.com/lsdopen/archy/pkg/inspector"
+	"github.com/lsdopen/archy/pkg/types"
 	"github.com/lsdopen/archy/pkg/webhook"
+	"github.com/lsdopen/archy/pkg/webhook/certmanager"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
@@ -21,13 +33,67 @@ func main() {
 		port     string
 		certFile string
 		keyFile  string
+
+		autoTLS           bool
+		serviceName       string
+		serviceNamespace  string
+		webhookConfigName string
+		certManagerIssuer string
+
+		onRegistryError string
+		onRateLimit     string
+		onAuthError     string
+		onNotFound      string
+
+		staticDockerConfig      string
+		cloudCredentialsConfig  string
+		secretCredentialsConfig string
+
+		localRuntimeEndpoints     string
+		healthCheckCanonicalImage string
 	)
 
 	flag.StringVar(&port, "port", "8443", "Port to listen on")
 	flag.StringVar(&certFile, "tls-cert", "/etc/webhook/certs/tls.crt", "Path to TLS certificate")
 	flag.StringVar(&keyFile, "tls-key", "/etc/webhook/certs/tls.key", "Path to TLS key")
+	flag.BoolVar(&autoTLS, "auto-tls", false, "Bootstrap and rotate the serving certificate and MutatingWebhookConfiguration caBundle automatically instead of requiring tls-cert/tls-key to be pre-provisioned")
+	flag.StringVar(&serviceName, "service-name", "archy-webhook", "Name of the Service fronting this webhook (used for certificate SANs when auto-tls is set)")
+	flag.StringVar(&serviceNamespace, "service-namespace", "default", "Namespace of the Service fronting this webhook (used for certificate SANs when auto-tls is set)")
+	flag.StringVar(&webhookConfigName, "webhook-config-name", "archy-webhook", "Name of the MutatingWebhookConfiguration whose caBundle is kept in sync when auto-tls is set")
+	flag.StringVar(&certManagerIssuer, "cert-manager-issuer", "", "Name of a cert-manager.io Issuer to request the certificate from instead of self-signing (implies auto-tls)")
+	flag.StringVar(&onRegistryError, "on-registry-error", "fail-closed", "What to do when a registry can't be inspected for reasons other than auth/rate-limit/not-found: fail-open, fail-closed, or assume=<arch>")
+	flag.StringVar(&onRateLimit, "on-rate-limit", "fail-closed", "What to do when a registry responds 429 Too Many Requests: fail-open, fail-closed, or assume=<arch>")
+	flag.StringVar(&onAuthError, "on-auth-error", "fail-closed", "What to do when a registry responds 401/403, e.g. an unreachable pull secret: fail-open, fail-closed, or assume=<arch>")
+	flag.StringVar(&onNotFound, "on-not-found", "fail-closed", "What to do when a registry responds 404 for an image: fail-open, fail-closed, or assume=<arch>")
+	flag.StringVar(&staticDockerConfig, "static-docker-config", "", "Path to a docker config.json (e.g. mounted from a Secret) consulted when a pod's imagePullSecrets don't resolve a credential")
+	flag.StringVar(&cloudCredentialsConfig, "cloud-credentials-configmap", "", "\"namespace/name\" of a ConfigMap mapping registry-host glob patterns to cloud-provider workload identities (ecr, gar/gcr, acr), e.g. for ECR/GAR/ACR without imagePullSecrets")
+	flag.StringVar(&secretCredentialsConfig, "secret-credentials-configmap", "", "\"namespace/name\" of a ConfigMap mapping registry-host glob patterns to \"namespace/name\" basic-auth Secret references, for private registries outside any pod's imagePullSecrets")
+	flag.StringVar(&localRuntimeEndpoints, "local-runtime-endpoints", "", "Comma-separated unix:// socket endpoints (dockershim and/or containerd CRI) consulted before a registry round-trip, for images already pulled onto this node")
+	flag.StringVar(&healthCheckCanonicalImage, "health-check-canonical-image", "library/alpine:latest", "A small, always-available Docker Hub image /ready uses to confirm registry reachability")
 	flag.Parse()
 
+	policy := webhook.Policy{}
+	for _, f := range []struct {
+		name   string
+		value  string
+		action *webhook.PolicyAction
+	}{
+		{"on-registry-error", onRegistryError, &policy.OnRegistryError},
+		{"on-rate-limit", onRateLimit, &policy.OnRateLimit},
+		{"on-auth-error", onAuthError, &policy.OnAuthError},
+		{"on-not-found", onNotFound, &policy.OnNotFound},
+	} {
+		action, err := webhook.ParsePolicyAction(f.value)
+		if err != nil {
+			log.Fatalf("Invalid -%s: %v", f.name, err)
+		}
+		*f.action = action
+	}
+
+	if certManagerIssuer != "" {
+		autoTLS = true
+	}
+
 	// Create in-cluster Kubernetes client for accessing secrets
 	config, err := rest.InClusterConfig()
 	if err != nil {
@@ -39,26 +105,104 @@ func main() {
 		log.Fatalf("Failed to create Kubernetes client: %v", err)
 	}
 
-	inspector := inspector.NewRegistryInspector()
-	handler := webhook.NewHandler(inspector, k8sClient)
+	var certMgr *certmanager.Manager
+	if autoTLS {
+		certDir := filepath.Dir(certFile)
+
+		dynamicClient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			log.Fatalf("Failed to create dynamic Kubernetes client: %v", err)
+		}
+
+		certMgr = certmanager.NewManager(k8sClient, dynamicClient, certmanager.Config{
+			ServiceName:       serviceName,
+			Namespace:         serviceNamespace,
+			WebhookConfigName: webhookConfigName,
+			CertDir:           certDir,
+			Issuer:            certManagerIssuer,
+		})
+
+		bootstrapCtx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+		if err := certMgr.Start(bootstrapCtx); err != nil {
+			cancel()
+			log.Fatalf("Failed to bootstrap webhook TLS certificate: %v", err)
+		}
+		cancel()
+
+		certFile = filepath.Join(certDir, "tls.crt")
+		keyFile = filepath.Join(certDir, "tls.key")
+	}
+
+	credResolver := credentials.NewResolver(k8sClient)
+	if staticDockerConfig != "" {
+		credResolver.SetStaticConfigPath(staticDockerConfig)
+	}
+	if cloudCredentialsConfig != "" {
+		ns, name, err := splitNamespacedName(cloudCredentialsConfig)
+		if err != nil {
+			log.Fatalf("Invalid -cloud-credentials-configmap: %v", err)
+		}
+		matcher, err := cloud.LoadMatcherFromConfigMap(context.Background(), k8sClient, ns, name)
+		if err != nil {
+			log.Fatalf("Failed to load cloud credentials configmap: %v", err)
+		}
+		credResolver.SetCloudMatcher(matcher)
+	}
+	if secretCredentialsConfig != "" {
+		ns, name, err := splitNamespacedName(secretCredentialsConfig)
+		if err != nil {
+			log.Fatalf("Invalid -secret-credentials-configmap: %v", err)
+		}
+		provider, err := credentials.LoadSecretCredentialProviderFromConfigMap(context.Background(), k8sClient, ns, name)
+		if err != nil {
+			log.Fatalf("Failed to load secret credentials configmap: %v", err)
+		}
+		credResolver.AddProvider(provider)
+	}
+
+	img := newImageInspector(localRuntimeEndpoints)
+	handler := webhook.NewHandlerWithCredentialResolver(img, k8sClient, policy, credResolver)
+
+	checker := health.NewChecker()
+	checker.Register("kubernetes-api", health.KubernetesAPICheck(k8sClient))
+	checker.Register("tls-certificate", health.TLSCertExpiryCheck(certFile))
+	checker.Register("registry", health.RegistryReachabilityCheck(registry.NewDockerHubClient(), healthCheckCanonicalImage))
+	checker.Start()
 
 	mux := http.NewServeMux()
 	mux.Handle("/mutate", handler)
+	mux.HandleFunc("/health", checker.LivenessHandler)
+	mux.HandleFunc("/ready", checker.ReadyHandler)
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		if _, err := w.Write([]byte("ok")); err != nil {
 			log.Printf("Failed to write health check response: %v", err)
 		}
 	})
+	mux.Handle("/metrics", promhttp.HandlerFor(handler.Metrics().Registry(), promhttp.HandlerOpts{}))
+
+	// Load the certificate through a CertReloader rather than handing
+	// certFile/keyFile straight to ListenAndServeTLS: http.Server only reads
+	// those once at startup, so without this a certificate rotated by
+	// certMgr (or any other rotation mechanism writing to the same path)
+	// would never be picked up, and handshakes would start failing once the
+	// stale leaf expired.
+	certReloader, err := certmanager.NewCertReloader(certFile, keyFile)
+	if err != nil {
+		log.Fatalf("Failed to load TLS certificate: %v", err)
+	}
 
 	server := &http.Server{
 		Addr:    ":" + port,
 		Handler: mux,
+		TLSConfig: &tls.Config{
+			GetCertificate: certReloader.GetCertificate,
+		},
 	}
 
 	go func() {
 		log.Printf("Starting server on port %s...", port)
-		if err := server.ListenAndServeTLS(certFile, keyFile); err != nil && err != http.ErrServerClosed {
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Could not listen on %s: %v", port, err)
 		}
 	}()
@@ -76,5 +220,56 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	certReloader.Close()
+	checker.Stop()
+
+	if certMgr != nil {
+		certMgr.Stop()
+	}
+
 	log.Println("Server exiting")
 }
+
+// newImageInspector builds the inspector.Inspector used to discover an
+// image's supported platforms. With no endpoints configured it's a plain
+// inspector.RegistryInspector; otherwise each comma-separated endpoint in
+// rawEndpoints is chained ahead of the registry as a local runtime lookup
+// (docker or containerd CRI, per inspector.NewLocalClient), so an image
+// already pulled onto the node skips a registry round-trip entirely. An
+// endpoint that fails to construct is logged and skipped rather than
+// aborting startup, since the remote registry path still works without it.
+func newImageInspector(rawEndpoints string) inspector.Inspector {
+	registryInspector := inspector.NewRegistryInspector()
+	if rawEndpoints == "" {
+		return registryInspector
+	}
+
+	var sources []types.RegistryClient
+	for _, endpoint := range strings.Split(rawEndpoints, ",") {
+		endpoint = strings.TrimSpace(endpoint)
+		if endpoint == "" {
+			continue
+		}
+		client, err := inspector.NewLocalClient(endpoint)
+		if err != nil {
+			log.Printf("Skipping local runtime endpoint %q: %v", endpoint, err)
+			continue
+		}
+		sources = append(sources, client)
+	}
+
+	if len(sources) == 0 {
+		return registryInspector
+	}
+
+	return inspector.NewLocalFirstInspector(inspector.NewChainedClient(sources...), registryInspector)
+}
+
+// splitNamespacedName splits a "namespace/name" flag value into its parts.
+func splitNamespacedName(value string) (namespace, name string, err error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("expected \"namespace/name\", got %q", value)
+	}
+	return parts[0], parts[1], nil
+}
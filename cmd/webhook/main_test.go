@@ -2,12 +2,9 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
-	"os"
-	"syscall"
 	"testing"
 	"time"
 
@@ -92,13 +89,13 @@ func TestHealthEndpoints_UnderLoad(t *testing.T) {
 	for i := 0; i < 100; i++ {
 		go func() {
 			defer func() { done <- true }()
-			
+
 			resp, err := http.Get(baseURL + "/health")
 			if err != nil {
 				return
 			}
 			defer resp.Body.Close()
-			
+
 			assert.Equal(t, http.StatusOK, resp.StatusCode)
 		}()
 	}
@@ -200,6 +197,36 @@ func TestSignalHandling(t *testing.T) {
 	assert.NotNil(t, handleSignals)
 }
 
+func TestSplitNamespacedName(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		wantNS   string
+		wantName string
+		wantErr  bool
+	}{
+		{name: "valid", value: "kube-system/archy-cloud-creds", wantNS: "kube-system", wantName: "archy-cloud-creds"},
+		{name: "no slash", value: "archy-cloud-creds", wantErr: true},
+		{name: "empty namespace", value: "/archy-cloud-creds", wantErr: true},
+		{name: "empty name", value: "kube-system/", wantErr: true},
+		{name: "empty value", value: "", wantErr: true},
+		{name: "extra slash goes into name", value: "kube-system/archy/creds", wantNS: "kube-system", wantName: "archy/creds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns, name, err := splitNamespacedName(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNS, ns)
+			assert.Equal(t, tt.wantName, name)
+		})
+	}
+}
+
 // Mock handlers for testing
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
@@ -226,4 +253,4 @@ func recoveryMiddleware(next http.Handler) http.Handler {
 // Mock signal handling function
 var handleSignals = func(server *http.Server) {
 	// Implementation would go here
-}
\ No newline at end of file
+}
@@ -0,0 +1,42 @@
+package cache
+
+// Cache is the interface Mutator and Validator cache resolved image
+// architectures behind, so the backend (in-process, Redis, ...) can be
+// swapped without touching admission logic. All implementations must agree
+// on MemoryCache's TTL semantics: a zero or negative TTL means a value is
+// never actually observable by a later Get, not that it's cached forever.
+type Cache interface {
+	// Get returns the cached value for key and whether it was found
+	// (false on a miss or an expired entry).
+	Get(key string) ([]string, bool)
+
+	// Set stores value for key, replacing any existing entry.
+	Set(key string, value []string)
+
+	// Delete removes key, if present.
+	Delete(key string)
+
+	// Len returns the number of entries currently cached. For a backend
+	// shared across a Redis keyspace, this is a best-effort count of the keys
+	// under this cache's own KeyPrefix, unlike RedisCache.Stats, which is
+	// instance-wide (see RedisCache.Len).
+	Len() int
+
+	// Stats returns hit/miss/eviction counters for the cache.
+	Stats() CacheStats
+}
+
+// CacheStats holds cache statistics
+type CacheStats struct {
+	Hits      int
+	Misses    int
+	Evictions int
+
+	// Admissions and Rejections count MemoryCache's W-TinyLFU admission
+	// test outcomes: each key evicted from the window segment either wins
+	// admission into the main cache (Admissions) or is discarded because
+	// the sketch favors the segment it was competing against (Rejections).
+	// Always zero for backends other than MemoryCache.
+	Admissions int
+	Rejections int
+}
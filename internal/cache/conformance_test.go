@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cacheFactory builds a fresh, empty Cache with the given capacity and TTL,
+// plus an advance function that makes d worth of time pass for that cache's
+// TTL bookkeeping. The same behavioral suite below runs against every
+// factory, so each backend is held to identical semantics.
+type cacheFactory func(t *testing.T, capacity int, ttl time.Duration) (c Cache, advance func(d time.Duration))
+
+func memoryFactory(t *testing.T, capacity int, ttl time.Duration) (Cache, func(time.Duration)) {
+	return NewMemoryCache(capacity, ttl), time.Sleep
+}
+
+func redisFactory(t *testing.T, capacity int, ttl time.Duration) (Cache, func(time.Duration)) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	c, err := NewRedisCache(RedisConfig{Addr: mr.Addr(), TTL: ttl})
+	require.NoError(t, err)
+	// miniredis runs on a virtual clock: it only expires keys when told time
+	// has passed, rather than on the wall clock.
+	return c, mr.FastForward
+}
+
+func TestCache_Conformance(t *testing.T) {
+	backends := map[string]cacheFactory{
+		"memory": memoryFactory,
+		"redis":  redisFactory,
+	}
+
+	for name, factory := range backends {
+		t.Run(name, func(t *testing.T) {
+			t.Run("GetSetRoundTrip", func(t *testing.T) {
+				c, _ := factory(t, 100, time.Hour)
+				c.Set("nginx:latest", []string{"amd64", "arm64"})
+
+				value, found := c.Get("nginx:latest")
+				require.True(t, found)
+				assert.Equal(t, []string{"amd64", "arm64"}, value)
+			})
+
+			t.Run("Miss", func(t *testing.T) {
+				c, _ := factory(t, 100, time.Hour)
+
+				_, found := c.Get("missing")
+				assert.False(t, found)
+			})
+
+			t.Run("Delete", func(t *testing.T) {
+				c, _ := factory(t, 100, time.Hour)
+				c.Set("nginx:latest", []string{"amd64"})
+
+				c.Delete("nginx:latest")
+
+				_, found := c.Get("nginx:latest")
+				assert.False(t, found)
+			})
+
+			t.Run("ZeroTTLNeverObservable", func(t *testing.T) {
+				c, advance := factory(t, 100, 0)
+				c.Set("nginx:latest", []string{"amd64"})
+
+				advance(time.Millisecond)
+
+				_, found := c.Get("nginx:latest")
+				assert.False(t, found)
+			})
+
+			t.Run("NegativeTTLNeverObservable", func(t *testing.T) {
+				c, _ := factory(t, 100, -time.Hour)
+				c.Set("nginx:latest", []string{"amd64"})
+
+				_, found := c.Get("nginx:latest")
+				assert.False(t, found)
+			})
+
+			t.Run("TTLExpiration", func(t *testing.T) {
+				// Redis only expires keys at second granularity, so the TTL
+				// here (unlike MemoryCache's own sub-second tests) has to be
+				// at least a second for this assertion to hold on every
+				// backend.
+				c, advance := factory(t, 100, 1100*time.Millisecond)
+				c.Set("nginx:latest", []string{"amd64"})
+
+				value, found := c.Get("nginx:latest")
+				require.True(t, found)
+				assert.Equal(t, []string{"amd64"}, value)
+
+				advance(1300 * time.Millisecond)
+
+				_, found = c.Get("nginx:latest")
+				assert.False(t, found)
+			})
+
+			t.Run("Len", func(t *testing.T) {
+				c, _ := factory(t, 100, time.Hour)
+				assert.Equal(t, 0, c.Len())
+
+				c.Set("nginx:latest", []string{"amd64"})
+				c.Set("redis:latest", []string{"amd64"})
+				assert.Equal(t, 2, c.Len())
+
+				c.Delete("nginx:latest")
+				assert.Equal(t, 1, c.Len())
+			})
+		})
+	}
+}
+
+func TestTwoTierCache_ServesFromLocalAfterRemoteMiss(t *testing.T) {
+	mr := miniredis.RunT(t)
+	remote, err := NewRedisCache(RedisConfig{Addr: mr.Addr(), TTL: time.Hour})
+	require.NoError(t, err)
+
+	c := NewTwoTierCache(remote, 100, time.Hour)
+	c.Set("nginx:latest", []string{"amd64"})
+
+	// Populated in both tiers by Set, so even stopping miniredis shouldn't
+	// affect a lookup served from the local tier.
+	mr.Close()
+
+	value, found := c.Get("nginx:latest")
+	require.True(t, found)
+	assert.Equal(t, []string{"amd64"}, value)
+}
+
+func TestTwoTierCache_PopulatesLocalFromRemoteOnLocalMiss(t *testing.T) {
+	mr := miniredis.RunT(t)
+	remote, err := NewRedisCache(RedisConfig{Addr: mr.Addr(), TTL: time.Hour})
+	require.NoError(t, err)
+
+	// Write directly to the remote tier, simulating another replica having
+	// resolved this image first.
+	remote.Set("nginx:latest", []string{"arm64"})
+
+	c := NewTwoTierCache(remote, 100, time.Hour)
+	value, found := c.Get("nginx:latest")
+	require.True(t, found)
+	assert.Equal(t, []string{"arm64"}, value)
+
+	mr.Close()
+
+	// Now served from the local tier's copy, remote being unreachable.
+	value, found = c.Get("nginx:latest")
+	require.True(t, found)
+	assert.Equal(t, []string{"arm64"}, value)
+}
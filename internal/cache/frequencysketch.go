@@ -0,0 +1,100 @@
+package cache
+
+import "hash/maphash"
+
+// sketchDepth is the number of independent hash rows the frequency sketch
+// maintains. Four rows (TinyLFU's usual choice) keeps the false-positive
+// rate low without much memory overhead.
+const sketchDepth = 4
+
+// maxSketchCount is the ceiling each 4-bit counter saturates at, rather than
+// wrapping or growing further.
+const maxSketchCount = 15
+
+// frequencySketch is a 4-bit Count-Min Sketch approximating how often a key
+// has recently been seen, used by MemoryCache's W-TinyLFU admission policy
+// to decide whether a key evicted from the window deserves a spot in the
+// main cache over the segment's current victim. It's a probabilistic
+// estimate (collisions only ever overestimate a key's frequency, never
+// underestimate it), which is why age periodically halves counts: a
+// key that was hot an hour ago shouldn't keep permanently outscoring one
+// that's hot now. Not safe for concurrent use; callers must hold
+// MemoryCache.mu.
+type frequencySketch struct {
+	rows  [sketchDepth][]uint8
+	seeds [sketchDepth]maphash.Seed
+	width uint64
+}
+
+// newFrequencySketch builds a sketch sized for roughly capacity distinct
+// keys. width is set generously (4x capacity, rounded up to a power of two)
+// to keep the collision rate low; a sketch is cheap relative to the cached
+// values it's making eviction decisions for.
+func newFrequencySketch(capacity int) *frequencySketch {
+	width := nextPowerOfTwo(capacity * 4)
+	if width < 16 {
+		width = 16
+	}
+
+	s := &frequencySketch{width: uint64(width)}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+		s.seeds[i] = maphash.MakeSeed()
+	}
+	return s
+}
+
+// increment records one more observation of key, saturating each row's
+// counter at maxSketchCount rather than overflowing.
+func (s *frequencySketch) increment(key string) {
+	for i := range s.rows {
+		idx := s.indexFor(i, key)
+		if s.rows[i][idx] < maxSketchCount {
+			s.rows[i][idx]++
+		}
+	}
+}
+
+// estimate returns key's approximate recent frequency: the minimum across
+// all rows, since any row's count can only be inflated by a collision with
+// another key, never deflated.
+func (s *frequencySketch) estimate(key string) uint8 {
+	min := uint8(maxSketchCount)
+	for i := range s.rows {
+		if c := s.rows[i][s.indexFor(i, key)]; c < min {
+			min = c
+		}
+	}
+	return min
+}
+
+// age halves every counter, keeping the sketch biased toward recent access
+// patterns instead of ones the cache hasn't seen in a long time. MemoryCache
+// calls this every 10*capacity accesses.
+func (s *frequencySketch) age() {
+	for i := range s.rows {
+		row := s.rows[i]
+		for j := range row {
+			row[j] /= 2
+		}
+	}
+}
+
+func (s *frequencySketch) indexFor(row int, key string) uint64 {
+	var h maphash.Hash
+	h.SetSeed(s.seeds[row])
+	h.WriteString(key)
+	return h.Sum64() % s.width
+}
+
+// nextPowerOfTwo returns the smallest power of two >= n (or 1 if n <= 0).
+func nextPowerOfTwo(n int) int {
+	if n <= 0 {
+		return 1
+	}
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrequencySketch_EstimateTracksIncrements(t *testing.T) {
+	s := newFrequencySketch(100)
+
+	assert.Equal(t, uint8(0), s.estimate("nginx"))
+
+	for i := 0; i < 5; i++ {
+		s.increment("nginx")
+	}
+	assert.Equal(t, uint8(5), s.estimate("nginx"))
+
+	// An unrelated key never incremented should still read zero (barring an
+	// astronomically unlikely 4-row collision).
+	assert.Equal(t, uint8(0), s.estimate("busybox"))
+}
+
+func TestFrequencySketch_SaturatesAtMaxCount(t *testing.T) {
+	s := newFrequencySketch(100)
+
+	for i := 0; i < maxSketchCount+10; i++ {
+		s.increment("hot")
+	}
+	assert.Equal(t, uint8(maxSketchCount), s.estimate("hot"))
+}
+
+func TestFrequencySketch_AgeHalvesCounts(t *testing.T) {
+	s := newFrequencySketch(100)
+
+	for i := 0; i < 8; i++ {
+		s.increment("nginx")
+	}
+	assert.Equal(t, uint8(8), s.estimate("nginx"))
+
+	s.age()
+	assert.Equal(t, uint8(4), s.estimate("nginx"))
+
+	s.age()
+	assert.Equal(t, uint8(2), s.estimate("nginx"))
+}
+
+func TestNextPowerOfTwo(t *testing.T) {
+	cases := map[int]int{
+		-1:  1,
+		0:   1,
+		1:   1,
+		2:   2,
+		3:   4,
+		4:   4,
+		5:   8,
+		100: 128,
+	}
+	for n, want := range cases {
+		assert.Equal(t, want, nextPowerOfTwo(n), "nextPowerOfTwo(%d)", n)
+	}
+}
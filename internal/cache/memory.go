@@ -1,40 +1,123 @@
 package cache
 
 import (
+	"container/list"
 	"sync"
 	"time"
 )
 
-// CacheEntry represents a cached item with expiration
-type CacheEntry struct {
-	Value      []string
-	Expiration time.Time
-	AccessTime time.Time
+var _ Cache = (*MemoryCache)(nil)
+
+// CacheSizeRecorder observes a MemoryCache's occupancy as entries are
+// written or evicted, e.g. to feed Prometheus gauges/counters without the
+// caller having to poll Len()/Stats() on a timer. It's implemented by
+// *metrics.Metrics; MemoryCache doesn't import the metrics package
+// directly, mirroring how TwoTierCache's TierRecorder decouples it from
+// metrics.
+type CacheSizeRecorder interface {
+	RecordCacheSize(n int)
+	RecordCacheCapacity(n int)
+	RecordCacheEviction()
 }
 
-// CacheStats holds cache statistics
-type CacheStats struct {
-	Hits      int
-	Misses    int
-	Evictions int
+// cacheSegment identifies which of MemoryCache's three internal lists an
+// entry currently lives in.
+type cacheSegment int
+
+const (
+	segmentWindow cacheSegment = iota
+	segmentProbationary
+	segmentProtected
+)
+
+// cacheNode is the value stored in each list.Element, and the value every
+// items map entry points at.
+type cacheNode struct {
+	key        string
+	value      []string
+	expiration time.Time
+	segment    cacheSegment
 }
 
-// MemoryCache implements an in-memory LRU cache with TTL
+// MemoryCache implements an in-memory cache admitting and evicting entries
+// under a Window TinyLFU policy: a small LRU "window" admits every new key,
+// and a frequency sketch gates which evicted window keys are allowed to
+// displace an existing entry in the larger main cache (split into
+// probationary and protected segments). This protects frequently-reused
+// entries (e.g. a base image every pod references) from being flushed by a
+// scan through many one-off images, which a plain LRU is vulnerable to. See
+// evictLRU's predecessor in git history for the policy this replaced.
 type MemoryCache struct {
-	mu       sync.RWMutex
-	items    map[string]*CacheEntry
-	capacity int
-	ttl      time.Duration
-	stats    CacheStats
+	mu  sync.Mutex
+	ttl time.Duration
+
+	windowCap       int
+	mainCap         int
+	protectedCap    int
+	probationaryCap int
+
+	items        map[string]*list.Element
+	window       *list.List
+	probationary *list.List
+	protected    *list.List
+
+	sketch       *frequencySketch
+	accessCount  int
+	agingPeriod  int
+
+	stats   CacheStats
+	metrics CacheSizeRecorder
 }
 
 // NewMemoryCache creates a new in-memory cache
 func NewMemoryCache(capacity int, ttl time.Duration) *MemoryCache {
-	return &MemoryCache{
-		items:    make(map[string]*CacheEntry),
-		capacity: capacity,
-		ttl:      ttl,
+	return NewMemoryCacheWithMetrics(capacity, ttl, nil)
+}
+
+// NewMemoryCacheWithMetrics creates a new in-memory cache like
+// NewMemoryCache, but pushes its size, capacity and eviction count to
+// metrics as they change, rather than leaving a caller to poll Len() and
+// Stats() on a timer. metrics may be nil, in which case nothing is
+// recorded.
+func NewMemoryCacheWithMetrics(capacity int, ttl time.Duration, metrics CacheSizeRecorder) *MemoryCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	// The window holds ~1% of capacity and admits every new key
+	// unconditionally; the remainder ("main") is split 80/20 between a
+	// protected segment (entries that have been hit at least once since
+	// entering main) and a probationary one (entries that haven't, and so
+	// are the first candidates TinyLFU's admission test evicts).
+	windowCap := capacity / 100
+	if windowCap < 1 {
+		windowCap = 1
+	}
+	mainCap := capacity - windowCap
+	if mainCap < 0 {
+		mainCap = 0
+	}
+	protectedCap := mainCap * 8 / 10
+	probationaryCap := mainCap - protectedCap
+
+	c := &MemoryCache{
+		ttl:             ttl,
+		windowCap:       windowCap,
+		mainCap:         mainCap,
+		protectedCap:    protectedCap,
+		probationaryCap: probationaryCap,
+		items:           make(map[string]*list.Element),
+		window:          list.New(),
+		probationary:    list.New(),
+		protected:       list.New(),
+		sketch:          newFrequencySketch(capacity),
+		agingPeriod:     10 * capacity,
+		metrics:         metrics,
 	}
+	if c.metrics != nil {
+		c.metrics.RecordCacheCapacity(capacity)
+	}
+	return c
 }
 
 // Get retrieves a value from the cache
@@ -42,23 +125,76 @@ func (c *MemoryCache) Get(key string) ([]string, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	entry, exists := c.items[key]
+	elem, exists := c.items[key]
 	if !exists {
+		// A miss still counts toward key's frequency: a popular key that
+		// isn't currently cached (never inserted yet, or previously
+		// evicted) should still be able to win the admission test on the
+		// strength of how often it's actually requested, not just how
+		// often it happens to be resident when requested.
+		c.recordAccess(key)
 		c.stats.Misses++
 		return nil, false
 	}
+	node := elem.Value.(*cacheNode)
 
-	// Check if expired
-	if c.ttl > 0 && time.Now().After(entry.Expiration) {
-		delete(c.items, key)
+	// Check if expired. This isn't gated on c.ttl > 0: Set gives a
+	// zero/negative TTL an Expiration of "now", so it must expire here too
+	// rather than being treated as cached forever.
+	if time.Now().After(node.expiration) {
+		c.removeElement(elem)
+		c.recordAccess(key)
 		c.stats.Misses++
+		c.recordSize()
 		return nil, false
 	}
 
-	// Update access time for LRU
-	entry.AccessTime = time.Now()
 	c.stats.Hits++
-	return entry.Value, true
+	c.recordAccess(key)
+	c.touch(elem, node)
+	// touch can demote a protected entry into probationary and, if that
+	// overflows probationaryCap, evict its tail, so the entry count may
+	// have changed even on a hit.
+	c.recordSize()
+	return node.value, true
+}
+
+// touch moves elem to the front of its segment's list, promoting it out of
+// probationary into protected on its first hit since entering main (an SLRU
+// "second chance": protected is reserved for entries that have proven
+// themselves at least once, so a probationary entry doesn't need to survive
+// many hits to earn the harder-to-evict segment).
+func (c *MemoryCache) touch(elem *list.Element, node *cacheNode) {
+	switch node.segment {
+	case segmentWindow:
+		c.window.MoveToFront(elem)
+	case segmentProtected:
+		c.protected.MoveToFront(elem)
+	case segmentProbationary:
+		if c.protectedCap == 0 {
+			c.probationary.MoveToFront(elem)
+			return
+		}
+		c.probationary.Remove(elem)
+		node.segment = segmentProtected
+		c.items[node.key] = c.protected.PushFront(node)
+		c.demoteProtectedOverflow()
+	}
+}
+
+// demoteProtectedOverflow moves protected's least-recently-used entry down
+// into probationary when protected has grown past protectedCap, keeping
+// protected bounded without discarding the entry outright (it still has to
+// lose the main cache's own capacity pressure to actually leave the cache).
+func (c *MemoryCache) demoteProtectedOverflow() {
+	for c.protected.Len() > c.protectedCap {
+		back := c.protected.Back()
+		node := back.Value.(*cacheNode)
+		c.protected.Remove(back)
+		node.segment = segmentProbationary
+		c.items[node.key] = c.probationary.PushFront(node)
+	}
+	c.trimProbationary()
 }
 
 // Set stores a value in the cache
@@ -68,60 +204,159 @@ func (c *MemoryCache) Set(key string, value []string) {
 
 	now := time.Now()
 	expiration := now.Add(c.ttl)
-	
-	// Handle zero or negative TTL
 	if c.ttl <= 0 {
 		expiration = now // Immediately expired
 	}
 
-	entry := &CacheEntry{
-		Value:      value,
-		Expiration: expiration,
-		AccessTime: now,
+	if elem, exists := c.items[key]; exists {
+		node := elem.Value.(*cacheNode)
+		node.value = value
+		node.expiration = expiration
+		c.recordAccess(key)
+		c.touch(elem, node)
+		c.recordSize()
+		return
 	}
 
-	c.items[key] = entry
+	node := &cacheNode{key: key, value: value, expiration: expiration, segment: segmentWindow}
+	c.items[key] = c.window.PushFront(node)
+	c.recordAccess(key)
 
-	// Evict if over capacity
-	if len(c.items) > c.capacity {
-		c.evictLRU()
+	for c.window.Len() > c.windowCap {
+		c.admitFromWindow()
 	}
+	c.recordSize()
 }
 
-// evictLRU removes the least recently used item
-func (c *MemoryCache) evictLRU() {
-	if len(c.items) == 0 {
+// admitFromWindow evicts the window's least-recently-used entry and either
+// grants it a place in main (directly, if main still has room, or by
+// winning TinyLFU's frequency comparison against main's current victim) or
+// discards it, per the W-TinyLFU admission policy.
+func (c *MemoryCache) admitFromWindow() {
+	back := c.window.Back()
+	candidate := back.Value.(*cacheNode)
+	c.window.Remove(back)
+	delete(c.items, candidate.key)
+
+	if c.mainCap == 0 {
+		c.stats.Rejections++
+		c.recordEviction()
 		return
 	}
 
-	var oldestKey string
-	var oldestTime time.Time
-	first := true
+	if c.probationary.Len()+c.protected.Len() < c.mainCap {
+		candidate.segment = segmentProbationary
+		c.items[candidate.key] = c.probationary.PushFront(candidate)
+		c.stats.Admissions++
+		return
+	}
 
-	for key, entry := range c.items {
-		if first || entry.AccessTime.Before(oldestTime) {
-			oldestKey = key
-			oldestTime = entry.AccessTime
-			first = false
-		}
+	victimElem := c.probationary.Back()
+	if victimElem == nil {
+		victimElem = c.protected.Back()
 	}
+	victim := victimElem.Value.(*cacheNode)
 
-	if oldestKey != "" {
-		delete(c.items, oldestKey)
+	if c.sketch.estimate(candidate.key) < c.sketch.estimate(victim.key) {
+		// The incoming key isn't hot enough to displace the victim: drop it
+		// and leave main untouched.
+		c.stats.Rejections++
+		c.recordEviction()
+		return
+	}
+
+	if victim.segment == segmentProtected {
+		c.protected.Remove(victimElem)
+	} else {
+		c.probationary.Remove(victimElem)
+	}
+	delete(c.items, victim.key)
+	c.stats.Evictions++
+	c.recordEviction()
+
+	candidate.segment = segmentProbationary
+	c.items[candidate.key] = c.probationary.PushFront(candidate)
+	c.stats.Admissions++
+}
+
+// trimProbationary evicts probationary's least-recently-used entries until
+// it's back within probationaryCap, e.g. after demoteProtectedOverflow
+// pushes an entry down from protected.
+func (c *MemoryCache) trimProbationary() {
+	for c.probationary.Len() > c.probationaryCap {
+		back := c.probationary.Back()
+		node := back.Value.(*cacheNode)
+		c.probationary.Remove(back)
+		delete(c.items, node.key)
 		c.stats.Evictions++
+		c.recordEviction()
+	}
+}
+
+// recordAccess feeds the frequency sketch and ages it every agingPeriod
+// accesses, so the sketch tracks recent access patterns rather than
+// accumulating a permanent record that never forgets a key's past
+// popularity.
+func (c *MemoryCache) recordAccess(key string) {
+	c.sketch.increment(key)
+	c.accessCount++
+	if c.accessCount >= c.agingPeriod {
+		c.sketch.age()
+		c.accessCount = 0
+	}
+}
+
+// Delete removes a key from the cache, if present.
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, exists := c.items[key]; exists {
+		c.removeElement(elem)
+	}
+	c.recordSize()
+}
+
+// removeElement deletes elem from whichever segment list it belongs to and
+// from the items map. Callers must hold c.mu.
+func (c *MemoryCache) removeElement(elem *list.Element) {
+	node := elem.Value.(*cacheNode)
+	switch node.segment {
+	case segmentWindow:
+		c.window.Remove(elem)
+	case segmentProbationary:
+		c.probationary.Remove(elem)
+	case segmentProtected:
+		c.protected.Remove(elem)
+	}
+	delete(c.items, node.key)
+}
+
+// recordEviction reports an eviction to metrics, if a CacheSizeRecorder was
+// configured. Callers must hold c.mu.
+func (c *MemoryCache) recordEviction() {
+	if c.metrics != nil {
+		c.metrics.RecordCacheEviction()
+	}
+}
+
+// recordSize pushes the cache's current entry count to metrics, if a
+// CacheSizeRecorder was configured. Callers must hold c.mu.
+func (c *MemoryCache) recordSize() {
+	if c.metrics != nil {
+		c.metrics.RecordCacheSize(len(c.items))
 	}
 }
 
 // Len returns the number of items in the cache
 func (c *MemoryCache) Len() int {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return len(c.items)
 }
 
 // Stats returns cache statistics
 func (c *MemoryCache) Stats() CacheStats {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.stats
-}
\ No newline at end of file
+}
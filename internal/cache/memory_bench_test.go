@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// referenceLRUCache is a minimal plain-LRU cache kept only so
+// BenchmarkCacheHitRatio_ZipfWorkload has something to compare MemoryCache's
+// W-TinyLFU policy against; it's not wired into the Cache interface and
+// isn't meant for production use.
+type referenceLRUCache struct {
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+func newReferenceLRUCache(capacity int) *referenceLRUCache {
+	return &referenceLRUCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *referenceLRUCache) get(key string) bool {
+	elem, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	c.order.MoveToFront(elem)
+	return true
+}
+
+func (c *referenceLRUCache) set(key string) {
+	if elem, ok := c.items[key]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(key)
+	if c.order.Len() > c.capacity {
+		back := c.order.Back()
+		c.order.Remove(back)
+		delete(c.items, back.Value.(string))
+	}
+}
+
+// zipfKeys generates n keys drawn from a Zipf distribution over vocabSize
+// distinct values, the standard stand-in for "most image pulls hit a small
+// set of popular base images, with a long tail of one-offs". math/rand's
+// Zipf requires s strictly > 1.0, so 1.01 stands in for the "Zipf(1.0)"
+// workload shape this benchmark is named for.
+func zipfKeys(n, vocabSize int) []string {
+	r := rand.New(rand.NewSource(1))
+	z := rand.NewZipf(r, 1.01, 1.0, uint64(vocabSize-1))
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("image-%d", z.Uint64())
+	}
+	return keys
+}
+
+// zipfWorkloadSize and zipfVocabSize fix the workload
+// BenchmarkCacheHitRatio_ZipfWorkload replays against each policy: hit
+// ratio is a property of the access pattern, not of how many times the
+// benchmark harness decides to repeat it, so the workload is generated
+// once at a fixed size rather than scaled by b.N.
+const (
+	zipfWorkloadSize = 200000
+	zipfVocabSize    = 10000
+	zipfCacheCap     = 1000
+)
+
+// BenchmarkCacheHitRatio_ZipfWorkload compares MemoryCache's W-TinyLFU hit
+// ratio against a plain LRU of the same capacity, under a Zipf(1.0)
+// workload where a small number of keys (the "popular base images") account
+// for most of the traffic. TinyLFU's whole premise is that it should do at
+// least as well as LRU here, since it protects those hot keys from being
+// flushed by the long tail of one-off keys a pure recency-based policy is
+// vulnerable to.
+func BenchmarkCacheHitRatio_ZipfWorkload(b *testing.B) {
+	keys := zipfKeys(zipfWorkloadSize, zipfVocabSize)
+
+	b.Run("TinyLFU", func(b *testing.B) {
+		c := NewMemoryCache(zipfCacheCap, time.Hour)
+		hits := 0
+		for _, key := range keys {
+			if _, found := c.Get(key); found {
+				hits++
+			} else {
+				c.Set(key, []string{"amd64"})
+			}
+		}
+		b.ReportMetric(float64(hits)/float64(len(keys))*100, "hit-%")
+	})
+
+	b.Run("LRU", func(b *testing.B) {
+		c := newReferenceLRUCache(zipfCacheCap)
+		hits := 0
+		for _, key := range keys {
+			if c.get(key) {
+				hits++
+			} else {
+				c.set(key)
+			}
+		}
+		b.ReportMetric(float64(hits)/float64(len(keys))*100, "hit-%")
+	})
+}
@@ -277,7 +277,62 @@ func TestMemoryCache_ThreadSafety(t *testing.T) {
 	}
 	
 	wg.Wait()
-	
+
 	// Should not panic and cache should be in consistent state
 	assert.True(t, cache.Len() >= 0)
+}
+
+func TestMemoryCache_ProtectsHotKeyFromOneOffScan(t *testing.T) {
+	cache := NewMemoryCache(100, 1*time.Hour)
+
+	// nginx is repeatedly re-pulled, like a popular base image.
+	cache.Set("nginx", []string{"amd64", "arm64"})
+	for i := 0; i < 20; i++ {
+		_, found := cache.Get("nginx")
+		require.True(t, found)
+	}
+
+	// A scan of many one-off images each seen exactly once, the pattern a
+	// plain LRU is vulnerable to: it would eventually push nginx out even
+	// though nginx is the far more valuable entry to keep cached.
+	for i := 0; i < 1000; i++ {
+		cache.Set(fmt.Sprintf("one-off-image-%d", i), []string{"amd64"})
+	}
+
+	_, found := cache.Get("nginx")
+	assert.True(t, found, "hot key should survive a scan of one-off keys")
+}
+
+func TestMemoryCache_AdmissionCounters(t *testing.T) {
+	// capacity 10 gives a 1-entry window, so every Set of a brand new key
+	// immediately evicts whatever previously sat in the window, driving it
+	// through the admission test deterministically.
+	cache := NewMemoryCache(10, 1*time.Hour)
+
+	cache.Set("popular", []string{"amd64"})
+	for i := 0; i < 10; i++ {
+		cache.Get("popular")
+	}
+
+	// Push "popular" out of the window and fill main to capacity (9
+	// entries): each of these is admitted directly, since main isn't full
+	// yet, so "popular" ends up as main's oldest (and therefore next
+	// eviction victim) entry.
+	for i := 0; i < 9; i++ {
+		cache.Set(fmt.Sprintf("filler-%d", i), []string{"amd64"})
+	}
+
+	stats := cache.Stats()
+	require.True(t, stats.Admissions > 0, "expected the direct-admission path to have run at least once")
+
+	// Main is now full. The next new key pushes the previous window
+	// occupant (filler-8, seen only once) up against "popular" (seen 11
+	// times) as the admission victim; filler-8 should lose.
+	cache.Set("intruder", []string{"amd64"})
+
+	stats = cache.Stats()
+	assert.True(t, stats.Rejections > 0, "expected a low-frequency candidate to lose the admission test against a hot victim")
+
+	_, found := cache.Get("popular")
+	assert.True(t, found, "the hot victim should have survived the admission test")
 }
\ No newline at end of file
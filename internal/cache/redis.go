@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ Cache = (*RedisCache)(nil)
+
+// RedisConfig configures a RedisCache.
+type RedisConfig struct {
+	// Addr is the Redis server address ("host:port").
+	Addr string
+	// Password authenticates to Redis, if set.
+	Password string
+	// DB selects the Redis logical database.
+	DB int
+	// TTL is how long each cached entry lives. It mirrors MemoryCache's
+	// semantics: a zero or negative TTL means an entry is never actually
+	// observable by a later Get, not that it's cached forever.
+	TTL time.Duration
+	// KeyPrefix namespaces this cache's keys within a shared Redis instance,
+	// e.g. so multiple archy deployments can share one Redis without
+	// colliding.
+	KeyPrefix string
+	// TLS enables a TLS connection to Redis with default settings (e.g. a
+	// managed Redis offering terminating TLS in front of the service). It
+	// doesn't support custom CAs or mutual TLS; use a stunnel/sidecar for
+	// that instead.
+	TLS bool
+}
+
+// RedisCache is a Cache backed by a shared Redis instance, so every replica
+// of an HA webhook deployment sees the same resolved architectures instead
+// of each maintaining its own independent, cold-on-restart cache.
+type RedisCache struct {
+	client    *redis.Client
+	ttl       time.Duration
+	keyPrefix string
+}
+
+// NewRedisCache connects to the Redis instance described by cfg, pinging it
+// so connection failures surface at startup rather than on the first
+// admission request.
+func NewRedisCache(cfg RedisConfig) (*RedisCache, error) {
+	opts := &redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	}
+	if cfg.TLS {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("connecting to redis at %s: %w", cfg.Addr, err)
+	}
+
+	return &RedisCache{client: client, ttl: cfg.TTL, keyPrefix: cfg.KeyPrefix}, nil
+}
+
+func (c *RedisCache) prefixedKey(key string) string {
+	return c.keyPrefix + key
+}
+
+// Get retrieves a value from the cache.
+func (c *RedisCache) Get(key string) ([]string, bool) {
+	data, err := c.client.Get(context.Background(), c.prefixedKey(key)).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	var value []string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Set stores a value in the cache, expiring it after ttl via Redis' own
+// SETEX so the set-with-expiration is atomic from every client's view.
+func (c *RedisCache) Set(key string, value []string) {
+	if c.ttl <= 0 {
+		// Mirrors MemoryCache: a zero/negative TTL means the entry is
+		// immediately expired, so there's nothing worth writing.
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	c.client.SetEx(context.Background(), c.prefixedKey(key), data, c.ttl)
+}
+
+// Delete removes a key from the cache, if present.
+func (c *RedisCache) Delete(key string) {
+	c.client.Del(context.Background(), c.prefixedKey(key))
+}
+
+// Len returns the number of keys under KeyPrefix via a non-blocking SCAN,
+// rather than Redis' DBSIZE (which counts the whole shared keyspace and
+// can't be scoped to a prefix). It returns -1, never a partial count, if the
+// scan fails partway through (e.g. Redis becomes unreachable mid-scan), so a
+// caller like CacheHealthCheck can tell "unknown" apart from "empty". Like
+// Stats, this scales with the number of keys under KeyPrefix, so it's meant
+// for periodic health reporting, not a hot path.
+func (c *RedisCache) Len() int {
+	ctx := context.Background()
+	pattern := globEscape(c.keyPrefix) + "*"
+
+	var count int
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, pattern, 1000).Result()
+		if err != nil {
+			return -1
+		}
+		count += len(keys)
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	return count
+}
+
+// globEscape escapes Redis SCAN/KEYS glob metacharacters (*, ?, [, ], \) in s
+// so it matches only as a literal prefix, not as a pattern in its own right.
+func globEscape(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		"*", `\*`,
+		"?", `\?`,
+		"[", `\[`,
+		"]", `\]`,
+	)
+	return replacer.Replace(s)
+}
+
+// Stats returns hit/miss/eviction counters read from Redis' own INFO stats
+// section, so they reflect the whole shared Redis instance rather than just
+// what this process observed. Note this is instance-wide, not scoped to
+// KeyPrefix: if multiple deployments share one Redis instance under
+// different prefixes, each one's Stats() includes the others' traffic too.
+func (c *RedisCache) Stats() CacheStats {
+	info, err := c.client.Info(context.Background(), "stats").Result()
+	if err != nil {
+		return CacheStats{}
+	}
+
+	return CacheStats{
+		Hits:      int(parseInfoCounter(info, "keyspace_hits")),
+		Misses:    int(parseInfoCounter(info, "keyspace_misses")),
+		Evictions: int(parseInfoCounter(info, "evicted_keys")),
+	}
+}
+
+// parseInfoCounter extracts the integer value of field from a Redis INFO
+// response (CRLF-separated "field:value" lines), returning 0 if field isn't
+// present or isn't an integer.
+func parseInfoCounter(info, field string) int64 {
+	prefix := field + ":"
+	for _, line := range strings.Split(info, "\r\n") {
+		if rest, ok := strings.CutPrefix(line, prefix); ok {
+			v, _ := strconv.ParseInt(rest, 10, 64)
+			return v
+		}
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedisCache_TLSConfiguredFailsAgainstPlainServer(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	_, err := NewRedisCache(RedisConfig{Addr: mr.Addr(), TTL: time.Hour, TLS: true})
+	assert.Error(t, err)
+}
+
+func TestRedisCache_Len_ScopedToGlobSpecialKeyPrefix(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c, err := NewRedisCache(RedisConfig{Addr: mr.Addr(), TTL: time.Hour, KeyPrefix: "archy[prod]:"})
+	assert.NoError(t, err)
+
+	c.Set("nginx:latest", []string{"amd64"})
+
+	// A key written under an unrelated prefix ("archyp:something") that the
+	// unescaped glob "archy[prod]:*" would match, since "[prod]" is a
+	// character class there, to confirm KeyPrefix's glob metacharacters are
+	// matched literally rather than as a pattern.
+	other, err := NewRedisCache(RedisConfig{Addr: mr.Addr(), TTL: time.Hour, KeyPrefix: "archy"})
+	assert.NoError(t, err)
+	other.Set("p:something", []string{"arm64"})
+
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestRedisCache_Len_ReturnsNegativeOneOnScanFailure(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c, err := NewRedisCache(RedisConfig{Addr: mr.Addr(), TTL: time.Hour})
+	assert.NoError(t, err)
+
+	mr.Close()
+
+	assert.Equal(t, -1, c.Len())
+}
@@ -0,0 +1,91 @@
+package cache
+
+import "time"
+
+var _ Cache = (*TwoTierCache)(nil)
+
+// TierRecorder observes which tier of a TwoTierCache served a hit, e.g. to
+// feed a Prometheus counter labelled by tier. It's implemented by
+// *metrics.Metrics; nil is a valid TwoTierCache field and simply means no
+// tier is recorded.
+type TierRecorder interface {
+	RecordCacheTierHit(tier string)
+}
+
+// TwoTierCache fronts a remote Cache (typically a RedisCache) with a local
+// MemoryCache, so repeat lookups for the same image within a short window
+// are served from memory instead of round-tripping to the remote backend on
+// every admission request. Writes go to both tiers so other replicas still
+// observe them via the remote tier.
+type TwoTierCache struct {
+	local   *MemoryCache
+	remote  Cache
+	metrics TierRecorder
+}
+
+// NewTwoTierCache creates a TwoTierCache whose local tier holds up to
+// capacity entries for ttl before falling back to remote.
+func NewTwoTierCache(remote Cache, capacity int, ttl time.Duration) *TwoTierCache {
+	return NewTwoTierCacheWithMetrics(remote, capacity, ttl, nil)
+}
+
+// NewTwoTierCacheWithMetrics creates a TwoTierCache exactly like
+// NewTwoTierCache, but reports which tier served each hit through metrics
+// (e.g. archy_cache_tier_hits_total{tier="l1|l2"}) so operators can see how
+// much the local tier is actually saving round trips to remote. metrics may
+// be nil to record nothing.
+func NewTwoTierCacheWithMetrics(remote Cache, capacity int, ttl time.Duration, metrics TierRecorder) *TwoTierCache {
+	return &TwoTierCache{
+		local:   NewMemoryCache(capacity, ttl),
+		remote:  remote,
+		metrics: metrics,
+	}
+}
+
+// Get checks the local tier first, populating it from remote on a local
+// miss so the next lookup for key doesn't need another round trip.
+func (c *TwoTierCache) Get(key string) ([]string, bool) {
+	if value, found := c.local.Get(key); found {
+		c.recordTierHit("l1")
+		return value, true
+	}
+
+	value, found := c.remote.Get(key)
+	if found {
+		c.recordTierHit("l2")
+		c.local.Set(key, value)
+	}
+	return value, found
+}
+
+func (c *TwoTierCache) recordTierHit(tier string) {
+	if c.metrics != nil {
+		c.metrics.RecordCacheTierHit(tier)
+	}
+}
+
+// Set stores value for key in both tiers.
+func (c *TwoTierCache) Set(key string, value []string) {
+	c.local.Set(key, value)
+	c.remote.Set(key, value)
+}
+
+// Delete removes key from both tiers.
+func (c *TwoTierCache) Delete(key string) {
+	c.local.Delete(key)
+	c.remote.Delete(key)
+}
+
+// Len returns the local tier's entry count. Run a CacheHealthCheck on the
+// remote tier directly (its utilization comes from the remote's own Len) if
+// the remote tier's size matters too.
+func (c *TwoTierCache) Len() int {
+	return c.local.Len()
+}
+
+// Stats returns the local tier's counters, since that's what actually
+// governs the hit rate callers observe (a local miss always falls through
+// to remote, so local stats already account for the full request volume).
+func (c *TwoTierCache) Stats() CacheStats {
+	return c.local.Stats()
+}
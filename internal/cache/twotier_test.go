@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTierRecorder struct {
+	hits map[string]int
+}
+
+func (f *fakeTierRecorder) RecordCacheTierHit(tier string) {
+	if f.hits == nil {
+		f.hits = make(map[string]int)
+	}
+	f.hits[tier]++
+}
+
+func TestTwoTierCache_RecordsL1HitOnLocalHit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	remote, err := NewRedisCache(RedisConfig{Addr: mr.Addr(), TTL: time.Hour})
+	require.NoError(t, err)
+
+	recorder := &fakeTierRecorder{}
+	c := NewTwoTierCacheWithMetrics(remote, 100, time.Hour, recorder)
+	c.Set("nginx:latest", []string{"amd64"})
+
+	_, found := c.Get("nginx:latest")
+	require.True(t, found)
+
+	require.Equal(t, 1, recorder.hits["l1"])
+	require.Equal(t, 0, recorder.hits["l2"])
+}
+
+func TestTwoTierCache_RecordsL2HitOnRemoteHit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	remote, err := NewRedisCache(RedisConfig{Addr: mr.Addr(), TTL: time.Hour})
+	require.NoError(t, err)
+	remote.Set("nginx:latest", []string{"arm64"})
+
+	recorder := &fakeTierRecorder{}
+	c := NewTwoTierCacheWithMetrics(remote, 100, time.Hour, recorder)
+
+	_, found := c.Get("nginx:latest")
+	require.True(t, found)
+
+	require.Equal(t, 0, recorder.hits["l1"])
+	require.Equal(t, 1, recorder.hits["l2"])
+}
+
+func TestTwoTierCache_Len(t *testing.T) {
+	mr := miniredis.RunT(t)
+	remote, err := NewRedisCache(RedisConfig{Addr: mr.Addr(), TTL: time.Hour})
+	require.NoError(t, err)
+
+	c := NewTwoTierCache(remote, 100, time.Hour)
+	require.Equal(t, 0, c.Len())
+
+	c.Set("nginx:latest", []string{"amd64"})
+	require.Equal(t, 1, c.Len())
+}
@@ -4,17 +4,44 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config holds all configuration for the webhook
 type Config struct {
-	Port         int
-	TLSCertPath  string
-	TLSKeyPath   string
-	DefaultArch  string
-	LogLevel     string
-	CacheTimeout time.Duration
+	Port           int
+	TLSCertPath    string
+	TLSKeyPath     string
+	DefaultArch    string
+	LogLevel       string
+	CacheTimeout   time.Duration
+	ValidationMode string
+	AffinityMode   string
+
+	VerifyMode        string
+	CosignKeyPath     string
+	KeylessIdentities []string
+	KeylessOIDCIssuer string
+
+	MirrorDir        string
+	MirrorMaxBytes   int64
+	MirrorListenAddr string
+
+	LocalRuntimeEndpoints []string
+
+	CloudCredentialConfigMapNamespace string
+	CloudCredentialConfigMapName      string
+
+	RequiredAttestationTypes []string
+
+	CacheBackend  string
+	RedisAddr     string
+	RedisPassword string
+	RedisTLS      bool
+	CacheTwoTier  bool
+
+	HealthCheckCanonicalImage string
 }
 
 // Load reads configuration from environment variables
@@ -60,6 +87,87 @@ func Load() (*Config, error) {
 	}
 	cfg.CacheTimeout = time.Duration(cacheTimeoutSecs) * time.Second
 
+	cfg.ValidationMode = getEnvWithDefault("VALIDATION_MODE", "off")
+	switch cfg.ValidationMode {
+	case "enforce", "warn", "off":
+		// Valid
+	default:
+		return nil, fmt.Errorf("invalid VALIDATION_MODE: %s", cfg.ValidationMode)
+	}
+
+	cfg.AffinityMode = getEnvWithDefault("AFFINITY_MODE", "nodeAffinity")
+	switch cfg.AffinityMode {
+	case "nodeSelector", "nodeAffinity":
+		// Valid
+	default:
+		return nil, fmt.Errorf("invalid AFFINITY_MODE: %s", cfg.AffinityMode)
+	}
+
+	cfg.VerifyMode = getEnvWithDefault("VERIFY_MODE", "off")
+	switch cfg.VerifyMode {
+	case "enforce", "warn", "off":
+		// Valid
+	default:
+		return nil, fmt.Errorf("invalid VERIFY_MODE: %s", cfg.VerifyMode)
+	}
+
+	cfg.CosignKeyPath = os.Getenv("COSIGN_KEY_PATH")
+	cfg.KeylessOIDCIssuer = os.Getenv("KEYLESS_OIDC_ISSUER")
+	if identities := os.Getenv("KEYLESS_IDENTITIES"); identities != "" {
+		cfg.KeylessIdentities = strings.Split(identities, ",")
+	}
+
+	cfg.MirrorDir = os.Getenv("MIRROR_DIR")
+	cfg.MirrorListenAddr = getEnvWithDefault("MIRROR_LISTEN_ADDR", ":5001")
+
+	mirrorMaxBytesStr := getEnvWithDefault("MIRROR_MAX_BYTES", "1073741824")
+	mirrorMaxBytes, err := strconv.ParseInt(mirrorMaxBytesStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MIRROR_MAX_BYTES: %w", err)
+	}
+	cfg.MirrorMaxBytes = mirrorMaxBytes
+
+	if endpoints := os.Getenv("LOCAL_RUNTIME_ENDPOINTS"); endpoints != "" {
+		cfg.LocalRuntimeEndpoints = strings.Split(endpoints, ",")
+	}
+
+	cfg.CloudCredentialConfigMapNamespace = os.Getenv("CLOUD_CREDENTIAL_CONFIGMAP_NAMESPACE")
+	cfg.CloudCredentialConfigMapName = os.Getenv("CLOUD_CREDENTIAL_CONFIGMAP_NAME")
+
+	if predicateTypes := os.Getenv("REQUIRED_ATTESTATION_TYPES"); predicateTypes != "" {
+		cfg.RequiredAttestationTypes = strings.Split(predicateTypes, ",")
+	}
+
+	cfg.CacheBackend = getEnvWithDefault("CACHE_BACKEND", "memory")
+	switch cfg.CacheBackend {
+	case "memory", "redis":
+		// Valid
+	default:
+		return nil, fmt.Errorf("invalid CACHE_BACKEND: %s", cfg.CacheBackend)
+	}
+
+	cfg.RedisAddr = os.Getenv("REDIS_ADDR")
+	if cfg.CacheBackend == "redis" && cfg.RedisAddr == "" {
+		return nil, fmt.Errorf("REDIS_ADDR is required when CACHE_BACKEND=redis")
+	}
+	cfg.RedisPassword = os.Getenv("REDIS_PASSWORD")
+
+	redisTLSStr := getEnvWithDefault("REDIS_TLS", "false")
+	redisTLS, err := strconv.ParseBool(redisTLSStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_TLS: %w", err)
+	}
+	cfg.RedisTLS = redisTLS
+
+	cacheTwoTierStr := getEnvWithDefault("CACHE_TWO_TIER", "false")
+	cacheTwoTier, err := strconv.ParseBool(cacheTwoTierStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CACHE_TWO_TIER: %w", err)
+	}
+	cfg.CacheTwoTier = cacheTwoTier
+
+	cfg.HealthCheckCanonicalImage = getEnvWithDefault("HEALTH_CHECK_CANONICAL_IMAGE", "library/alpine:latest")
+
 	return cfg, nil
 }
 
@@ -0,0 +1,72 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// identityTokenUsername is the sentinel docker-credential-helper protocol
+// uses in place of a real username to signal that Secret is a bearer
+// identity token rather than a password, e.g. what docker-credential-ecr-login
+// and docker-credential-gcr return.
+const identityTokenUsername = "<token>"
+
+// HelperInvoker runs a docker-credential-<name> binary to resolve
+// credentials for a registry host, the same protocol the Docker CLI and
+// go-containerregistry's authn package use. It is an interface so tests can
+// substitute a fake instead of executing real binaries.
+type HelperInvoker interface {
+	// Invoke runs the named helper's "get" command for registryHost and
+	// returns the username/secret pair it reports.
+	Invoke(ctx context.Context, helperName, registryHost string) (username, secret string, err error)
+}
+
+// execHelperInvoker is the default HelperInvoker: it shells out to
+// docker-credential-<name>, writing registryHost to its stdin and decoding
+// the {"Username":...,"Secret":...} JSON it prints to stdout, exactly as
+// the Docker CLI does.
+type execHelperInvoker struct{}
+
+func (execHelperInvoker) Invoke(ctx context.Context, helperName, registryHost string) (string, string, error) {
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helperName, "get")
+	cmd.Stdin = strings.NewReader(registryHost)
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return "", "", fmt.Errorf("invoking docker-credential-%s: %w", helperName, err)
+	}
+
+	var resp struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return "", "", fmt.Errorf("decoding docker-credential-%s output: %w", helperName, err)
+	}
+
+	return resp.Username, resp.Secret, nil
+}
+
+// matchCredHelper returns the helper name configured for host in
+// credHelpers, checking an exact match first and then a "*.suffix"
+// wildcard entry, the same precedence the Docker CLI applies.
+func matchCredHelper(credHelpers map[string]string, host string) (string, bool) {
+	if name, ok := credHelpers[host]; ok {
+		return name, true
+	}
+
+	for pattern, name := range credHelpers {
+		suffix := strings.TrimPrefix(pattern, "*")
+		if strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, suffix) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
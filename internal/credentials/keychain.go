@@ -0,0 +1,33 @@
+package credentials
+
+import (
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// Keychain adapts a RegistryCredential (as resolved by Resolver) into an
+// authn.Keychain, so go-containerregistry-based callers (e.g.
+// pkg/inspector.RegistryInspector) can authenticate with the same
+// credentials the rest of the pipeline uses instead of maintaining their
+// own pull-secret lookup. A nil cred yields authn.Anonymous.
+func Keychain(cred *RegistryCredential) authn.Keychain {
+	if cred == nil {
+		return staticKeychain{authn.Anonymous}
+	}
+
+	if cred.IdentityToken != "" {
+		return staticKeychain{&authn.Bearer{Token: cred.IdentityToken}}
+	}
+
+	return staticKeychain{&authn.Basic{Username: cred.Username, Password: cred.Password}}
+}
+
+// staticKeychain always resolves to the same Authenticator, regardless of
+// the resource being authenticated, since Resolver already scoped cred to a
+// single registry before Keychain was called.
+type staticKeychain struct {
+	auth authn.Authenticator
+}
+
+func (k staticKeychain) Resolve(authn.Resource) (authn.Authenticator, error) {
+	return k.auth, nil
+}
@@ -0,0 +1,27 @@
+package credentials
+
+import (
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeychain_NilCredentialIsAnonymous(t *testing.T) {
+	auth, err := Keychain(nil).Resolve(nil)
+	require.NoError(t, err)
+	assert.Equal(t, authn.Anonymous, auth)
+}
+
+func TestKeychain_IdentityTokenYieldsBearer(t *testing.T) {
+	auth, err := Keychain(&RegistryCredential{IdentityToken: "tok"}).Resolve(nil)
+	require.NoError(t, err)
+	assert.Equal(t, &authn.Bearer{Token: "tok"}, auth)
+}
+
+func TestKeychain_UsernamePasswordYieldsBasic(t *testing.T) {
+	auth, err := Keychain(&RegistryCredential{Username: "user", Password: "pass"}).Resolve(nil)
+	require.NoError(t, err)
+	assert.Equal(t, &authn.Basic{Username: "user", Password: "pass"}, auth)
+}
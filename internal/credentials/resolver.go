@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -12,13 +13,32 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+
+	"github.com/lsdopen/archy/pkg/credentials/cloud"
 )
 
+// CredentialProvider resolves registry credentials independent of
+// Kubernetes Secrets or docker credential helpers, so operators can plug an
+// in-process provider (e.g. IRSA, GKE Workload Identity, Azure Managed
+// Identity) directly into Resolver's priority chain instead of writing a
+// short-lived token to a static config.json on disk.
+type CredentialProvider interface {
+	// Provide resolves a credential for registry. It returns (nil, nil)
+	// when the provider simply has nothing for this registry, so the chain
+	// falls through to the next step rather than treating a miss as an
+	// error.
+	Provide(ctx context.Context, registry string) (*RegistryCredential, error)
+}
+
 // RegistryCredential holds registry authentication information
 type RegistryCredential struct {
 	Username string
 	Password string
 	Registry string
+
+	// IdentityToken is a bearer identity token in place of Username/Password,
+	// as returned by credential helpers like docker-credential-ecr-login.
+	IdentityToken string
 }
 
 // Resolver handles credential resolution for container registries
@@ -27,6 +47,28 @@ type Resolver struct {
 	cache  map[string]*cacheEntry
 	mu     sync.RWMutex
 	ttl    time.Duration
+
+	// staticConfigPath, when set, is a docker config.json mounted into the
+	// webhook (e.g. via a Secret volume) consulted after pod and service
+	// account imagePullSecrets, for operators running cloud-provider
+	// credential helpers cluster-wide instead of per-namespace secrets.
+	staticConfigPath string
+
+	helperInvoker HelperInvoker
+	helperTimeout time.Duration
+
+	// cloudMatcher, when set, resolves a registry-pattern to a cloud-provider
+	// workload-identity Provider (ECR/GAR/ACR), consulted after the static
+	// config and cached on its own terms (see cloud.Matcher) rather than via
+	// r.cache, since a cloud token's lifetime comes from the provider's
+	// response, not r.ttl.
+	cloudMatcher *cloud.Matcher
+
+	// providers are additional in-process CredentialProviders consulted, in
+	// registration order, after cloudMatcher. Unlike cloudMatcher they carry
+	// no built-in caching of their own, so a resolved credential is cached
+	// via r.cache/r.ttl like any other step.
+	providers []CredentialProvider
 }
 
 type cacheEntry struct {
@@ -35,7 +77,9 @@ type cacheEntry struct {
 }
 
 type dockerConfig struct {
-	Auths map[string]dockerAuth `json:"auths"`
+	Auths       map[string]dockerAuth `json:"auths"`
+	CredHelpers map[string]string     `json:"credHelpers"`
+	CredsStore  string                `json:"credsStore"`
 }
 
 type dockerAuth struct {
@@ -47,19 +91,86 @@ type dockerAuth struct {
 // NewResolver creates a new credential resolver
 func NewResolver(client kubernetes.Interface) *Resolver {
 	return &Resolver{
-		client: client,
-		cache:  make(map[string]*cacheEntry),
-		ttl:    5 * time.Minute,
+		client:        client,
+		cache:         make(map[string]*cacheEntry),
+		ttl:           5 * time.Minute,
+		helperInvoker: execHelperInvoker{},
+		helperTimeout: 5 * time.Second,
 	}
 }
 
 // NewResolverWithTTL creates a resolver with custom TTL
 func NewResolverWithTTL(client kubernetes.Interface, ttl time.Duration) *Resolver {
-	return &Resolver{
-		client: client,
-		cache:  make(map[string]*cacheEntry),
-		ttl:    ttl,
-	}
+	r := NewResolver(client)
+	r.ttl = ttl
+	return r
+}
+
+// NewResolverWithHelperInvoker creates a resolver that consults docker
+// credential helpers (credHelpers/credsStore) through invoker instead of
+// the default, which shells out to docker-credential-<name> binaries. This
+// exists so tests can substitute a fake invoker.
+func NewResolverWithHelperInvoker(client kubernetes.Interface, invoker HelperInvoker) *Resolver {
+	r := NewResolver(client)
+	r.helperInvoker = invoker
+	return r
+}
+
+// NewResolverWithStaticConfig creates a resolver that also falls back to a
+// static docker config.json mounted at configPath (e.g. from a Secret or
+// ConfigMap volume) when neither the pod's nor its service account's
+// imagePullSecrets yield a credential. This lets operators configure
+// cloud-provider credential helpers (ecr-login, docker-credential-gcr, ...)
+// cluster-wide instead of per-namespace secrets.
+func NewResolverWithStaticConfig(client kubernetes.Interface, configPath string) *Resolver {
+	r := NewResolver(client)
+	r.staticConfigPath = configPath
+	return r
+}
+
+// NewResolverWithCloudProviders creates a resolver that also falls back to
+// matcher for registries matching a cloud-provider pattern (e.g.
+// "*.dkr.ecr.*.amazonaws.com") when no imagePullSecrets or static config
+// yields a credential, exchanging the pod's workload identity for a
+// short-lived token instead of requiring an operator-managed secret. See
+// cloud.LoadMatcherFromConfigMap for how matcher is typically built.
+func NewResolverWithCloudProviders(client kubernetes.Interface, matcher *cloud.Matcher) *Resolver {
+	r := NewResolver(client)
+	r.cloudMatcher = matcher
+	return r
+}
+
+// NewResolverWithProviders creates a resolver that also falls back to
+// providers, in order, when no imagePullSecrets, static config or cloud
+// workload-identity match yields a credential. This is the extension point
+// for in-process credential sources that don't fit cloud.Matcher's
+// pattern-matched Provider model.
+func NewResolverWithProviders(client kubernetes.Interface, providers ...CredentialProvider) *Resolver {
+	r := NewResolver(client)
+	r.providers = providers
+	return r
+}
+
+// AddProvider registers an additional CredentialProvider, consulted after
+// any existing providers in the order they were added.
+func (r *Resolver) AddProvider(provider CredentialProvider) {
+	r.providers = append(r.providers, provider)
+}
+
+// SetStaticConfigPath configures r to also fall back to a static docker
+// config.json at path, the same source NewResolverWithStaticConfig
+// configures at construction time. Exposed separately so callers can combine
+// it with cloud providers and/or registered CredentialProviders on a single
+// Resolver instead of being limited to one NewResolverWithX constructor.
+func (r *Resolver) SetStaticConfigPath(path string) {
+	r.staticConfigPath = path
+}
+
+// SetCloudMatcher configures r to also fall back to matcher for
+// cloud-provider workload-identity tokens, the same source
+// NewResolverWithCloudProviders configures at construction time.
+func (r *Resolver) SetCloudMatcher(matcher *cloud.Matcher) {
+	r.cloudMatcher = matcher
 }
 
 // ResolveCredentials resolves registry credentials using hybrid priority chain
@@ -84,10 +195,61 @@ func (r *Resolver) ResolveCredentials(pod *corev1.Pod, imageRef string) (*Regist
 		return cred, nil
 	}
 
-	// 3. Return nil for anonymous access
+	// 3. Try a static docker config.json mounted into the webhook
+	if r.staticConfigPath != "" {
+		if cred := r.getStaticConfigCredentials(registry); cred != nil {
+			r.setCache(cacheKey, cred)
+			return cred, nil
+		}
+	}
+
+	// 4. Try a cloud-provider workload-identity token for a known cloud
+	// registry (ECR/GAR/ACR). Deliberately not cached via r.setCache: the
+	// token is already cached inside cloudMatcher with a TTL derived from
+	// the provider's own response, not r.ttl.
+	if r.cloudMatcher != nil {
+		if cred := r.getCloudProviderCredentials(registry); cred != nil {
+			return cred, nil
+		}
+	}
+
+	// 5. Try any registered in-process CredentialProviders, in registration
+	// order.
+	if cred := r.getProviderCredentials(registry); cred != nil {
+		r.setCache(cacheKey, cred)
+		return cred, nil
+	}
+
+	// 6. Return nil for anonymous access
 	return nil, nil
 }
 
+func (r *Resolver) getProviderCredentials(registry string) *RegistryCredential {
+	for _, provider := range r.providers {
+		cred, err := provider.Provide(context.Background(), registry)
+		if err == nil && cred != nil {
+			return cred
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) getCloudProviderCredentials(registry string) *RegistryCredential {
+	token, matched, err := r.cloudMatcher.Token(context.Background(), registry)
+	if !matched || err != nil {
+		return nil
+	}
+
+	cred := &RegistryCredential{Registry: registry}
+	if token.IdentityToken != "" {
+		cred.IdentityToken = token.IdentityToken
+	} else {
+		cred.Username = token.Username
+		cred.Password = token.Password
+	}
+	return cred
+}
+
 func (r *Resolver) getPodCredentials(pod *corev1.Pod, registry string) *RegistryCredential {
 	for _, secretRef := range pod.Spec.ImagePullSecrets {
 		if cred := r.getSecretCredential(pod.Namespace, secretRef.Name, registry); cred != nil {
@@ -98,6 +260,10 @@ func (r *Resolver) getPodCredentials(pod *corev1.Pod, registry string) *Registry
 }
 
 func (r *Resolver) getServiceAccountCredentials(pod *corev1.Pod, registry string) *RegistryCredential {
+	if r.client == nil {
+		return nil
+	}
+
 	saName := pod.Spec.ServiceAccountName
 	if saName == "" {
 		saName = "default"
@@ -117,6 +283,10 @@ func (r *Resolver) getServiceAccountCredentials(pod *corev1.Pod, registry string
 }
 
 func (r *Resolver) getSecretCredential(namespace, secretName, registry string) *RegistryCredential {
+	if r.client == nil {
+		return nil
+	}
+
 	secret, err := r.client.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
 	if err != nil {
 		return nil
@@ -147,6 +317,36 @@ func (r *Resolver) parseDockerConfigSecret(secret *corev1.Secret, registry strin
 		return nil, fmt.Errorf("failed to parse docker config: %w", err)
 	}
 
+	return r.resolveFromDockerConfig(&config, registry)
+}
+
+// getStaticConfigCredentials reads and parses r.staticConfigPath the same
+// way parseDockerConfigSecret reads a Secret, returning nil on any read or
+// parse error so callers simply treat it as a miss.
+func (r *Resolver) getStaticConfigCredentials(registry string) *RegistryCredential {
+	configData, err := os.ReadFile(r.staticConfigPath)
+	if err != nil {
+		return nil
+	}
+
+	var config dockerConfig
+	if err := json.Unmarshal(configData, &config); err != nil {
+		return nil
+	}
+
+	cred, err := r.resolveFromDockerConfig(&config, registry)
+	if err != nil {
+		return nil
+	}
+	return cred
+}
+
+// resolveFromDockerConfig looks up registry in config's auths entries
+// first, the same host-matching rules parseDockerConfigSecret always used,
+// then falls through to a docker credential helper named by
+// config.CredHelpers (exact host match, then "*.suffix" wildcard) or,
+// failing that, config.CredsStore.
+func (r *Resolver) resolveFromDockerConfig(config *dockerConfig, registry string) (*RegistryCredential, error) {
 	for host, auth := range config.Auths {
 		if r.registryMatches(host, registry) {
 			cred := &RegistryCredential{
@@ -176,9 +376,56 @@ func (r *Resolver) parseDockerConfigSecret(secret *corev1.Secret, registry strin
 		}
 	}
 
+	if cred := r.credentialHelperLookup(config, registry); cred != nil {
+		return cred, nil
+	}
+
 	return nil, fmt.Errorf("no credentials found for registry %s", registry)
 }
 
+// credentialHelperLookup resolves registry via a docker-credential-<name>
+// binary, caching the result per helper/registry pair. Any non-zero exit or
+// timeout simply falls through (returns nil) rather than failing the
+// admission request, so a misconfigured or unreachable helper degrades to
+// anonymous access instead of blocking pods.
+func (r *Resolver) credentialHelperLookup(config *dockerConfig, registry string) *RegistryCredential {
+	if r.helperInvoker == nil {
+		return nil
+	}
+
+	helperName, ok := matchCredHelper(config.CredHelpers, registry)
+	if !ok {
+		helperName = config.CredsStore
+	}
+	if helperName == "" {
+		return nil
+	}
+
+	cacheKey := fmt.Sprintf("helper:%s:%s", helperName, registry)
+	if cred := r.getFromCache(cacheKey); cred != nil {
+		return cred
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.helperTimeout)
+	defer cancel()
+
+	username, secret, err := r.helperInvoker.Invoke(ctx, helperName, registry)
+	if err != nil {
+		return nil
+	}
+
+	cred := &RegistryCredential{Registry: registry}
+	if username == identityTokenUsername {
+		cred.IdentityToken = secret
+	} else {
+		cred.Username = username
+		cred.Password = secret
+	}
+
+	r.setCache(cacheKey, cred)
+	return cred
+}
+
 func (r *Resolver) registryMatches(configHost, imageRegistry string) bool {
 	// Normalize hosts
 	configHost = strings.TrimPrefix(configHost, "https://")
@@ -233,6 +480,12 @@ func (r *Resolver) setCache(key string, cred *RegistryCredential) {
 	}
 }
 
+// ExtractRegistry returns the registry hostname encoded in an image
+// reference, defaulting to "docker.io" for unqualified references.
+func ExtractRegistry(imageRef string) string {
+	return extractRegistry(imageRef)
+}
+
 func extractRegistry(imageRef string) string {
 	// Handle Docker Hub images
 	if !strings.Contains(imageRef, "/") || (!strings.Contains(imageRef, ".") && !strings.Contains(imageRef, ":")) {
@@ -245,4 +498,4 @@ func extractRegistry(imageRef string) string {
 	}
 
 	return "docker.io"
-}
\ No newline at end of file
+}
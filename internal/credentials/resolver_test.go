@@ -1,8 +1,12 @@
 package credentials
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -11,8 +15,28 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/lsdopen/archy/pkg/credentials/cloud"
 )
 
+// fakeHelperInvoker is a mockable HelperInvoker, mirroring how
+// fakeVerifiableClient lets webhook tests control an external dependency
+// without running a real binary.
+type fakeHelperInvoker struct {
+	username  string
+	secret    string
+	err       error
+	callCount int
+}
+
+func (f *fakeHelperInvoker) Invoke(ctx context.Context, helperName, registryHost string) (string, string, error) {
+	f.callCount++
+	if f.err != nil {
+		return "", "", f.err
+	}
+	return f.username, f.secret, nil
+}
+
 func TestResolver_PodImagePullSecretsExtraction(t *testing.T) {
 	client := fake.NewSimpleClientset()
 	resolver := NewResolver(client)
@@ -154,7 +178,7 @@ func TestResolver_DockerConfigJsonParsing(t *testing.T) {
 			wantPass: "pass2",
 		},
 		{
-			name: "invalid JSON",
+			name:       "invalid JSON",
 			configData: `{invalid json}`,
 			registry:   "registry.example.com",
 			wantErr:    true,
@@ -332,10 +356,10 @@ func TestResolver_MissingSecretHandling(t *testing.T) {
 
 func TestResolver_RegistryURLMatching(t *testing.T) {
 	tests := []struct {
-		name         string
-		configHost   string
-		imageRef     string
-		shouldMatch  bool
+		name        string
+		configHost  string
+		imageRef    string
+		shouldMatch bool
 	}{
 		{
 			name:        "exact match",
@@ -372,4 +396,244 @@ func TestResolver_RegistryURLMatching(t *testing.T) {
 			assert.Equal(t, tt.shouldMatch, matches)
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestResolver_CredHelperResolvesViaExactHostMatch(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	invoker := &fakeHelperInvoker{username: "AWS", secret: "ecr-token"}
+	resolver := NewResolverWithHelperInvoker(client, invoker)
+
+	config := dockerConfig{
+		CredHelpers: map[string]string{
+			"registry.example.com": "ecr-login",
+		},
+	}
+
+	cred, err := resolver.resolveFromDockerConfig(&config, "registry.example.com")
+	require.NoError(t, err)
+	require.NotNil(t, cred)
+	assert.Equal(t, "AWS", cred.Username)
+	assert.Equal(t, "ecr-token", cred.Password)
+	assert.Equal(t, 1, invoker.callCount)
+}
+
+func TestResolver_CredHelperResolvesViaWildcardSuffix(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	invoker := &fakeHelperInvoker{username: "_token", secret: "gcr-token"}
+	resolver := NewResolverWithHelperInvoker(client, invoker)
+
+	config := dockerConfig{
+		CredHelpers: map[string]string{
+			"*.gcr.io": "gcr",
+		},
+	}
+
+	cred, err := resolver.resolveFromDockerConfig(&config, "us.gcr.io")
+	require.NoError(t, err)
+	require.NotNil(t, cred)
+	assert.Equal(t, "_token", cred.Username)
+}
+
+func TestResolver_CredHelperFallsBackToCredsStore(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	invoker := &fakeHelperInvoker{username: "user", secret: "pass"}
+	resolver := NewResolverWithHelperInvoker(client, invoker)
+
+	config := dockerConfig{CredsStore: "desktop"}
+
+	cred, err := resolver.resolveFromDockerConfig(&config, "registry.example.com")
+	require.NoError(t, err)
+	require.NotNil(t, cred)
+	assert.Equal(t, "user", cred.Username)
+}
+
+func TestResolver_CredHelperIdentityTokenSentinel(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	invoker := &fakeHelperInvoker{username: "<token>", secret: "bearer-token"}
+	resolver := NewResolverWithHelperInvoker(client, invoker)
+
+	config := dockerConfig{CredsStore: "ecr-login"}
+
+	cred, err := resolver.resolveFromDockerConfig(&config, "registry.example.com")
+	require.NoError(t, err)
+	require.NotNil(t, cred)
+	assert.Empty(t, cred.Username)
+	assert.Equal(t, "bearer-token", cred.IdentityToken)
+}
+
+func TestResolver_CredHelperFailureIsTreatedAsNoCredentials(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	invoker := &fakeHelperInvoker{err: fmt.Errorf("exit status 1")}
+	resolver := NewResolverWithHelperInvoker(client, invoker)
+
+	config := dockerConfig{CredsStore: "desktop"}
+
+	cred, err := resolver.resolveFromDockerConfig(&config, "registry.example.com")
+	require.Error(t, err)
+	assert.Nil(t, cred)
+}
+
+func TestResolver_CredHelperResultIsCached(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	invoker := &fakeHelperInvoker{username: "user", secret: "pass"}
+	resolver := NewResolverWithHelperInvoker(client, invoker)
+
+	config := dockerConfig{CredsStore: "desktop"}
+
+	_, err := resolver.resolveFromDockerConfig(&config, "registry.example.com")
+	require.NoError(t, err)
+
+	_, err = resolver.resolveFromDockerConfig(&config, "registry.example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, invoker.callCount)
+}
+
+func TestResolver_StaticConfigFallbackWhenNoSecretsMatch(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	configJSON, err := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			"registry.example.com": map[string]interface{}{
+				"username": "static-user",
+				"password": "static-pass",
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(configPath, configJSON, 0o600))
+
+	resolver := NewResolverWithStaticConfig(client, configPath)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "static-pod", Namespace: "default"},
+	}
+
+	cred, err := resolver.ResolveCredentials(pod, "registry.example.com/image:tag")
+	require.NoError(t, err)
+	require.NotNil(t, cred)
+	assert.Equal(t, "static-user", cred.Username)
+	assert.Equal(t, "static-pass", cred.Password)
+}
+
+func TestResolver_StaticConfigMissingFileIsTreatedAsMiss(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	resolver := NewResolverWithStaticConfig(client, "/nonexistent/config.json")
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "static-pod", Namespace: "default"},
+	}
+
+	cred, err := resolver.ResolveCredentials(pod, "registry.example.com/image:tag")
+	assert.NoError(t, err)
+	assert.Nil(t, cred)
+}
+
+type fakeCloudProvider struct {
+	token *cloud.Token
+}
+
+func (f *fakeCloudProvider) Name() string { return "fake" }
+
+func (f *fakeCloudProvider) GetToken(ctx context.Context, registry string) (*cloud.Token, error) {
+	return f.token, nil
+}
+
+func TestResolver_CloudProviderFallbackWhenNoSecretsMatch(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	matcher := cloud.NewMatcher()
+	matcher.Register("*.dkr.ecr.*.amazonaws.com", &fakeCloudProvider{
+		token: &cloud.Token{Username: "AWS", Password: "ecr-token", ExpiresAt: time.Now().Add(time.Hour)},
+	})
+
+	resolver := NewResolverWithCloudProviders(client, matcher)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "cloud-pod", Namespace: "default"},
+	}
+
+	cred, err := resolver.ResolveCredentials(pod, "123456789.dkr.ecr.us-east-1.amazonaws.com/image:tag")
+	require.NoError(t, err)
+	require.NotNil(t, cred)
+	assert.Equal(t, "AWS", cred.Username)
+	assert.Equal(t, "ecr-token", cred.Password)
+}
+
+func TestResolver_CloudProviderNoMatchFallsThroughToAnonymous(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	matcher := cloud.NewMatcher()
+	matcher.Register("*.azurecr.io", &fakeCloudProvider{
+		token: &cloud.Token{Username: "00000000-0000-0000-0000-000000000000", Password: "acr-token", ExpiresAt: time.Now().Add(time.Hour)},
+	})
+
+	resolver := NewResolverWithCloudProviders(client, matcher)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "cloud-pod", Namespace: "default"},
+	}
+
+	cred, err := resolver.ResolveCredentials(pod, "registry.example.com/image:tag")
+	assert.NoError(t, err)
+	assert.Nil(t, cred)
+}
+
+type fakeCredentialProvider struct {
+	cred *RegistryCredential
+	err  error
+}
+
+func (f *fakeCredentialProvider) Provide(ctx context.Context, registry string) (*RegistryCredential, error) {
+	return f.cred, f.err
+}
+
+func TestResolver_ProviderFallbackWhenNoOtherSourceMatches(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	resolver := NewResolverWithProviders(client, &fakeCredentialProvider{
+		cred: &RegistryCredential{Username: "irsa", IdentityToken: "irsa-token"},
+	})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "provider-pod", Namespace: "default"},
+	}
+
+	cred, err := resolver.ResolveCredentials(pod, "registry.example.com/image:tag")
+	require.NoError(t, err)
+	require.NotNil(t, cred)
+	assert.Equal(t, "irsa-token", cred.IdentityToken)
+}
+
+func TestResolver_ProvidersConsultedInRegistrationOrder(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	resolver := NewResolverWithProviders(client, &fakeCredentialProvider{cred: nil})
+	resolver.AddProvider(&fakeCredentialProvider{
+		cred: &RegistryCredential{Username: "second", Password: "pass"},
+	})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "provider-pod", Namespace: "default"},
+	}
+
+	cred, err := resolver.ResolveCredentials(pod, "registry.example.com/image:tag")
+	require.NoError(t, err)
+	require.NotNil(t, cred)
+	assert.Equal(t, "second", cred.Username)
+}
+
+func TestResolver_ProviderNoMatchFallsThroughToAnonymous(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	resolver := NewResolverWithProviders(client, &fakeCredentialProvider{cred: nil})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "provider-pod", Namespace: "default"},
+	}
+
+	cred, err := resolver.ResolveCredentials(pod, "registry.example.com/image:tag")
+	assert.NoError(t, err)
+	assert.Nil(t, cred)
+}
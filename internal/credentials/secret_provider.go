@@ -0,0 +1,132 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SecretRef names a Kubernetes Secret of type "kubernetes.io/basic-auth"
+// (keys "username"/"password") holding static credentials for a registry.
+type SecretRef struct {
+	Namespace string
+	Name      string
+}
+
+// SecretCredentialProvider is a CredentialProvider that resolves static
+// basic-auth credentials from an operator-configured Secret, keyed by a
+// glob-style registry-host pattern (e.g. "registry.internal.example.com",
+// "*.corp.example.com") rather than anything derived from the pod. This is
+// the extension point for private/self-hosted registries that aren't
+// reachable through a pod's or service account's imagePullSecrets and don't
+// have a cloud.Provider (see pkg/credentials/cloud).
+type SecretCredentialProvider struct {
+	client kubernetes.Interface
+
+	mu       sync.Mutex
+	patterns []secretPattern
+}
+
+type secretPattern struct {
+	pattern *regexp.Regexp
+	ref     SecretRef
+}
+
+// NewSecretCredentialProvider returns an empty SecretCredentialProvider;
+// call Register to add registry-pattern/Secret mappings.
+func NewSecretCredentialProvider(client kubernetes.Interface) *SecretCredentialProvider {
+	return &SecretCredentialProvider{client: client}
+}
+
+// Register associates pattern with ref. Patterns are matched in registration
+// order, first match wins, mirroring cloud.Matcher's registration semantics.
+func (p *SecretCredentialProvider) Register(pattern string, ref SecretRef) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.patterns = append(p.patterns, secretPattern{pattern: globToRegexp(pattern), ref: ref})
+}
+
+// Provide implements CredentialProvider by fetching the Secret registered
+// for the first pattern matching registry and reading its basic-auth keys.
+func (p *SecretCredentialProvider) Provide(ctx context.Context, registry string) (*RegistryCredential, error) {
+	ref, ok := p.refFor(registry)
+	if !ok {
+		return nil, nil
+	}
+
+	secret, err := p.client.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("fetching credential secret %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+
+	username := string(secret.Data[corev1.BasicAuthUsernameKey])
+	password := string(secret.Data[corev1.BasicAuthPasswordKey])
+	if username == "" && password == "" {
+		return nil, fmt.Errorf("secret %s/%s has no %s/%s data", ref.Namespace, ref.Name, corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey)
+	}
+
+	return &RegistryCredential{Username: username, Password: password, Registry: registry}, nil
+}
+
+func (p *SecretCredentialProvider) refFor(registry string) (SecretRef, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, sp := range p.patterns {
+		if sp.pattern.MatchString(registry) {
+			return sp.ref, true
+		}
+	}
+	return SecretRef{}, false
+}
+
+// LoadSecretCredentialProviderFromConfigMap builds a SecretCredentialProvider
+// from a ConfigMap whose Data maps a registry-host glob pattern to a
+// "namespace/name" Secret reference, e.g.:
+//
+//	data:
+//	  "registry.internal.example.com": "archy-system/internal-registry-creds"
+//	  "*.corp.example.com": "archy-system/corp-registry-creds"
+//
+// This is the operator-facing configuration surface in lieu of a dedicated
+// CRD, mirroring cloud.LoadMatcherFromConfigMap.
+func LoadSecretCredentialProviderFromConfigMap(ctx context.Context, client kubernetes.Interface, namespace, name string) (*SecretCredentialProvider, error) {
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("loading secret credential configmap %s/%s: %w", namespace, name, err)
+	}
+
+	provider := NewSecretCredentialProvider(client)
+	for pattern, ref := range cm.Data {
+		secretRef, err := parseSecretRef(strings.TrimSpace(ref))
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+		provider.Register(pattern, secretRef)
+	}
+
+	return provider, nil
+}
+
+func parseSecretRef(ref string) (SecretRef, error) {
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return SecretRef{}, fmt.Errorf("secret reference %q must be \"namespace/name\"", ref)
+	}
+	return SecretRef{Namespace: parts[0], Name: parts[1]}, nil
+}
+
+// globToRegexp compiles pattern, a registry-host glob where "*" matches any
+// run of characters (including none), into an anchored regexp, matching
+// cloud.Matcher's pattern syntax.
+func globToRegexp(pattern string) *regexp.Regexp {
+	escaped := regexp.QuoteMeta(pattern)
+	escaped = strings.ReplaceAll(escaped, `\*`, `.*`)
+	return regexp.MustCompile("^" + escaped + "$")
+}
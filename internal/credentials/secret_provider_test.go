@@ -0,0 +1,109 @@
+package credentials
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSecretCredentialProvider_MatchesWildcardPattern(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "corp-registry-creds", Namespace: "archy-system"},
+		Data: map[string][]byte{
+			corev1.BasicAuthUsernameKey: []byte("alice"),
+			corev1.BasicAuthPasswordKey: []byte("s3cr3t"),
+		},
+	})
+
+	provider := NewSecretCredentialProvider(client)
+	provider.Register("*.corp.example.com", SecretRef{Namespace: "archy-system", Name: "corp-registry-creds"})
+
+	cred, err := provider.Provide(context.Background(), "registry.corp.example.com")
+	require.NoError(t, err)
+	require.NotNil(t, cred)
+	assert.Equal(t, "alice", cred.Username)
+	assert.Equal(t, "s3cr3t", cred.Password)
+}
+
+func TestSecretCredentialProvider_NoMatchReturnsNilWithoutError(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	provider := NewSecretCredentialProvider(client)
+	provider.Register("*.corp.example.com", SecretRef{Namespace: "archy-system", Name: "corp-registry-creds"})
+
+	cred, err := provider.Provide(context.Background(), "gcr.io")
+	require.NoError(t, err)
+	assert.Nil(t, cred)
+}
+
+func TestSecretCredentialProvider_MissingSecretReturnsError(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	provider := NewSecretCredentialProvider(client)
+	provider.Register("registry.internal.example.com", SecretRef{Namespace: "archy-system", Name: "missing"})
+
+	_, err := provider.Provide(context.Background(), "registry.internal.example.com")
+	assert.Error(t, err)
+}
+
+func TestLoadSecretCredentialProviderFromConfigMap(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-auth", Namespace: "archy-system"},
+			Data: map[string]string{
+				"*.corp.example.com": "archy-system/corp-registry-creds",
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "corp-registry-creds", Namespace: "archy-system"},
+			Data: map[string][]byte{
+				corev1.BasicAuthUsernameKey: []byte("alice"),
+				corev1.BasicAuthPasswordKey: []byte("s3cr3t"),
+			},
+		},
+	)
+
+	provider, err := LoadSecretCredentialProviderFromConfigMap(context.Background(), client, "archy-system", "registry-auth")
+	require.NoError(t, err)
+
+	cred, err := provider.Provide(context.Background(), "registry.corp.example.com")
+	require.NoError(t, err)
+	require.NotNil(t, cred)
+	assert.Equal(t, "alice", cred.Username)
+}
+
+func TestLoadSecretCredentialProviderFromConfigMap_InvalidSecretRef(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-auth", Namespace: "archy-system"},
+		Data: map[string]string{
+			"*.corp.example.com": "not-a-namespaced-name",
+		},
+	})
+
+	_, err := LoadSecretCredentialProviderFromConfigMap(context.Background(), client, "archy-system", "registry-auth")
+	assert.Error(t, err)
+}
+
+func TestResolver_SecretProviderIntegratesViaAddProvider(t *testing.T) {
+	k8sClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "corp-registry-creds", Namespace: "archy-system"},
+		Data: map[string][]byte{
+			corev1.BasicAuthUsernameKey: []byte("alice"),
+			corev1.BasicAuthPasswordKey: []byte("s3cr3t"),
+		},
+	})
+
+	provider := NewSecretCredentialProvider(k8sClient)
+	provider.Register("registry.corp.example.com", SecretRef{Namespace: "archy-system", Name: "corp-registry-creds"})
+
+	resolver := NewResolverWithProviders(fake.NewSimpleClientset(), provider)
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+	cred, err := resolver.ResolveCredentials(pod, "registry.corp.example.com/app:latest")
+	require.NoError(t, err)
+	require.NotNil(t, cred)
+	assert.Equal(t, "alice", cred.Username)
+}
@@ -0,0 +1,110 @@
+package health
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/lsdopen/archy/internal/cache"
+	"github.com/lsdopen/archy/pkg/types"
+)
+
+// minCertValidity is how much longer a TLS certificate must remain valid
+// for TLSCertExpiryCheck to report healthy, so an operator has a week's
+// warning before a rotation failure actually breaks the webhook.
+const minCertValidity = 7 * 24 * time.Hour
+
+// KubernetesAPICheck reports whether the Kubernetes API is reachable, by
+// listing a single Namespace (the same call path Mutator's namespace fail
+// policy lookup and Validator's node informer already depend on).
+func KubernetesAPICheck(kubeClient kubernetes.Interface) CheckFunc {
+	return func(ctx context.Context) (any, error) {
+		if _, err := kubeClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{Limit: 1}); err != nil {
+			return nil, fmt.Errorf("kubernetes API unreachable: %w", err)
+		}
+		return nil, nil
+	}
+}
+
+// TLSCertExpiryCheck reports the serving certificate at certFile's
+// remaining validity, failing once it's under minCertValidity so an
+// operator has advance warning of a rotation failure instead of finding
+// out when the webhook starts rejecting TLS handshakes.
+func TLSCertExpiryCheck(certFile string) CheckFunc {
+	return func(ctx context.Context) (any, error) {
+		certPEM, err := os.ReadFile(certFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading TLS certificate: %w", err)
+		}
+
+		block, _ := pem.Decode(certPEM)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM block found in %s", certFile)
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing TLS certificate: %w", err)
+		}
+
+		remaining := time.Until(cert.NotAfter)
+		details := map[string]any{
+			"notAfter":  cert.NotAfter,
+			"remaining": remaining.Round(time.Second).String(),
+		}
+		if remaining < minCertValidity {
+			return details, fmt.Errorf("TLS certificate expires in %s, under the %s warning threshold", remaining.Round(time.Minute), minCertValidity)
+		}
+		return details, nil
+	}
+}
+
+// RegistryReachabilityCheck resolves canonicalImage's supported
+// architectures through client, failing if the registry can't be reached.
+// canonicalImage should be a small, always-available image (e.g.
+// "library/alpine:latest") so the check exercises the real lookup path
+// Mutator/Validator depend on without pulling anything unusual.
+func RegistryReachabilityCheck(client types.RegistryClient, canonicalImage string) CheckFunc {
+	return func(ctx context.Context) (any, error) {
+		archs, err := client.GetSupportedArchitectures(ctx, canonicalImage)
+		if err != nil {
+			return nil, fmt.Errorf("registry unreachable for %s: %w", canonicalImage, err)
+		}
+		return map[string]any{"image": canonicalImage, "architectures": archs}, nil
+	}
+}
+
+// CacheHealthCheck reports c's miss rate and, when capacity > 0, its
+// utilization against capacity. It never fails: a high miss rate or full
+// cache is informational, not an outage. Len() returning -1 (a backend that
+// couldn't size itself, e.g. RedisCache mid-outage) omits utilization rather
+// than reporting a meaningless negative value.
+func CacheHealthCheck(c cache.Cache, capacity int) CheckFunc {
+	return func(ctx context.Context) (any, error) {
+		stats := c.Stats()
+
+		var missRate float64
+		if total := stats.Hits + stats.Misses; total > 0 {
+			missRate = float64(stats.Misses) / float64(total)
+		}
+
+		details := map[string]any{
+			"hits":      stats.Hits,
+			"misses":    stats.Misses,
+			"evictions": stats.Evictions,
+			"missRate":  missRate,
+		}
+
+		if length := c.Len(); capacity > 0 && length >= 0 {
+			details["utilization"] = float64(length) / float64(capacity)
+		}
+
+		return details, nil
+	}
+}
@@ -0,0 +1,134 @@
+package health
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/lsdopen/archy/internal/cache"
+)
+
+// writeTestCert PEM-encodes a minimal self-signed certificate valid for
+// notAfter to a temp file, mirroring the repo's existing
+// writeCosignKeyFile test helper shape.
+func writeTestCert(t *testing.T, notAfter time.Time) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "archy-webhook"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	f, err := os.CreateTemp(t.TempDir(), "cert-*.pem")
+	require.NoError(t, err)
+	defer f.Close()
+
+	require.NoError(t, pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	return f.Name()
+}
+
+func TestKubernetesAPICheck(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	check := KubernetesAPICheck(client)
+
+	_, err := check(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestTLSCertExpiryCheck_HealthyWellBeforeExpiry(t *testing.T) {
+	certFile := writeTestCert(t, time.Now().Add(30*24*time.Hour))
+	check := TLSCertExpiryCheck(certFile)
+
+	_, err := check(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestTLSCertExpiryCheck_FailsUnderWarningThreshold(t *testing.T) {
+	certFile := writeTestCert(t, time.Now().Add(24*time.Hour))
+	check := TLSCertExpiryCheck(certFile)
+
+	_, err := check(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expires in")
+}
+
+func TestTLSCertExpiryCheck_MissingFile(t *testing.T) {
+	check := TLSCertExpiryCheck("/nonexistent/cert.pem")
+
+	_, err := check(context.Background())
+	assert.Error(t, err)
+}
+
+type fakeRegistryClient struct {
+	archs []string
+	err   error
+}
+
+func (f *fakeRegistryClient) GetSupportedArchitectures(ctx context.Context, image string) ([]string, error) {
+	return f.archs, f.err
+}
+
+func TestRegistryReachabilityCheck_Success(t *testing.T) {
+	check := RegistryReachabilityCheck(&fakeRegistryClient{archs: []string{"amd64", "arm64"}}, "library/alpine:latest")
+
+	details, err := check(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"amd64", "arm64"}, details.(map[string]any)["architectures"])
+}
+
+func TestRegistryReachabilityCheck_Failure(t *testing.T) {
+	check := RegistryReachabilityCheck(&fakeRegistryClient{err: errors.New("unreachable")}, "library/alpine:latest")
+
+	_, err := check(context.Background())
+	assert.Error(t, err)
+}
+
+func TestCacheHealthCheck_ReportsMissRateAndUtilization(t *testing.T) {
+	c := cache.NewMemoryCache(10, time.Hour)
+	c.Set("a", []string{"amd64"})
+	c.Get("a")        // hit
+	c.Get("missing")  // miss
+
+	check := CacheHealthCheck(c, 10)
+	details, err := check(context.Background())
+	require.NoError(t, err)
+
+	d := details.(map[string]any)
+	assert.Equal(t, 1, d["hits"])
+	assert.Equal(t, 1, d["misses"])
+	assert.InDelta(t, 0.5, d["missRate"], 0.001)
+	assert.InDelta(t, 0.1, d["utilization"], 0.001)
+}
+
+func TestCacheHealthCheck_NeverFails(t *testing.T) {
+	c := cache.NewMemoryCache(1, time.Hour)
+	for i := 0; i < 10; i++ {
+		c.Get(fmt.Sprintf("miss-%d", i))
+	}
+
+	check := CacheHealthCheck(c, 1)
+	_, err := check(context.Background())
+	assert.NoError(t, err)
+}
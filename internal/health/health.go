@@ -0,0 +1,265 @@
+// Package health runs a registry of named checks in the background on
+// independent schedules and serves their most recently cached results as
+// JSON, so an HTTP health probe never blocks on an expensive check (a
+// Kubernetes API call, a registry round-trip) itself.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultInterval is how often a registered check re-runs when no
+// WithInterval option overrides it.
+const defaultInterval = 30 * time.Second
+
+// defaultTimeout bounds how long a single check run is allowed to take, so
+// a hung dependency (an unreachable registry, a stalled Kubernetes API)
+// can't wedge that check's goroutine forever.
+const defaultTimeout = 10 * time.Second
+
+// Status is a check's last-run outcome.
+type Status string
+
+const (
+	// StatusPass means the check's most recent run succeeded.
+	StatusPass Status = "PASS"
+	// StatusFail means the check's most recent run returned an error, or
+	// it hasn't run yet.
+	StatusFail Status = "FAIL"
+)
+
+// CheckFunc performs a single health check, returning details to surface in
+// the JSON response (e.g. a TLS certificate's expiry, a cache's hit rate)
+// alongside a non-nil error if the check failed.
+type CheckFunc func(ctx context.Context) (details any, err error)
+
+// Result is a check's cached outcome, as surfaced in the JSON response.
+type Result struct {
+	Status    Status    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+	Details   any       `json:"details,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// config holds a registered check's scheduling parameters.
+type config struct {
+	interval     time.Duration
+	initialDelay time.Duration
+}
+
+// Option configures a registered check's scheduling.
+type Option func(*config)
+
+// WithInterval overrides how often a check re-runs (default 30s).
+func WithInterval(d time.Duration) Option {
+	return func(c *config) { c.interval = d }
+}
+
+// WithInitialDelay delays a check's first run by d, e.g. to let a
+// dependency (an informer, a credential resolver) finish warming up before
+// it's first probed.
+func WithInitialDelay(d time.Duration) Option {
+	return func(c *config) { c.initialDelay = d }
+}
+
+// check pairs a registered CheckFunc with its schedule and most recently
+// cached Result.
+type check struct {
+	fn  CheckFunc
+	cfg config
+
+	mu     sync.RWMutex
+	result Result
+}
+
+func (c *check) execute() {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	details, err := c.fn(ctx)
+
+	result := Result{Timestamp: time.Now(), Details: details}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	} else {
+		result.Status = StatusPass
+	}
+
+	c.mu.Lock()
+	c.result = result
+	c.mu.Unlock()
+}
+
+func (c *check) cachedResult() Result {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.result
+}
+
+// Checker runs a registry of named checks in the background and serves
+// their cached results over HTTP: ReadyHandler aggregates every registered
+// check (so readiness reflects real dependencies), while LivenessHandler
+// reports only whether the checker's own goroutines are running, since a
+// liveness probe shouldn't fail because a downstream dependency is down.
+type Checker struct {
+	mu     sync.RWMutex
+	checks map[string]*check
+
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	started bool
+}
+
+// NewChecker creates an empty Checker. Register checks on it, then call
+// Start to begin running them in the background.
+func NewChecker() *Checker {
+	return &Checker{
+		checks: make(map[string]*check),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Register adds a named check. It has no effect on checks already running;
+// call Register for every check before Start. Operators can add their own
+// checks (e.g. a custom dependency probe) the same way the built-in ones in
+// cmd/webhook are registered.
+func (c *Checker) Register(name string, fn CheckFunc, opts ...Option) {
+	cfg := config{interval: defaultInterval}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = &check{fn: fn, cfg: cfg}
+}
+
+// Start begins running every registered check on its own schedule in a
+// background goroutine, until Stop is called.
+func (c *Checker) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.started {
+		return
+	}
+	c.started = true
+
+	for _, ch := range c.checks {
+		c.wg.Add(1)
+		go c.run(ch)
+	}
+}
+
+func (c *Checker) run(ch *check) {
+	defer c.wg.Done()
+
+	if ch.cfg.initialDelay > 0 {
+		select {
+		case <-time.After(ch.cfg.initialDelay):
+		case <-c.stop:
+			return
+		}
+	}
+
+	ch.execute()
+
+	ticker := time.NewTicker(ch.cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			ch.execute()
+		}
+	}
+}
+
+// Stop halts every check's background goroutine and waits for them to
+// exit.
+func (c *Checker) Stop() {
+	close(c.stop)
+	c.wg.Wait()
+}
+
+// report is the JSON shape served by both handlers.
+type report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// snapshot aggregates the cached results of every check in checkNames
+// (every registered check if checkNames is nil). Status is "OK" only if
+// every included check's cached result is StatusPass; a check with no
+// cached result yet (hasn't run) counts as failed, since there's nothing
+// to trust.
+func (c *Checker) snapshot(checkNames map[string]bool) report {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	checks := make(map[string]Result, len(c.checks))
+	ok := true
+	for name, ch := range c.checks {
+		if checkNames != nil && !checkNames[name] {
+			continue
+		}
+
+		result := ch.cachedResult()
+		if result.Timestamp.IsZero() {
+			result.Status = StatusFail
+		}
+		if result.Status != StatusPass {
+			ok = false
+		}
+		checks[name] = result
+	}
+
+	status := "OK"
+	if !ok {
+		status = "FAILED"
+	}
+	return report{Status: status, Checks: checks}
+}
+
+// ReadyHandler serves the aggregated cached result of every registered
+// check as JSON, responding 200 when all pass and 503 otherwise.
+func (c *Checker) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, c.snapshot(nil))
+}
+
+// LivenessHandler reports whether the checker's background loop is
+// running. It never depends on a registered check's result, since a
+// liveness probe failing a downstream dependency (a registry, the
+// Kubernetes API) would cause Kubernetes to restart a pod that can't fix
+// the outage by restarting.
+func (c *Checker) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	running := c.started
+	c.mu.RUnlock()
+	select {
+	case <-c.stop:
+		running = false
+	default:
+	}
+
+	status := "OK"
+	if !running {
+		status = "FAILED"
+	}
+	writeReport(w, report{Status: status, Checks: map[string]Result{}})
+}
+
+func writeReport(w http.ResponseWriter, rep report) {
+	w.Header().Set("Content-Type", "application/json")
+	if rep.Status != "OK" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(rep)
+}
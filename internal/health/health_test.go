@@ -0,0 +1,144 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_ReadyHandler_AllPassingIsOK(t *testing.T) {
+	c := NewChecker()
+	c.Register("always-ok", func(ctx context.Context) (any, error) {
+		return map[string]string{"foo": "bar"}, nil
+	}, WithInterval(time.Hour))
+	c.Start()
+	defer c.Stop()
+
+	waitForResult(t, c, "always-ok")
+
+	rec := httptest.NewRecorder()
+	c.ReadyHandler(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var got report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "OK", got.Status)
+	assert.Equal(t, StatusPass, got.Checks["always-ok"].Status)
+}
+
+func TestChecker_ReadyHandler_OneFailureIsFailed(t *testing.T) {
+	c := NewChecker()
+	c.Register("ok", func(ctx context.Context) (any, error) { return nil, nil }, WithInterval(time.Hour))
+	c.Register("broken", func(ctx context.Context) (any, error) {
+		return nil, errors.New("boom")
+	}, WithInterval(time.Hour))
+	c.Start()
+	defer c.Stop()
+
+	waitForResult(t, c, "ok")
+	waitForResult(t, c, "broken")
+
+	rec := httptest.NewRecorder()
+	c.ReadyHandler(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var got report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "FAILED", got.Status)
+	assert.Equal(t, StatusFail, got.Checks["broken"].Status)
+	assert.Equal(t, "boom", got.Checks["broken"].Error)
+	assert.Equal(t, StatusPass, got.Checks["ok"].Status)
+}
+
+func TestChecker_ReadyHandler_UnranCheckCountsAsFailed(t *testing.T) {
+	c := NewChecker()
+	c.Register("slow-to-start", func(ctx context.Context) (any, error) { return nil, nil },
+		WithInitialDelay(time.Hour), WithInterval(time.Hour))
+	c.Start()
+	defer c.Stop()
+
+	rec := httptest.NewRecorder()
+	c.ReadyHandler(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var got report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "FAILED", got.Status)
+}
+
+func TestChecker_LivenessHandler_IgnoresCheckFailures(t *testing.T) {
+	c := NewChecker()
+	c.Register("broken", func(ctx context.Context) (any, error) {
+		return nil, errors.New("boom")
+	}, WithInterval(time.Hour))
+	c.Start()
+	defer c.Stop()
+
+	waitForResult(t, c, "broken")
+
+	rec := httptest.NewRecorder()
+	c.LivenessHandler(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var got report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "OK", got.Status)
+}
+
+func TestChecker_RunsPeriodically(t *testing.T) {
+	var calls int32
+	c := NewChecker()
+	c.Register("ticking", func(ctx context.Context) (any, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, nil
+	}, WithInterval(10*time.Millisecond))
+	c.Start()
+	defer c.Stop()
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&calls) >= 3
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestChecker_StopHaltsBackgroundRuns(t *testing.T) {
+	var calls int32
+	c := NewChecker()
+	c.Register("ticking", func(ctx context.Context) (any, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, nil
+	}, WithInterval(5*time.Millisecond))
+	c.Start()
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&calls) >= 1 }, time.Second, time.Millisecond)
+	c.Stop()
+
+	afterStop := atomic.LoadInt32(&calls)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, afterStop, atomic.LoadInt32(&calls))
+}
+
+// waitForResult blocks until name's check has run at least once.
+func waitForResult(t *testing.T, c *Checker, name string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		c.mu.RLock()
+		ch, ok := c.checks[name]
+		c.mu.RUnlock()
+		if !ok {
+			return false
+		}
+		return !ch.cachedResult().Timestamp.IsZero()
+	}, time.Second, time.Millisecond)
+}
@@ -3,11 +3,27 @@ package metrics
 import (
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// defaultMaxHotImageLabels bounds how many distinct image label values a
+// single metric family (e.g. archy_mutations_total) will track as
+// first-class Prometheus series. It's sized well above any realistic
+// cluster's working set of images, so in practice it only engages when
+// something is churning through unique tags or digests fast enough to be a
+// cardinality problem rather than normal variety. Kept comfortably below
+// 1000 because archy_mutation_duration_seconds is a histogram: each hot
+// image mints several bucket series, not just one, so the cap governs total
+// /metrics response size as much as image count.
+const defaultMaxHotImageLabels = 500
+
+// otherImageLabel is the image label value every metric family folds values
+// beyond its hot set into.
+const otherImageLabel = "__other__"
+
 // Metrics holds all Prometheus metrics
 type Metrics struct {
 	registry         *prometheus.Registry
@@ -15,20 +31,78 @@ type Metrics struct {
 	mutationDuration *prometheus.HistogramVec
 	cacheHits        *prometheus.CounterVec
 	cacheMisses      *prometheus.CounterVec
+	cacheTierHits    *prometheus.CounterVec
+	verifyFailures   *prometheus.CounterVec
+	mirrorHits       prometheus.Counter
+	mirrorMisses     prometheus.Counter
+	mirrorBytes      *prometheus.CounterVec
+
+	ratelimitDecisions *prometheus.CounterVec
+	ratelimitWait      prometheus.Histogram
+
+	registryCacheHits   *prometheus.CounterVec
+	registryCacheMisses *prometheus.CounterVec
+	registryDuration    *prometheus.HistogramVec
+	registryResponses   *prometheus.CounterVec
+
+	registryMirrorRequests *prometheus.CounterVec
+
+	registrySingleflightShared *prometheus.CounterVec
+
+	registryLookupDuration *prometheus.HistogramVec
+	registryLookupErrors   *prometheus.CounterVec
+
+	admissionRequests *prometheus.CounterVec
+	admissionDuration prometheus.Histogram
+
+	cacheSize      prometheus.Gauge
+	cacheCapacity  prometheus.Gauge
+	cacheEvictions prometheus.Counter
+
+	labelsDropped *prometheus.CounterVec
+	seriesActive  *prometheus.GaugeVec
+
+	stripImageTags bool
+	mutationsGuard *imageLabelGuard
+	durationGuard  *imageLabelGuard
+	cacheHitGuard  *imageLabelGuard
+	cacheMissGuard *imageLabelGuard
+	verifyGuard    *imageLabelGuard
+	ratelimitGuard *imageLabelGuard
 }
 
 var labelSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_]`)
 
-// NewMetrics creates a new metrics instance
+// NewMetrics creates a new metrics instance. Image labels are recorded with
+// their tag intact; use NewMetricsWithStripTag to drop tags instead, e.g.
+// when a registry mirrors the same content under many short-lived tags.
 func NewMetrics() *Metrics {
+	return NewMetricsWithStripTag(false)
+}
+
+// NewMetricsWithStripTag creates a new metrics instance like NewMetrics, but
+// when stripImageTags is set, image labels are recorded without their tag
+// (e.g. "nginx" rather than "nginx:1.27"), trading tag-level detail for
+// lower cardinality when tags themselves (rather than distinct images) are
+// driving the series count.
+func NewMetricsWithStripTag(stripImageTags bool) *Metrics {
 	registry := prometheus.NewRegistry()
 
+	// archy_mutations_total, archy_mutation_duration_seconds,
+	// archy_cache_hits_total and archy_cache_misses_total used to carry a
+	// single "image" label holding the full reference (tag or digest
+	// included unless stripImageTags was set) — every unique tag or digest
+	// minted its own series, which is exactly the cardinality bomb the
+	// imageLabelGuard below was a bandage for. They now carry image_repo
+	// (registry+repository, tag/digest always stripped) and a separate
+	// image_registry (just the registry host, a small fixed set), so
+	// cardinality is bounded by distinct images rather than distinct pulls.
 	mutationsTotal := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "archy_mutations_total",
 			Help: "Total number of pod mutations performed",
 		},
-		[]string{"image", "architecture", "success"},
+		[]string{"image_repo", "image_registry", "architecture", "success"},
 	)
 
 	mutationDuration := prometheus.NewHistogramVec(
@@ -37,7 +111,7 @@ func NewMetrics() *Metrics {
 			Help:    "Duration of pod mutations in seconds",
 			Buckets: []float64{0.001, 0.01, 0.1, 1, 5, 10},
 		},
-		[]string{"image", "architecture"},
+		[]string{"image_repo", "image_registry", "architecture"},
 	)
 
 	cacheHits := prometheus.NewCounterVec(
@@ -45,7 +119,7 @@ func NewMetrics() *Metrics {
 			Name: "archy_cache_hits_total",
 			Help: "Total number of cache hits",
 		},
-		[]string{"image"},
+		[]string{"image_repo", "image_registry"},
 	)
 
 	cacheMisses := prometheus.NewCounterVec(
@@ -53,45 +127,403 @@ func NewMetrics() *Metrics {
 			Name: "archy_cache_misses_total",
 			Help: "Total number of cache misses",
 		},
+		[]string{"image_repo", "image_registry"},
+	)
+
+	cacheTierHits := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "archy_cache_tier_hits_total",
+			Help: "Total number of cache.TwoTierCache hits, by which tier served them (l1: local memory, l2: remote)",
+		},
+		[]string{"tier"},
+	)
+
+	verifyFailures := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "archy_signature_verification_failures_total",
+			Help: "Total number of images whose Cosign signature failed verification",
+		},
 		[]string{"image"},
 	)
 
+	mirrorHits := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "archy_mirror_hits_total",
+			Help: "Total number of manifest lookups served from the local pull-through mirror",
+		},
+	)
+
+	mirrorMisses := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "archy_mirror_misses_total",
+			Help: "Total number of manifest lookups that missed the local mirror and went upstream",
+		},
+	)
+
+	mirrorBytes := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "archy_mirror_bytes_total",
+			Help: "Total manifest bytes served from or stored into the local mirror",
+		},
+		[]string{"direction"},
+	)
+
+	registryCacheHits := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "archy_registry_cache_hits_total",
+			Help: "Total number of resilient registry client cache hits (positive or negative), by registry host",
+		},
+		[]string{"host"},
+	)
+
+	registryCacheMisses := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "archy_registry_cache_misses_total",
+			Help: "Total number of resilient registry client cache misses, by registry host",
+		},
+		[]string{"host"},
+	)
+
+	registryDuration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "archy_registry_upstream_duration_seconds",
+			Help:    "Duration of upstream registry manifest lookups in seconds, by registry host",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10},
+		},
+		[]string{"host"},
+	)
+
+	registryResponses := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "archy_registry_responses_total",
+			Help: "Total upstream registry responses, by registry host and outcome (ok, rate_limited, not_found, server_error, error)",
+		},
+		[]string{"host", "outcome"},
+	)
+
+	registryMirrorRequests := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "archy_registry_mirror_requests_total",
+			Help: "Total requests issued by MirroredClient, by mirror (or \"origin\") and result (success, not_found, unauthorized, server_error, breaker_open, error)",
+		},
+		[]string{"mirror", "result"},
+	)
+
+	registrySingleflightShared := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "archy_registry_singleflight_shared_total",
+			Help: "Total number of resilient registry client lookups that were coalesced onto an in-flight upstream call for the same host and image, by registry host",
+		},
+		[]string{"host"},
+	)
+
+	ratelimitDecisions := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "archy_ratelimit_decisions_total",
+			Help: "Total number of Mutator rate-limit decisions for a cache-miss registry lookup, by (namespace, image repository) key and decision (allow, deny; stale is reserved for a Cache backend that can distinguish an expired-but-present entry from an absent one, which MemoryCache/RedisCache don't today)",
+		},
+		[]string{"key", "decision"},
+	)
+
+	ratelimitWait := prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "archy_ratelimit_wait_seconds",
+			Help:    "How long a cache-miss registry lookup waited on Mutator's rate limiter before being allowed or denied",
+			Buckets: []float64{0.001, 0.01, 0.1, 0.5, 1, 2, 5},
+		},
+	)
+
+	admissionRequests := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "archy_admission_requests_total",
+			Help: "Total number of admission webhook requests received, by the AdmissionReview API version the caller sent (v1, v1beta1)",
+		},
+		[]string{"version"},
+	)
+
+	labelsDropped := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "archy_metrics_labels_dropped_total",
+			Help: "Total number of image label values folded into image=\"__other__\" after a metric family's hot-set capacity was reached, by metric family",
+		},
+		[]string{"metric"},
+	)
+
+	seriesActive := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "archy_metrics_series_active",
+			Help: "Current number of distinct image label values tracked as first-class series for a metric family, by metric family",
+		},
+		[]string{"metric"},
+	)
+
+	cacheSize := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "archy_cache_size",
+			Help: "Current number of entries in Mutator's architecture-detection MemoryCache",
+		},
+	)
+
+	cacheCapacity := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "archy_cache_capacity",
+			Help: "Configured maximum entry count for Mutator's architecture-detection MemoryCache",
+		},
+	)
+
+	cacheEvictions := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "archy_cache_evictions_total",
+			Help: "Total number of entries evicted from Mutator's architecture-detection MemoryCache to stay within capacity",
+		},
+	)
+
+	registryLookupDuration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "archy_registry_lookup_duration_seconds",
+			Help:    "Duration of Mutator's registry architecture lookups in seconds, by registry host",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10},
+		},
+		[]string{"registry"},
+	)
+
+	registryLookupErrors := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "archy_registry_lookup_errors_total",
+			Help: "Total number of Mutator registry architecture lookups that failed or returned no architectures, by registry host and reason (rate_limited, unauthorized, not_found, empty_result, other)",
+		},
+		[]string{"registry", "reason"},
+	)
+
+	admissionDuration := prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "archy_webhook_admission_duration_seconds",
+			Help:    "Duration of a full admission webhook request, from decode through response, in seconds",
+			Buckets: []float64{0.001, 0.01, 0.1, 0.5, 1, 2, 5},
+		},
+	)
+
 	registry.MustRegister(mutationsTotal)
 	registry.MustRegister(mutationDuration)
 	registry.MustRegister(cacheHits)
 	registry.MustRegister(cacheMisses)
+	registry.MustRegister(cacheTierHits)
+	registry.MustRegister(verifyFailures)
+	registry.MustRegister(mirrorHits)
+	registry.MustRegister(mirrorMisses)
+	registry.MustRegister(mirrorBytes)
+	registry.MustRegister(registryCacheHits)
+	registry.MustRegister(registryCacheMisses)
+	registry.MustRegister(registryDuration)
+	registry.MustRegister(registryResponses)
+	registry.MustRegister(registryMirrorRequests)
+	registry.MustRegister(registrySingleflightShared)
+	registry.MustRegister(ratelimitDecisions)
+	registry.MustRegister(ratelimitWait)
+	registry.MustRegister(admissionRequests)
+	registry.MustRegister(admissionDuration)
+	registry.MustRegister(labelsDropped)
+	registry.MustRegister(seriesActive)
+	registry.MustRegister(cacheSize)
+	registry.MustRegister(cacheCapacity)
+	registry.MustRegister(cacheEvictions)
+	registry.MustRegister(registryLookupDuration)
+	registry.MustRegister(registryLookupErrors)
 
-	return &Metrics{
-		registry:         registry,
-		mutationsTotal:   mutationsTotal,
-		mutationDuration: mutationDuration,
-		cacheHits:        cacheHits,
-		cacheMisses:      cacheMisses,
+	m := &Metrics{
+		registry:                   registry,
+		mutationsTotal:             mutationsTotal,
+		mutationDuration:           mutationDuration,
+		cacheHits:                  cacheHits,
+		cacheMisses:                cacheMisses,
+		cacheTierHits:              cacheTierHits,
+		verifyFailures:             verifyFailures,
+		mirrorHits:                 mirrorHits,
+		mirrorMisses:               mirrorMisses,
+		mirrorBytes:                mirrorBytes,
+		registryCacheHits:          registryCacheHits,
+		registryCacheMisses:        registryCacheMisses,
+		registryDuration:           registryDuration,
+		registryResponses:          registryResponses,
+		registryMirrorRequests:     registryMirrorRequests,
+		registrySingleflightShared: registrySingleflightShared,
+		registryLookupDuration:     registryLookupDuration,
+		registryLookupErrors:       registryLookupErrors,
+		ratelimitDecisions:         ratelimitDecisions,
+		ratelimitWait:              ratelimitWait,
+		admissionRequests:          admissionRequests,
+		admissionDuration:          admissionDuration,
+		cacheSize:                  cacheSize,
+		cacheCapacity:              cacheCapacity,
+		cacheEvictions:             cacheEvictions,
+		labelsDropped:              labelsDropped,
+		seriesActive:               seriesActive,
+		stripImageTags:             stripImageTags,
 	}
+
+	m.mutationsGuard = newImageLabelGuard(defaultMaxHotImageLabels, labelsDropped.WithLabelValues("archy_mutations_total"), seriesActive.WithLabelValues("archy_mutations_total"))
+	m.durationGuard = newImageLabelGuard(defaultMaxHotImageLabels, labelsDropped.WithLabelValues("archy_mutation_duration_seconds"), seriesActive.WithLabelValues("archy_mutation_duration_seconds"))
+	m.cacheHitGuard = newImageLabelGuard(defaultMaxHotImageLabels, labelsDropped.WithLabelValues("archy_cache_hits_total"), seriesActive.WithLabelValues("archy_cache_hits_total"))
+	m.cacheMissGuard = newImageLabelGuard(defaultMaxHotImageLabels, labelsDropped.WithLabelValues("archy_cache_misses_total"), seriesActive.WithLabelValues("archy_cache_misses_total"))
+	m.verifyGuard = newImageLabelGuard(defaultMaxHotImageLabels, labelsDropped.WithLabelValues("archy_signature_verification_failures_total"), seriesActive.WithLabelValues("archy_signature_verification_failures_total"))
+	m.ratelimitGuard = newImageLabelGuard(defaultMaxHotImageLabels, labelsDropped.WithLabelValues("archy_ratelimit_decisions_total"), seriesActive.WithLabelValues("archy_ratelimit_decisions_total"))
+
+	return m
 }
 
 // RecordMutation records a pod mutation
 func (m *Metrics) RecordMutation(image, architecture string, success bool, duration time.Duration) {
-	sanitizedImage := sanitizeLabel(image)
+	repo, registryHost := m.splitImageLabels(image)
 	successStr := "false"
 	if success {
 		successStr = "true"
 	}
 
-	m.mutationsTotal.WithLabelValues(sanitizedImage, architecture, successStr).Inc()
-	m.mutationDuration.WithLabelValues(sanitizedImage, architecture).Observe(duration.Seconds())
+	m.mutationsTotal.WithLabelValues(m.mutationsGuard.admit(repo), registryHost, architecture, successStr).Inc()
+	m.mutationDuration.WithLabelValues(m.durationGuard.admit(repo), registryHost, architecture).Observe(duration.Seconds())
 }
 
 // RecordCacheHit records a cache hit
 func (m *Metrics) RecordCacheHit(image string) {
-	sanitizedImage := sanitizeLabel(image)
-	m.cacheHits.WithLabelValues(sanitizedImage).Inc()
+	repo, registryHost := m.splitImageLabels(image)
+	m.cacheHits.WithLabelValues(m.cacheHitGuard.admit(repo), registryHost).Inc()
 }
 
 // RecordCacheMiss records a cache miss
 func (m *Metrics) RecordCacheMiss(image string) {
-	sanitizedImage := sanitizeLabel(image)
-	m.cacheMisses.WithLabelValues(sanitizedImage).Inc()
+	repo, registryHost := m.splitImageLabels(image)
+	m.cacheMisses.WithLabelValues(m.cacheMissGuard.admit(repo), registryHost).Inc()
+}
+
+// RecordCacheTierHit records a cache.TwoTierCache hit served by tier ("l1"
+// or "l2"). Unlike RecordCacheHit/RecordCacheMiss, tier is a small fixed set
+// of values, so it isn't run through an imageLabelGuard.
+func (m *Metrics) RecordCacheTierHit(tier string) {
+	m.cacheTierHits.WithLabelValues(tier).Inc()
+}
+
+// RecordVerificationFailure records an image whose Cosign signature could
+// not be verified.
+func (m *Metrics) RecordVerificationFailure(image string) {
+	canonicalImage := m.canonicalizeImage(image)
+	m.verifyFailures.WithLabelValues(m.verifyGuard.admit(canonicalImage)).Inc()
+}
+
+// RecordMirrorHit records a manifest lookup served from the local mirror
+// store without going upstream.
+func (m *Metrics) RecordMirrorHit() {
+	m.mirrorHits.Inc()
+}
+
+// RecordMirrorMiss records a manifest lookup that missed the local mirror
+// store and had to fetch upstream.
+func (m *Metrics) RecordMirrorMiss() {
+	m.mirrorMisses.Inc()
+}
+
+// RecordMirrorBytes records n manifest bytes served ("served") from or
+// stored ("stored") into the local mirror.
+func (m *Metrics) RecordMirrorBytes(direction string, n int) {
+	m.mirrorBytes.WithLabelValues(direction).Add(float64(n))
+}
+
+// RecordRegistryCacheHit records a hit in the resilient registry client's
+// cache (positive or negative) for host, rather than going upstream.
+func (m *Metrics) RecordRegistryCacheHit(host string) {
+	m.registryCacheHits.WithLabelValues(hostLabel(host)).Inc()
+}
+
+// RecordRegistryCacheMiss records a resilient registry client cache miss for
+// host that had to consult (or wait on a coalesced call to) upstream.
+func (m *Metrics) RecordRegistryCacheMiss(host string) {
+	m.registryCacheMisses.WithLabelValues(hostLabel(host)).Inc()
+}
+
+// RecordRegistryUpstreamLatency records how long an upstream manifest lookup
+// against host took.
+func (m *Metrics) RecordRegistryUpstreamLatency(host string, duration time.Duration) {
+	m.registryDuration.WithLabelValues(hostLabel(host)).Observe(duration.Seconds())
+}
+
+// RecordRegistryResponse records the outcome of an upstream manifest lookup
+// against host: "ok", "rate_limited", "not_found", "server_error" or "error".
+func (m *Metrics) RecordRegistryResponse(host, outcome string) {
+	m.registryResponses.WithLabelValues(hostLabel(host), outcome).Inc()
+}
+
+// RecordRegistryMirrorRequest records the outcome of a MirroredClient
+// request against mirror (or "origin" for the canonical upstream): "success",
+// "not_found", "unauthorized", "server_error", "breaker_open" or "error".
+func (m *Metrics) RecordRegistryMirrorRequest(mirror, result string) {
+	m.registryMirrorRequests.WithLabelValues(hostLabel(mirror), result).Inc()
+}
+
+// RecordRegistrySingleflightShared records a resilient registry client lookup
+// against host that was coalesced onto an in-flight upstream call for the
+// same host and image, rather than issuing its own.
+func (m *Metrics) RecordRegistrySingleflightShared(host string) {
+	m.registrySingleflightShared.WithLabelValues(hostLabel(host)).Inc()
+}
+
+// RecordRateLimitDecision records a Mutator rate-limit decision for key (a
+// "namespace/repo" pair, see Mutator's rateLimitKey) and decision ("allow"
+// or "deny"). key runs through the same bounded hot-set guard as the image
+// labels above, since distinct (namespace, repo) pairs can grow just as
+// unbounded as distinct images.
+func (m *Metrics) RecordRateLimitDecision(key, decision string) {
+	m.ratelimitDecisions.WithLabelValues(m.ratelimitGuard.admit(sanitizeLabel(key)), decision).Inc()
+}
+
+// RecordRateLimitWait records how long a cache-miss registry lookup waited
+// on Mutator's rate limiter before Allow returned.
+func (m *Metrics) RecordRateLimitWait(d time.Duration) {
+	m.ratelimitWait.Observe(d.Seconds())
+}
+
+// RecordAdmissionRequest records an incoming admission webhook request's
+// AdmissionReview API version (e.g. "v1", "v1beta1"), so operators can see
+// when every caller has migrated off the deprecated v1beta1 API.
+func (m *Metrics) RecordAdmissionRequest(version string) {
+	m.admissionRequests.WithLabelValues(version).Inc()
+}
+
+// RecordAdmissionDuration records how long a full admission webhook request
+// took, from decode through response.
+func (m *Metrics) RecordAdmissionDuration(d time.Duration) {
+	m.admissionDuration.Observe(d.Seconds())
+}
+
+// RecordCacheSize records the current entry count of Mutator's
+// architecture-detection MemoryCache. Pushed by the cache itself on every
+// Set/Delete rather than polled, so the gauge never lags behind.
+func (m *Metrics) RecordCacheSize(n int) {
+	m.cacheSize.Set(float64(n))
+}
+
+// RecordCacheCapacity records the configured maximum entry count of
+// Mutator's architecture-detection MemoryCache.
+func (m *Metrics) RecordCacheCapacity(n int) {
+	m.cacheCapacity.Set(float64(n))
+}
+
+// RecordCacheEviction records one entry evicted from Mutator's
+// architecture-detection MemoryCache to stay within capacity.
+func (m *Metrics) RecordCacheEviction() {
+	m.cacheEvictions.Inc()
+}
+
+// RecordRegistryLookupDuration records how long a registry architecture
+// lookup against registryHost took.
+func (m *Metrics) RecordRegistryLookupDuration(registryHost string, duration time.Duration) {
+	m.registryLookupDuration.WithLabelValues(hostLabel(registryHost)).Observe(duration.Seconds())
+}
+
+// RecordRegistryLookupError records a registry architecture lookup against
+// registryHost that failed or returned no architectures, classified by
+// reason ("rate_limited", "unauthorized", "not_found", "empty_result" or
+// "other").
+func (m *Metrics) RecordRegistryLookupError(registryHost, reason string) {
+	m.registryLookupErrors.WithLabelValues(hostLabel(registryHost), reason).Inc()
 }
 
 // Registry returns the Prometheus registry
@@ -99,27 +531,168 @@ func (m *Metrics) Registry() *prometheus.Registry {
 	return m.registry
 }
 
+// hostLabel bounds the length of a registry/mirror host label without
+// mangling it. Unlike sanitizeLabel (built for image references, where
+// '/', ':' and '@' are cardinality/readability concerns worth collapsing to
+// underscores), Prometheus label values accept any UTF-8 string, and a
+// hostname's dots and colons are exactly what make it recognizable on a
+// dashboard — replacing them would turn "registry.example.com" into the
+// far less useful "registry_example_com".
+func hostLabel(label string) string {
+	if len(label) > 63 {
+		label = label[:63]
+	}
+	if label == "" {
+		label = "unknown"
+	}
+	return label
+}
+
 // sanitizeLabel sanitizes label values for Prometheus
 func sanitizeLabel(label string) string {
 	// Replace invalid characters with underscores
 	sanitized := labelSanitizer.ReplaceAllString(label, "_")
-	
-	// Limit length to prevent cardinality explosion
-	if len(sanitized) > 100 {
-		sanitized = sanitized[:100]
+
+	// Limit length to 63 chars, the Prometheus-recommended bound for label
+	// values: keeps /metrics scrape payloads and index memory bounded even
+	// when a caller passes something pathological.
+	if len(sanitized) > 63 {
+		sanitized = sanitized[:63]
 	}
-	
+
 	// Remove consecutive underscores
 	for strings.Contains(sanitized, "__") {
 		sanitized = strings.ReplaceAll(sanitized, "__", "_")
 	}
-	
+
 	// Trim underscores from start and end
 	sanitized = strings.Trim(sanitized, "_")
-	
+
 	if sanitized == "" {
 		sanitized = "unknown"
 	}
-	
+
 	return sanitized
-}
\ No newline at end of file
+}
+
+// canonicalizeImage reduces an image reference to a low-cardinality label
+// value: its digest suffix (if any) is always stripped, since every unique
+// digest would otherwise mint its own series for what's usually the same
+// handful of images being re-pulled; its tag is additionally stripped when m
+// was built with stripImageTags, for registries where distinct tags (not
+// distinct images) are the source of churn. The result still passes through
+// sanitizeLabel, since stripping doesn't remove characters Prometheus
+// disallows in label values.
+func (m *Metrics) canonicalizeImage(image string) string {
+	if i := strings.IndexByte(image, '@'); i != -1 {
+		image = image[:i]
+	}
+	if m.stripImageTags {
+		image = StripImageTag(image)
+	}
+	return sanitizeLabel(image)
+}
+
+// splitImageLabels reduces image to the two labels RecordMutation,
+// RecordCacheHit and RecordCacheMiss record against: image_repo
+// (registry+repository, with its digest and tag always stripped —
+// unlike canonicalizeImage, this doesn't depend on stripImageTags, since
+// tags are exactly the cardinality source these three metrics used to
+// bleed) and image_registry (just the registry host, e.g. "docker.io" or
+// "registry.example.com": a small, fixed-size set regardless of cluster
+// size).
+func (m *Metrics) splitImageLabels(image string) (repo, registryHost string) {
+	repo = image
+	if i := strings.IndexByte(repo, '@'); i != -1 {
+		repo = repo[:i]
+	}
+	repo = StripImageTag(repo)
+	registryHost = imageRegistryHost(repo)
+	if registryHost != "docker.io" {
+		repo = strings.TrimPrefix(repo, registryHost+"/")
+	}
+	return sanitizeLabel(repo), sanitizeLabel(registryHost)
+}
+
+// imageRegistryHost extracts a low-cardinality registry host from repo
+// (already digest/tag-stripped): the first path segment is treated as a
+// host when it contains a '.' or ':' (a port), the same rule
+// credentials.ExtractRegistry applies to image references elsewhere in
+// this codebase (notably including an unqualified "localhost/..." image,
+// which that function treats as a Docker Hub repo rather than a host);
+// anything else is assumed to be an unqualified Docker Hub reference.
+func imageRegistryHost(repo string) string {
+	slash := strings.IndexByte(repo, '/')
+	if slash == -1 {
+		return "docker.io"
+	}
+	host := repo[:slash]
+	if strings.ContainsAny(host, ".:") {
+		return host
+	}
+	return "docker.io"
+}
+
+// StripImageTag removes a trailing ":tag" from image, taking care not to
+// mistake a registry host's port (e.g. "registry.example.com:5000/nginx")
+// for one: a colon only introduces a tag when it appears after the last '/'.
+// Exported so other packages deriving a repository identity from an image
+// reference (e.g. webhook.rateLimitKey) share this logic instead of
+// duplicating it.
+func StripImageTag(image string) string {
+	slash := strings.LastIndex(image, "/")
+	colon := strings.LastIndex(image, ":")
+	if colon > slash {
+		return image[:colon]
+	}
+	return image
+}
+
+// imageLabelGuard bounds how many distinct image label values a single
+// metric family tracks as first-class Prometheus series: the first maxHot
+// distinct values seen are admitted as "hot" and keep their own series;
+// every value after that is folded into otherImageLabel instead, so a pod
+// churning through unique tags or digests can't grow that family's
+// cardinality without bound. Each fold increments dropped, and active
+// tracks the current hot-set size.
+type imageLabelGuard struct {
+	mu      sync.Mutex
+	maxHot  int
+	hot     map[string]struct{}
+	dropped prometheus.Counter
+	active  prometheus.Gauge
+}
+
+func newImageLabelGuard(maxHot int, dropped prometheus.Counter, active prometheus.Gauge) *imageLabelGuard {
+	return &imageLabelGuard{
+		maxHot:  maxHot,
+		hot:     make(map[string]struct{}),
+		dropped: dropped,
+		active:  active,
+	}
+}
+
+// admit returns the image label value a caller should actually record
+// against: image itself if it's (or becomes) part of the hot set, otherwise
+// otherImageLabel.
+func (g *imageLabelGuard) admit(image string) string {
+	if image == otherImageLabel {
+		return otherImageLabel
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.hot[image]; ok {
+		return image
+	}
+
+	if len(g.hot) >= g.maxHot {
+		g.dropped.Inc()
+		return otherImageLabel
+	}
+
+	g.hot[image] = struct{}{}
+	g.active.Set(float64(len(g.hot)))
+	return image
+}
@@ -5,20 +5,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"runtime"
-	"strings"
 	"sync"
 	"testing"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
 )
 
 func TestMetrics_CollectionUnderHighLoad(t *testing.T) {
 	metrics := NewMetrics()
-	
+
 	// Simulate high load
 	var wg sync.WaitGroup
 	for i := 0; i < 1000; i++ {
@@ -28,33 +25,33 @@ func TestMetrics_CollectionUnderHighLoad(t *testing.T) {
 			metrics.RecordMutation("nginx:latest", "amd64", true, 10*time.Millisecond)
 		}()
 	}
-	
+
 	wg.Wait()
-	
+
 	// Verify metrics are collected
 	req := httptest.NewRequest("GET", "/metrics", nil)
 	w := httptest.NewRecorder()
-	
+
 	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
 	handler.ServeHTTP(w, req)
-	
+
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "archy_mutations_total")
 }
 
 func TestMetrics_AccuracyDuringFailures(t *testing.T) {
 	metrics := NewMetrics()
-	
+
 	// Record successful mutations
 	for i := 0; i < 10; i++ {
 		metrics.RecordMutation("nginx:latest", "amd64", true, 5*time.Millisecond)
 	}
-	
+
 	// Record failed mutations
 	for i := 0; i < 5; i++ {
 		metrics.RecordMutation("nginx:latest", "amd64", false, 100*time.Millisecond)
 	}
-	
+
 	// Record cache operations
 	for i := 0; i < 20; i++ {
 		metrics.RecordCacheHit("nginx:latest")
@@ -62,66 +59,141 @@ func TestMetrics_AccuracyDuringFailures(t *testing.T) {
 	for i := 0; i < 8; i++ {
 		metrics.RecordCacheMiss("nginx:latest")
 	}
-	
+
 	// Verify metrics accuracy
 	req := httptest.NewRequest("GET", "/metrics", nil)
 	w := httptest.NewRecorder()
-	
+
 	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
 	handler.ServeHTTP(w, req)
-	
+
 	body := w.Body.String()
-	
-	// Check mutation counts
-	assert.Contains(t, body, `archy_mutations_total{architecture="amd64",image="nginx:latest",success="true"} 10`)
-	assert.Contains(t, body, `archy_mutations_total{architecture="amd64",image="nginx:latest",success="false"} 5`)
-	
+
+	// Check mutation counts. The tag is stripped from image_repo regardless
+	// of stripImageTags, so "nginx:latest" collapses onto image_repo="nginx".
+	assert.Contains(t, body, `archy_mutations_total{architecture="amd64",image_registry="docker_io",image_repo="nginx",success="true"} 10`)
+	assert.Contains(t, body, `archy_mutations_total{architecture="amd64",image_registry="docker_io",image_repo="nginx",success="false"} 5`)
+
 	// Check cache metrics
-	assert.Contains(t, body, `archy_cache_hits_total{image="nginx:latest"} 20`)
-	assert.Contains(t, body, `archy_cache_misses_total{image="nginx:latest"} 8`)
+	assert.Contains(t, body, `archy_cache_hits_total{image_registry="docker_io",image_repo="nginx"} 20`)
+	assert.Contains(t, body, `archy_cache_misses_total{image_registry="docker_io",image_repo="nginx"} 8`)
 }
 
 func TestMetrics_CardinalityExplosionPrevention(t *testing.T) {
 	metrics := NewMetrics()
-	
-	// Try to create high cardinality by using many different image names
+
+	// Try to create high cardinality with 10000 genuinely distinct image
+	// repositories (not just distinct tags, which image_repo now always
+	// strips), as a registry serving many uniquely named images would.
 	for i := 0; i < 10000; i++ {
-		image := strings.Repeat("a", i%100) + ":latest" // Varying length images
+		image := fmt.Sprintf("registry.example.com/app%d:v1", i)
 		metrics.RecordMutation(image, "amd64", true, 1*time.Millisecond)
 	}
-	
+
 	// Metrics should still be collectable without memory explosion
 	req := httptest.NewRequest("GET", "/metrics", nil)
 	w := httptest.NewRecorder()
-	
+
 	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
 	handler.ServeHTTP(w, req)
-	
+
 	assert.Equal(t, http.StatusOK, w.Code)
-	
+
+	body := w.Body.String()
+
 	// Response should not be excessively large (indicating cardinality explosion)
-	assert.True(t, len(w.Body.String()) < 1024*1024) // Less than 1MB
+	assert.True(t, len(body) < 1024*1024) // Less than 1MB
+
+	// Everything past the hot-set cap should have folded into a single
+	// "__other__" series rather than minting 10000 of its own.
+	assert.Contains(t, body, `image_repo="__other__"`)
+	assert.Contains(t, body, fmt.Sprintf(`archy_metrics_labels_dropped_total{metric="archy_mutations_total"} %d`, 10000-defaultMaxHotImageLabels))
+	assert.Contains(t, body, fmt.Sprintf(`archy_metrics_series_active{metric="archy_mutations_total"} %d`, defaultMaxHotImageLabels))
+}
+
+func TestMetrics_LabelDroppedAndSeriesActiveStayBounded(t *testing.T) {
+	metrics := NewMetrics()
+
+	for i := 0; i < defaultMaxHotImageLabels+5; i++ {
+		metrics.RecordCacheHit(fmt.Sprintf("image-%d", i))
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `archy_cache_hits_total{image_registry="docker_io",image_repo="__other__"} 5`)
+	assert.Contains(t, body, `archy_metrics_labels_dropped_total{metric="archy_cache_hits_total"} 5`)
+	assert.Contains(t, body, fmt.Sprintf(`archy_metrics_series_active{metric="archy_cache_hits_total"} %d`, defaultMaxHotImageLabels))
+}
+
+func TestMetrics_StripImageTag(t *testing.T) {
+	metrics := NewMetricsWithStripTag(true)
+
+	metrics.RecordVerificationFailure("registry.example.com/nginx:1.27")
+	metrics.RecordVerificationFailure("registry.example.com/nginx:1.28")
+	metrics.RecordVerificationFailure("registry.example.com:5000/nginx:1.29")
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	// The two nginx:1.27/1.28 failures collapse onto one tag-stripped series...
+	assert.Contains(t, body, `archy_signature_verification_failures_total{image="registry_example_com_nginx"} 2`)
+	// ...while the differently-hosted (and differently-ported) image keeps its own series.
+	assert.Contains(t, body, `archy_signature_verification_failures_total{image="registry_example_com_5000_nginx"} 1`)
+}
+
+func TestMetrics_SplitImageLabels(t *testing.T) {
+	// RecordMutation/RecordCacheHit/RecordCacheMiss always strip the tag
+	// from image_repo and record the registry host separately as
+	// image_registry, regardless of stripImageTags.
+	metrics := NewMetrics()
+
+	metrics.RecordCacheHit("registry.example.com/nginx:1.27")
+	metrics.RecordCacheHit("registry.example.com/nginx:1.28")
+	metrics.RecordCacheHit("nginx:latest")
+	metrics.RecordCacheHit("nginx@sha256:abc123def456")
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	// The two registry.example.com/nginx hits share one tag-stripped series...
+	assert.Contains(t, body, `archy_cache_hits_total{image_registry="registry_example_com",image_repo="nginx"} 2`)
+	// ...and unqualified Docker Hub references (by tag or by digest) share
+	// another, with image_registry="docker_io" (the sanitized host).
+	assert.Contains(t, body, `archy_cache_hits_total{image_registry="docker_io",image_repo="nginx"} 2`)
 }
 
 func TestMetrics_ScrapingTimeout(t *testing.T) {
 	metrics := NewMetrics()
-	
+
 	// Add many metrics to potentially slow down scraping
 	for i := 0; i < 1000; i++ {
 		metrics.RecordMutation("nginx:latest", "amd64", true, 1*time.Millisecond)
 	}
-	
+
 	// Test scraping with timeout
 	req := httptest.NewRequest("GET", "/metrics", nil)
 	w := httptest.NewRecorder()
-	
+
 	start := time.Now()
 	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{
 		Timeout: 1 * time.Second,
 	})
 	handler.ServeHTTP(w, req)
 	duration := time.Since(start)
-	
+
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.True(t, duration < 2*time.Second) // Should complete quickly
 }
@@ -130,61 +202,61 @@ func TestMetrics_MemoryUsageGrowth(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping memory test in short mode")
 	}
-	
+
 	metrics := NewMetrics()
-	
+
 	// Record many metrics over time
 	for i := 0; i < 10000; i++ {
 		metrics.RecordMutation("nginx:latest", "amd64", true, 1*time.Millisecond)
 		metrics.RecordCacheHit("nginx:latest")
-		
+
 		if i%1000 == 0 {
 			// Force GC periodically
 			runtime.GC()
 		}
 	}
-	
+
 	// Memory usage should be reasonable
 	req := httptest.NewRequest("GET", "/metrics", nil)
 	w := httptest.NewRecorder()
-	
+
 	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
 	handler.ServeHTTP(w, req)
-	
+
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "archy_mutations_total")
 }
 
 func TestMetrics_ConcurrentScraping(t *testing.T) {
 	metrics := NewMetrics()
-	
+
 	// Add some metrics
 	metrics.RecordMutation("nginx:latest", "amd64", true, 5*time.Millisecond)
-	
+
 	// Concurrent scraping
 	var wg sync.WaitGroup
 	errors := make(chan error, 10)
-	
+
 	for i := 0; i < 10; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			
+
 			req := httptest.NewRequest("GET", "/metrics", nil)
 			w := httptest.NewRecorder()
-			
+
 			handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
 			handler.ServeHTTP(w, req)
-			
+
 			if w.Code != http.StatusOK {
 				errors <- fmt.Errorf("unexpected status code: %d", w.Code)
 			}
 		}()
 	}
-	
+
 	wg.Wait()
 	close(errors)
-	
+
 	for err := range errors {
 		t.Errorf("Concurrent scraping failed: %v", err)
 	}
@@ -192,7 +264,7 @@ func TestMetrics_ConcurrentScraping(t *testing.T) {
 
 func TestMetrics_HistogramBuckets(t *testing.T) {
 	metrics := NewMetrics()
-	
+
 	// Record mutations with different durations
 	durations := []time.Duration{
 		1 * time.Millisecond,
@@ -201,19 +273,19 @@ func TestMetrics_HistogramBuckets(t *testing.T) {
 		1 * time.Second,
 		5 * time.Second,
 	}
-	
+
 	for _, duration := range durations {
 		metrics.RecordMutation("nginx:latest", "amd64", true, duration)
 	}
-	
+
 	req := httptest.NewRequest("GET", "/metrics", nil)
 	w := httptest.NewRecorder()
-	
+
 	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
 	handler.ServeHTTP(w, req)
-	
+
 	body := w.Body.String()
-	
+
 	// Check histogram buckets are present
 	assert.Contains(t, body, "archy_mutation_duration_seconds_bucket")
 	assert.Contains(t, body, `le="0.001"`)
@@ -223,9 +295,131 @@ func TestMetrics_HistogramBuckets(t *testing.T) {
 	assert.Contains(t, body, `le="+Inf"`)
 }
 
+func TestMetrics_RecordVerificationFailure(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.RecordVerificationFailure("nginx:latest")
+	metrics.RecordVerificationFailure("nginx:latest")
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+	handler.ServeHTTP(w, req)
+
+	assert.Contains(t, w.Body.String(), `archy_signature_verification_failures_total{image="nginx_latest"} 2`)
+}
+
+func TestMetrics_RecordMirrorHitAndMiss(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.RecordMirrorHit()
+	metrics.RecordMirrorHit()
+	metrics.RecordMirrorMiss()
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, "archy_mirror_hits_total 2")
+	assert.Contains(t, body, "archy_mirror_misses_total 1")
+}
+
+func TestMetrics_RecordMirrorBytes(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.RecordMirrorBytes("served", 100)
+	metrics.RecordMirrorBytes("stored", 250)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `archy_mirror_bytes_total{direction="served"} 100`)
+	assert.Contains(t, body, `archy_mirror_bytes_total{direction="stored"} 250`)
+}
+
+func TestMetrics_RecordRegistryCacheHitAndMiss(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.RecordRegistryCacheHit("registry.example.com")
+	metrics.RecordRegistryCacheHit("registry.example.com")
+	metrics.RecordRegistryCacheMiss("registry.example.com")
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `archy_registry_cache_hits_total{host="registry.example.com"} 2`)
+	assert.Contains(t, body, `archy_registry_cache_misses_total{host="registry.example.com"} 1`)
+}
+
+func TestMetrics_RecordRegistryUpstreamLatencyAndResponse(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.RecordRegistryUpstreamLatency("registry.example.com", 50*time.Millisecond)
+	metrics.RecordRegistryResponse("registry.example.com", "ok")
+	metrics.RecordRegistryResponse("registry.example.com", "rate_limited")
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, "archy_registry_upstream_duration_seconds_bucket")
+	assert.Contains(t, body, `archy_registry_responses_total{host="registry.example.com",outcome="ok"} 1`)
+	assert.Contains(t, body, `archy_registry_responses_total{host="registry.example.com",outcome="rate_limited"} 1`)
+}
+
+func TestMetrics_RecordRegistryMirrorRequest(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.RecordRegistryMirrorRequest("mirror.internal.example.com", "success")
+	metrics.RecordRegistryMirrorRequest("mirror.internal.example.com", "server_error")
+	metrics.RecordRegistryMirrorRequest("origin", "success")
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `archy_registry_mirror_requests_total{mirror="mirror.internal.example.com",result="success"} 1`)
+	assert.Contains(t, body, `archy_registry_mirror_requests_total{mirror="mirror.internal.example.com",result="server_error"} 1`)
+	assert.Contains(t, body, `archy_registry_mirror_requests_total{mirror="origin",result="success"} 1`)
+}
+
+func TestMetrics_RecordRegistrySingleflightShared(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.RecordRegistrySingleflightShared("registry.example.com")
+	metrics.RecordRegistrySingleflightShared("registry.example.com")
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `archy_registry_singleflight_shared_total{host="registry.example.com"} 2`)
+}
+
 func TestMetrics_LabelSanitization(t *testing.T) {
 	metrics := NewMetrics()
-	
+
 	// Test with potentially problematic labels
 	problematicImages := []string{
 		"nginx:latest",
@@ -234,21 +428,21 @@ func TestMetrics_LabelSanitization(t *testing.T) {
 		"nginx:tag-with-dashes",
 		"nginx:tag_with_underscores",
 	}
-	
+
 	for _, image := range problematicImages {
 		metrics.RecordMutation(image, "amd64", true, 1*time.Millisecond)
 	}
-	
+
 	req := httptest.NewRequest("GET", "/metrics", nil)
 	w := httptest.NewRecorder()
-	
+
 	handler := promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
 	handler.ServeHTTP(w, req)
-	
+
 	assert.Equal(t, http.StatusOK, w.Code)
-	
+
 	// Should not contain invalid Prometheus label characters
 	body := w.Body.String()
 	assert.NotContains(t, body, "@") // Should be sanitized
 	assert.NotContains(t, body, "/") // Should be sanitized
-}
\ No newline at end of file
+}
@@ -0,0 +1,129 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/lsdopen/archy/internal/metrics"
+	"github.com/lsdopen/archy/pkg/types"
+)
+
+// ManifestFetcher is implemented by registry clients that can return a
+// manifest's raw bytes and content digest directly, letting Client persist
+// them to the local mirror store. Clients that don't implement it are
+// wrapped as a no-op, since there's nothing to mirror.
+type ManifestFetcher interface {
+	FetchManifest(ctx context.Context, image string) (repo, digest string, body []byte, err error)
+}
+
+// Client wraps an upstream types.RegistryClient with the local Store: a
+// lookup first checks the store for image's last-known digest, and on a
+// miss fetches upstream once per distinct image even under concurrent
+// callers, then persists the result so later admissions (including from
+// other replicas sharing the store's directory via a PVC) are served from
+// disk instead of the upstream registry.
+type Client struct {
+	store   *Store
+	metrics *metrics.Metrics
+
+	group singleflight.Group
+}
+
+// NewClient creates a mirror Client backed by store, recording hit/miss/byte
+// metrics through m.
+func NewClient(store *Store, m *metrics.Metrics) *Client {
+	return &Client{store: store, metrics: m}
+}
+
+// Wrap returns a types.RegistryClient that consults the local mirror before
+// falling back to upstream. If upstream doesn't implement ManifestFetcher,
+// it's returned unchanged since there's no raw manifest available to mirror.
+func (c *Client) Wrap(upstream types.RegistryClient) types.RegistryClient {
+	fetcher, ok := upstream.(ManifestFetcher)
+	if !ok {
+		return upstream
+	}
+	return &wrappedClient{mirror: c, upstream: upstream, fetcher: fetcher}
+}
+
+type wrappedClient struct {
+	mirror   *Client
+	upstream types.RegistryClient
+	fetcher  ManifestFetcher
+}
+
+func (w *wrappedClient) GetSupportedArchitectures(ctx context.Context, image string) ([]string, error) {
+	if digest, ok := w.mirror.store.ResolveRef(image); ok {
+		if body, found := w.mirror.store.Get(digest); found {
+			if archs, err := decodeArchitectures(body); err == nil && len(archs) > 0 {
+				w.mirror.metrics.RecordMirrorHit()
+				w.mirror.metrics.RecordMirrorBytes("served", len(body))
+				return archs, nil
+			}
+		}
+	}
+
+	w.mirror.metrics.RecordMirrorMiss()
+
+	// Key the singleflight group per upstream client instance (one per
+	// registry host) plus image, so a thundering herd of pods referencing
+	// the same image collapses into a single upstream fetch without
+	// colliding across distinct registries that happen to share a repo/tag.
+	key := fmt.Sprintf("%p/%s", w.fetcher, image)
+
+	result, err, _ := w.mirror.group.Do(key, func() (interface{}, error) {
+		_, digest, body, err := w.fetcher.FetchManifest(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+
+		archs, err := decodeArchitectures(body)
+		if err != nil {
+			return nil, err
+		}
+
+		if putErr := w.mirror.store.Put(digest, body); putErr == nil {
+			w.mirror.store.SetRef(image, digest)
+			w.mirror.metrics.RecordMirrorBytes("stored", len(body))
+		}
+
+		return archs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]string), nil
+}
+
+// manifestList is the subset of an OCI/Docker manifest list this package
+// needs: the architecture each platform-specific manifest targets.
+type manifestList struct {
+	Manifests []struct {
+		Platform struct {
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+func decodeArchitectures(body []byte) ([]string, error) {
+	var parsed manifestList
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("decoding manifest: %w", err)
+	}
+
+	var archs []string
+	seen := make(map[string]bool)
+	for _, m := range parsed.Manifests {
+		arch := m.Platform.Architecture
+		if arch != "" && !seen[arch] {
+			archs = append(archs, arch)
+			seen[arch] = true
+		}
+	}
+
+	return archs, nil
+}
@@ -0,0 +1,149 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lsdopen/archy/internal/metrics"
+	"github.com/lsdopen/archy/internal/registry"
+)
+
+const testManifest = `{"manifests":[{"platform":{"architecture":"amd64"}},{"platform":{"architecture":"arm64"}}]}`
+
+// fakeManifestFetcher implements ManifestFetcher, counting calls so tests can
+// assert on request coalescing and mirror hits/misses.
+type fakeManifestFetcher struct {
+	calls  int32
+	repo   string
+	digest string
+	body   []byte
+	err    error
+}
+
+func (f *fakeManifestFetcher) GetSupportedArchitectures(ctx context.Context, image string) ([]string, error) {
+	return nil, fmt.Errorf("not used by these tests")
+}
+
+func (f *fakeManifestFetcher) FetchManifest(ctx context.Context, image string) (string, string, []byte, error) {
+	atomic.AddInt32(&f.calls, 1)
+	if f.err != nil {
+		return "", "", nil, f.err
+	}
+	return f.repo, f.digest, f.body, nil
+}
+
+func TestClient_WrapPassesThroughNonFetcherClients(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 0)
+	require.NoError(t, err)
+	c := NewClient(store, metrics.NewMetrics())
+
+	upstream := registry.NewDockerHubClient()
+	wrapped := c.Wrap(upstream)
+
+	assert.Same(t, upstream, wrapped)
+}
+
+func TestClient_FetchesUpstreamOnMissThenServesFromStore(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 0)
+	require.NoError(t, err)
+	c := NewClient(store, metrics.NewMetrics())
+
+	fetcher := &fakeManifestFetcher{repo: "myorg/app", digest: "sha256:abc", body: []byte(testManifest)}
+	wrapped := c.Wrap(fetcher)
+
+	archs, err := wrapped.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"amd64", "arm64"}, archs)
+	assert.EqualValues(t, 1, fetcher.calls)
+
+	// Second lookup should be served from the store without another upstream call.
+	archs, err = wrapped.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"amd64", "arm64"}, archs)
+	assert.EqualValues(t, 1, fetcher.calls)
+}
+
+func TestClient_PropagatesUpstreamFetchError(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 0)
+	require.NoError(t, err)
+	c := NewClient(store, metrics.NewMetrics())
+
+	fetcher := &fakeManifestFetcher{err: fmt.Errorf("registry unreachable")}
+	wrapped := c.Wrap(fetcher)
+
+	_, err = wrapped.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+	assert.Error(t, err)
+}
+
+func TestDecodeArchitectures_DedupesAndSkipsEmpty(t *testing.T) {
+	body := []byte(`{"manifests":[{"platform":{"architecture":"amd64"}},{"platform":{"architecture":"amd64"}},{"platform":{"architecture":""}}]}`)
+
+	archs, err := decodeArchitectures(body)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"amd64"}, archs)
+}
+
+func TestClient_ConcurrentLookupsCoalesceToSingleUpstreamFetch(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 0)
+	require.NoError(t, err)
+	c := NewClient(store, metrics.NewMetrics())
+
+	fetcher := newBlockingManifestFetcher("myorg/app", "sha256:abc", []byte(testManifest))
+	wrapped := c.Wrap(fetcher)
+
+	const callers = 5
+	results := make(chan []string, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			archs, err := wrapped.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+			assert.NoError(t, err)
+			results <- archs
+		}()
+	}
+
+	<-fetcher.entered
+	close(fetcher.release)
+
+	for i := 0; i < callers; i++ {
+		assert.ElementsMatch(t, []string{"amd64", "arm64"}, <-results)
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&fetcher.calls))
+}
+
+// blockingManifestFetcher blocks its first FetchManifest call until release
+// is closed, letting a test line up concurrent callers before it completes.
+type blockingManifestFetcher struct {
+	calls   int32
+	entered chan struct{}
+	release chan struct{}
+	repo    string
+	digest  string
+	body    []byte
+}
+
+func newBlockingManifestFetcher(repo, digest string, body []byte) *blockingManifestFetcher {
+	return &blockingManifestFetcher{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+		repo:    repo,
+		digest:  digest,
+		body:    body,
+	}
+}
+
+func (f *blockingManifestFetcher) GetSupportedArchitectures(ctx context.Context, image string) ([]string, error) {
+	return nil, fmt.Errorf("not used by these tests")
+}
+
+func (f *blockingManifestFetcher) FetchManifest(ctx context.Context, image string) (string, string, []byte, error) {
+	if atomic.AddInt32(&f.calls, 1) == 1 {
+		close(f.entered)
+	}
+	<-f.release
+	return f.repo, f.digest, f.body, nil
+}
@@ -0,0 +1,76 @@
+package mirror
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Server exposes a Store's manifests over the same "/v2/<repo>/manifests/<ref>"
+// shape as the OCI Distribution Spec, so it can be pointed at as if it were a
+// read-through registry mirror.
+type Server struct {
+	store *Store
+}
+
+// NewServer creates a Server serving manifests out of store.
+func NewServer(store *Store) *Server {
+	return &Server{store: store}
+}
+
+// Handler returns the mirror's HTTP routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/", s.handleManifest)
+	return mux
+}
+
+func (s *Server) handleManifest(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v2/" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	repo, ref, ok := parseManifestPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	digest := ref
+	if !strings.HasPrefix(ref, "sha256:") {
+		resolved, found := s.store.ResolveRef(repo + ":" + ref)
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		digest = resolved
+	}
+
+	body, found := s.store.Get(digest)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.list.v2+json")
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Write(body)
+}
+
+// parseManifestPath extracts repo and ref from "/v2/<repo>/manifests/<ref>".
+func parseManifestPath(path string) (repo, ref string, ok bool) {
+	const prefix = "/v2/"
+	const infix = "/manifests/"
+
+	if !strings.HasPrefix(path, prefix) {
+		return "", "", false
+	}
+
+	rest := strings.TrimPrefix(path, prefix)
+	idx := strings.LastIndex(rest, infix)
+	if idx < 0 {
+		return "", "", false
+	}
+
+	return rest[:idx], rest[idx+len(infix):], true
+}
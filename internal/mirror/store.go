@@ -0,0 +1,175 @@
+// Package mirror implements an on-disk, pull-through cache of OCI manifests
+// so repeated admission of the same image doesn't send a fresh request
+// upstream for every pod, and so a shared PVC lets other replicas reuse
+// manifests one of them already fetched.
+package mirror
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store is an on-disk, content-addressable cache of manifest bytes keyed by
+// digest, plus a small ref table mapping "repo:tag"-style image references to
+// the digest they last resolved to. It evicts the least recently used
+// manifests once the total size on disk exceeds maxBytes.
+type Store struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*storeEntry
+	refs    map[string]string
+	size    int64
+}
+
+type storeEntry struct {
+	size       int64
+	accessedAt time.Time
+}
+
+// NewStore creates (if necessary) dir and returns a Store backed by it,
+// indexing any manifests already present so a restarted process reuses what
+// a shared PVC already holds instead of starting cold.
+func NewStore(dir string, maxBytes int64) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating mirror dir: %w", err)
+	}
+
+	s := &Store{
+		dir:      dir,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*storeEntry),
+		refs:     make(map[string]string),
+	}
+
+	if err := s.loadExisting(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Store) loadExisting() error {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("reading mirror dir: %w", err)
+	}
+
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		digest := filenameToDigest(f.Name())
+		s.entries[digest] = &storeEntry{size: info.Size(), accessedAt: info.ModTime()}
+		s.size += info.Size()
+	}
+
+	return nil
+}
+
+// Get returns the manifest bytes stored for digest, if present, and bumps its
+// recency for eviction purposes.
+func (s *Store) Get(digest string) ([]byte, bool) {
+	s.mu.Lock()
+	entry, ok := s.entries[digest]
+	if ok {
+		entry.accessedAt = time.Now()
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	body, err := os.ReadFile(s.path(digest))
+	if err != nil {
+		return nil, false
+	}
+	return body, true
+}
+
+// Put persists body under digest, evicting the least recently used manifests
+// until the store fits within maxBytes.
+func (s *Store) Put(digest string, body []byte) error {
+	if err := os.WriteFile(s.path(digest), body, 0o644); err != nil {
+		return fmt.Errorf("writing manifest %s: %w", digest, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.entries[digest]; ok {
+		s.size -= existing.size
+	}
+	s.entries[digest] = &storeEntry{size: int64(len(body)), accessedAt: time.Now()}
+	s.size += int64(len(body))
+
+	s.evictLocked()
+	return nil
+}
+
+// ResolveRef returns the digest that image last resolved to, if known.
+func (s *Store) ResolveRef(image string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	digest, ok := s.refs[image]
+	return digest, ok
+}
+
+// SetRef records that image currently resolves to digest.
+func (s *Store) SetRef(image, digest string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refs[image] = digest
+}
+
+// evictLocked removes the least recently accessed manifests until the store
+// fits within maxBytes. Callers must hold s.mu. A non-positive maxBytes
+// disables eviction.
+func (s *Store) evictLocked() {
+	if s.maxBytes <= 0 {
+		return
+	}
+
+	for s.size > s.maxBytes {
+		var oldest string
+		var oldestAt time.Time
+		for digest, entry := range s.entries {
+			if oldest == "" || entry.accessedAt.Before(oldestAt) {
+				oldest = digest
+				oldestAt = entry.accessedAt
+			}
+		}
+		if oldest == "" {
+			return
+		}
+
+		os.Remove(s.path(oldest))
+		s.size -= s.entries[oldest].size
+		delete(s.entries, oldest)
+	}
+}
+
+func (s *Store) path(digest string) string {
+	return filepath.Join(s.dir, digestToFilename(digest))
+}
+
+// digestToFilename and filenameToDigest translate between a manifest digest
+// ("sha256:<hex>") and its on-disk name, since ':' isn't valid in a file name
+// on every platform the mirror's PVC might be backed by.
+func digestToFilename(digest string) string {
+	return strings.Replace(digest, ":", "_", 1)
+}
+
+func filenameToDigest(name string) string {
+	return strings.Replace(name, "_", ":", 1)
+}
@@ -0,0 +1,100 @@
+package mirror
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T, maxBytes int64) *Store {
+	t.Helper()
+	store, err := NewStore(t.TempDir(), maxBytes)
+	require.NoError(t, err)
+	return store
+}
+
+func TestStore_PutGetRoundTrip(t *testing.T) {
+	store := newTestStore(t, 0)
+
+	err := store.Put("sha256:abc", []byte(`{"manifests":[]}`))
+	require.NoError(t, err)
+
+	body, ok := store.Get("sha256:abc")
+	require.True(t, ok)
+	assert.Equal(t, `{"manifests":[]}`, string(body))
+}
+
+func TestStore_GetMissingDigest(t *testing.T) {
+	store := newTestStore(t, 0)
+
+	_, ok := store.Get("sha256:doesnotexist")
+	assert.False(t, ok)
+}
+
+func TestStore_SetRefResolveRef(t *testing.T) {
+	store := newTestStore(t, 0)
+
+	_, ok := store.ResolveRef("myorg/app:latest")
+	assert.False(t, ok)
+
+	store.SetRef("myorg/app:latest", "sha256:abc")
+
+	digest, ok := store.ResolveRef("myorg/app:latest")
+	require.True(t, ok)
+	assert.Equal(t, "sha256:abc", digest)
+}
+
+func TestStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	store := newTestStore(t, 10)
+
+	require.NoError(t, store.Put("sha256:a", []byte("aaaaa")))
+	require.NoError(t, store.Put("sha256:b", []byte("bbbbb")))
+
+	// Touch "a" so "b" becomes the least recently used entry.
+	_, ok := store.Get("sha256:a")
+	require.True(t, ok)
+
+	require.NoError(t, store.Put("sha256:c", []byte("ccccc")))
+
+	_, aStillPresent := store.Get("sha256:a")
+	_, bStillPresent := store.Get("sha256:b")
+	_, cStillPresent := store.Get("sha256:c")
+
+	assert.True(t, aStillPresent)
+	assert.False(t, bStillPresent)
+	assert.True(t, cStillPresent)
+}
+
+func TestStore_ReloadsExistingManifestsFromDisk(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewStore(dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, store.Put("sha256:abc", []byte("hello")))
+
+	reopened, err := NewStore(dir, 0)
+	require.NoError(t, err)
+
+	body, ok := reopened.Get("sha256:abc")
+	require.True(t, ok)
+	assert.Equal(t, "hello", string(body))
+}
+
+func TestDigestFilenameRoundTrip(t *testing.T) {
+	digest := "sha256:abcdef"
+	assert.Equal(t, digest, filenameToDigest(digestToFilename(digest)))
+}
+
+func TestNewStore_CreatesMissingDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested", "mirror")
+
+	_, err := NewStore(dir, 0)
+	require.NoError(t, err)
+
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+}
@@ -0,0 +1,197 @@
+// Package ratelimit provides a key-scoped token-bucket rate limiter, used by
+// Mutator to keep an admission burst (e.g. a Deployment scaling from 0 to
+// 500 pods) from hammering the container registry or the credential
+// resolver's Kubernetes API calls.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter decides whether a call identified by key may proceed right now,
+// waiting up to ctx's deadline for a slot to free up if not. It's the
+// extension point Mutator gates cache-miss registry lookups behind;
+// TokenBucketLimiter is the only implementation today, but a distributed
+// backend (Redis, gubernator) sharing limits across every webhook replica
+// can implement the same interface later.
+type Limiter interface {
+	// Allow reports whether key may proceed, blocking up to ctx's deadline
+	// for a token to become available. A false return - including one
+	// caused by ctx expiring - means the caller must not proceed.
+	Allow(ctx context.Context, key string) bool
+}
+
+var _ Limiter = (*TokenBucketLimiter)(nil)
+
+// defaultIdleTTL is how long a key's bucket can go untouched before
+// cleanup removes it, for NewTokenBucketLimiter callers that don't need a
+// non-default value.
+const defaultIdleTTL = 10 * time.Minute
+
+// cleanupInterval is how often TokenBucketLimiter scans for idle buckets to
+// drop.
+const cleanupInterval = time.Minute
+
+// bucket is one key's token bucket: tokens refill continuously at rate
+// tokens/second, capped at burst, and lastUsed tracks idleness for cleanup.
+// Each bucket has its own mutex so concurrent callers for different keys
+// never contend with each other, only callers sharing the same key do.
+type bucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time
+	lastUsed time.Time
+}
+
+func newBucket(burst float64, now time.Time) *bucket {
+	return &bucket{tokens: burst, last: now, lastUsed: now}
+}
+
+// take reports whether a token is available at now, consuming one if so. If
+// not, it also returns how long until the next token refills, so the caller
+// can wait rather than denying immediately on a momentary burst.
+func (b *bucket) take(now time.Time, rate, burst float64) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	b.lastUsed = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	missing := 1 - b.tokens
+	return false, time.Duration(missing / rate * float64(time.Second))
+}
+
+func (b *bucket) idleFor(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.Sub(b.lastUsed)
+}
+
+// TokenBucketLimiter is an in-memory Limiter. Each distinct key (Mutator
+// uses "namespace/repo") gets its own token bucket of burst capacity,
+// refilling at rate tokens/second; a key untouched for longer than idleTTL
+// has its bucket dropped the next time the background cleanup loop runs, so
+// a webhook that sees many distinct keys over its lifetime doesn't grow
+// memory without bound. Buckets are stored behind a single map guarded by a
+// mutex only for the lookup/insert itself; the actual token accounting for
+// a key happens under that bucket's own mutex, so concurrent callers for
+// different keys don't serialize on each other.
+type TokenBucketLimiter struct {
+	rate  float64
+	burst float64
+
+	idleTTL time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewTokenBucketLimiter creates a TokenBucketLimiter allowing up to burst
+// calls immediately for a previously-idle key, then rate calls per second
+// thereafter, cleaning up buckets idle for longer than defaultIdleTTL. Use
+// NewTokenBucketLimiterWithIdleTTL to override that.
+func NewTokenBucketLimiter(rate float64, burst int) *TokenBucketLimiter {
+	return NewTokenBucketLimiterWithIdleTTL(rate, burst, defaultIdleTTL)
+}
+
+// NewTokenBucketLimiterWithIdleTTL creates a TokenBucketLimiter exactly like
+// NewTokenBucketLimiter, but dropping a key's bucket once it's gone idleTTL
+// without an Allow call, instead of the default 10 minutes.
+func NewTokenBucketLimiterWithIdleTTL(rate float64, burst int, idleTTL time.Duration) *TokenBucketLimiter {
+	l := &TokenBucketLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		idleTTL: idleTTL,
+		buckets: make(map[string]*bucket),
+		stop:    make(chan struct{}),
+	}
+	l.wg.Add(1)
+	go l.cleanupLoop()
+	return l
+}
+
+func (l *TokenBucketLimiter) bucketFor(key string, now time.Time) *bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newBucket(l.burst, now)
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// Allow reports whether key has a token available, waiting in short steps
+// for the bucket to refill one if not, up to ctx's own deadline. It never
+// waits past that deadline, so a caller that bounds ctx always gets a
+// bounded wait; a ctx with no deadline waits as long as it takes for a
+// token to refill (1/rate seconds for the next token, longer when other
+// callers of the same key take the refilled tokens first).
+func (l *TokenBucketLimiter) Allow(ctx context.Context, key string) bool {
+	now := time.Now()
+	b := l.bucketFor(key, now)
+
+	for {
+		ok, wait := b.take(now, l.rate, l.burst)
+		if ok {
+			return true
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case now = <-timer.C:
+		}
+	}
+}
+
+func (l *TokenBucketLimiter) cleanupLoop() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.cleanup(time.Now())
+		}
+	}
+}
+
+func (l *TokenBucketLimiter) cleanup(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, b := range l.buckets {
+		if b.idleFor(now) > l.idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Stop halts the background idle-bucket cleanup goroutine. It doesn't
+// affect in-flight Allow calls.
+func (l *TokenBucketLimiter) Stop() {
+	close(l.stop)
+	l.wg.Wait()
+}
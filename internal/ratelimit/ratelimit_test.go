@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucketLimiter_AllowsBurstThenDeniesWithinSamePeriod(t *testing.T) {
+	l := NewTokenBucketLimiter(1, 2)
+	defer l.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.True(t, l.Allow(ctx, "ns/nginx"))
+	assert.True(t, l.Allow(ctx, "ns/nginx"))
+	assert.False(t, l.Allow(ctx, "ns/nginx"))
+}
+
+func TestTokenBucketLimiter_DistinctKeysHaveIndependentBuckets(t *testing.T) {
+	l := NewTokenBucketLimiter(1, 1)
+	defer l.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.True(t, l.Allow(ctx, "ns/nginx"))
+	assert.True(t, l.Allow(ctx, "ns/busybox"))
+}
+
+func TestTokenBucketLimiter_WaitsForRefillWithinDeadline(t *testing.T) {
+	l := NewTokenBucketLimiter(100, 1)
+	defer l.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.True(t, l.Allow(ctx, "ns/nginx"))
+	// The bucket is now empty; at 100 tokens/sec a new one refills in
+	// ~10ms, well inside the 1s deadline, so this should wait rather than
+	// deny outright.
+	start := time.Now()
+	assert.True(t, l.Allow(ctx, "ns/nginx"))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestTokenBucketLimiter_DeniesOnceContextExpires(t *testing.T) {
+	l := NewTokenBucketLimiter(0.001, 1)
+	defer l.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assert.True(t, l.Allow(ctx, "ns/nginx"))
+	assert.False(t, l.Allow(ctx, "ns/nginx"))
+}
+
+func TestTokenBucketLimiter_CleanupDropsIdleBuckets(t *testing.T) {
+	l := NewTokenBucketLimiterWithIdleTTL(1, 1, 10*time.Millisecond)
+	defer l.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.True(t, l.Allow(ctx, "ns/nginx"))
+
+	l.cleanup(time.Now().Add(time.Hour))
+
+	l.mu.Lock()
+	_, exists := l.buckets["ns/nginx"]
+	l.mu.Unlock()
+	assert.False(t, exists, "idle bucket should have been dropped")
+}
@@ -5,11 +5,46 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/lsdopen/archy/internal/credentials"
 	"github.com/lsdopen/archy/pkg/types"
 )
 
 // NewClient creates a new registry client based on the registry URL
 func NewClient(registryURL string) (types.RegistryClient, error) {
+	return NewClientWithCredentials(registryURL, nil)
+}
+
+// NewClientWithCredentials creates a registry client for registryURL, dispatching
+// on hostname the same way NewClient does, but authenticating requests with
+// cred when the registry challenges for Basic or Bearer auth. This is the
+// factory Mutator.detectArchitectures uses once credentials have been resolved
+// for an image, instead of hard-coding registry.NewDockerHubClient.
+func NewClientWithCredentials(registryURL string, cred *credentials.RegistryCredential) (types.RegistryClient, error) {
+	parsedURL, err := normalizeRegistryURL(registryURL)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname := parsedURL.Hostname()
+	switch {
+	case strings.Contains(hostname, "docker.io") || strings.Contains(hostname, "registry-1.docker.io"):
+		if cred == nil {
+			return NewDockerHubClient(), nil
+		}
+		return NewDockerHubClientWithCredentials(cred.Username, cred.Password), nil
+	default:
+		// Any other host is assumed to speak the OCI Distribution Spec v2,
+		// which covers ECR, GCR, ACR, Quay, Harbor, GHCR and self-hosted
+		// distribution instances.
+		base := parsedURL.Scheme + "://" + parsedURL.Host
+		if cred == nil {
+			return NewGenericClient(base), nil
+		}
+		return NewGenericClientWithCredentials(base, cred), nil
+	}
+}
+
+func normalizeRegistryURL(registryURL string) (*url.URL, error) {
 	if registryURL == "" {
 		return nil, fmt.Errorf("registry URL cannot be empty")
 	}
@@ -33,12 +68,5 @@ func NewClient(registryURL string) (types.RegistryClient, error) {
 		return nil, fmt.Errorf("unsupported URL scheme: %s", parsedURL.Scheme)
 	}
 
-	// Determine registry type based on hostname
-	hostname := parsedURL.Hostname()
-	switch {
-	case strings.Contains(hostname, "docker.io") || strings.Contains(hostname, "registry-1.docker.io"):
-		return NewDockerHubClient(), nil
-	default:
-		return nil, fmt.Errorf("unsupported registry: %s", hostname)
-	}
-}
\ No newline at end of file
+	return parsedURL, nil
+}
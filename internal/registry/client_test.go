@@ -45,30 +45,29 @@ func TestClientFactory_InvalidRegistryURLs(t *testing.T) {
 	}
 }
 
-func TestClientFactory_UnsupportedRegistryTypes(t *testing.T) {
+func TestClientFactory_GenericRegistryFallback(t *testing.T) {
+	// Hosts that aren't specifically recognized fall back to the generic
+	// OCI Distribution v2 client rather than erroring.
 	tests := []struct {
-		name    string
-		url     string
-		wantErr string
+		name string
+		url  string
 	}{
 		{
-			name:    "unsupported registry",
-			url:     "https://unsupported-registry.com",
-			wantErr: "unsupported registry",
+			name: "self-hosted distribution instance",
+			url:  "https://unsupported-registry.com",
 		},
 		{
-			name:    "unknown registry domain",
-			url:     "https://unknown.registry.example.com",
-			wantErr: "unsupported registry",
+			name: "unknown registry domain",
+			url:  "https://unknown.registry.example.com",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client, err := NewClient(tt.url)
-			require.Error(t, err)
-			assert.Nil(t, client)
-			assert.Contains(t, err.Error(), tt.wantErr)
+			require.NoError(t, err)
+			require.NotNil(t, client)
+			assert.IsType(t, &GenericClient{}, client)
 		})
 	}
 }
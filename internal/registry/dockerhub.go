@@ -3,16 +3,44 @@ package registry
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/lsdopen/archy/pkg/inspector"
 )
 
+// ErrRateLimited is returned when a registry responds 429 Too Many Requests.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// ErrUnauthorized is returned when a registry responds 403 Forbidden, i.e.
+// the presented (or absent) credential isn't allowed to read the repository.
+var ErrUnauthorized = errors.New("access denied to repository")
+
+// ErrNotFound is returned when a registry responds 404 Not Found for a
+// manifest request.
+var ErrNotFound = errors.New("image not found")
+
 // DockerHubClient implements registry client for Docker Hub
 type DockerHubClient struct {
 	baseURL    string
 	httpClient *http.Client
+	username   string
+	password   string
+
+	// maxRetryAfterWait caps how long fetchManifestBytes will sleep between
+	// attempts when the registry responds 429/503, whether driven by its
+	// Retry-After header or our own backoff. Zero uses
+	// defaultMaxRetryAfterWait.
+	maxRetryAfterWait time.Duration
+
+	mu     sync.Mutex
+	tokens map[string]*bearerToken
 }
 
 // DockerHubManifest represents Docker Hub manifest list response
@@ -32,9 +60,20 @@ func NewDockerHubClient() *DockerHubClient {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		tokens: make(map[string]*bearerToken),
 	}
 }
 
+// NewDockerHubClientWithCredentials creates a Docker Hub client that
+// authenticates using the given username/password when the registry
+// challenges for Basic or Bearer auth (resolved via credentials.Resolver).
+func NewDockerHubClientWithCredentials(username, password string) *DockerHubClient {
+	c := NewDockerHubClient()
+	c.username = username
+	c.password = password
+	return c
+}
+
 // GetSupportedArchitectures retrieves supported architectures for an image
 func (c *DockerHubClient) GetSupportedArchitectures(ctx context.Context, image string) ([]string, error) {
 	// Parse image reference
@@ -43,105 +82,345 @@ func (c *DockerHubClient) GetSupportedArchitectures(ctx context.Context, image s
 		return []string{"amd64"}, nil // Default fallback
 	}
 
-	// Build manifest URL
-	url := fmt.Sprintf("%s/v2/%s/manifests/%s", c.baseURL, repo, tag)
+	scope := fmt.Sprintf("repository:%s:pull", repo)
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", c.baseURL, repo, tag)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	manifest, failOpen, err := c.fetchManifest(ctx, manifestURL, scope)
 	if err != nil {
+		return nil, err
+	}
+	if failOpen {
 		return []string{"amd64"}, nil // Fail open
 	}
 
-	// Set headers for manifest list
-	req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.list.v2+json")
+	// Extract architectures
+	var architectures []string
+	seen := make(map[string]bool)
 
-	resp, err := c.httpClient.Do(req)
+	for _, m := range manifest.Manifests {
+		arch := m.Platform.Architecture
+		if arch != "" && !seen[arch] {
+			architectures = append(architectures, arch)
+			seen[arch] = true
+		}
+	}
+
+	if len(architectures) == 0 {
+		return []string{"amd64"}, nil // Default fallback
+	}
+
+	return architectures, nil
+}
+
+// GetSupportedPlatforms retrieves the full set of platforms (architecture,
+// OS, variant) an image supports, richer than GetSupportedArchitectures:
+// it understands OCI image indexes and single (non-list) manifests via
+// ManifestParser, fetching the referenced config blob for the latter.
+func (c *DockerHubClient) GetSupportedPlatforms(ctx context.Context, image string) ([]inspector.Platform, error) {
+	repo, tag := parseImageReference(image)
+	if repo == "" {
+		return []inspector.Platform{{Architecture: "amd64"}}, nil // Default fallback
+	}
+
+	scope := fmt.Sprintf("repository:%s:pull", repo)
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", c.baseURL, repo, tag)
+
+	data, failOpen, err := c.fetchManifestBytes(ctx, manifestURL, scope)
 	if err != nil {
-		if strings.Contains(err.Error(), "timeout") {
-			return nil, fmt.Errorf("request timeout: %w", err)
+		return nil, err
+	}
+	if failOpen {
+		return []inspector.Platform{{Architecture: "amd64"}}, nil // Fail open
+	}
+
+	parser := NewManifestParser()
+	platforms, err := parser.ParsePlatformsWithConfigFetcher(ctx, data, func(ctx context.Context, digest string) ([]byte, error) {
+		return c.fetchBlob(ctx, repo, digest, scope)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(platforms) == 0 {
+		return []inspector.Platform{{Architecture: "amd64"}}, nil // Default fallback
+	}
+
+	return platforms, nil
+}
+
+// fetchManifest GETs manifestURL, transparently resolving a bearer token and
+// retrying once if the registry challenges with 401, and decodes the
+// resulting manifest list. failOpen reports that the caller should degrade
+// to the default architecture list rather than treating this as an error,
+// matching the client's existing fail-open behavior for request-building
+// and transient network failures (but not for the classified error statuses
+// below, which callers need to distinguish for fail-open/fail-closed
+// admission policy).
+func (c *DockerHubClient) fetchManifest(ctx context.Context, manifestURL, scope string) (manifest *DockerHubManifest, failOpen bool, err error) {
+	data, failOpen, err := c.fetchManifestBytes(ctx, manifestURL, scope)
+	if err != nil || failOpen {
+		return nil, failOpen, err
+	}
+
+	var decoded DockerHubManifest
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, false, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	return &decoded, false, nil
+}
+
+// fetchManifestBytes is fetchManifest's request/auth/status-classification
+// logic, stopping short of decoding the body into DockerHubManifest so
+// GetSupportedPlatforms can instead hand the raw bytes to ManifestParser,
+// which understands manifest shapes (OCI indexes, single manifests)
+// DockerHubManifest doesn't model.
+func (c *DockerHubClient) fetchManifestBytes(ctx context.Context, manifestURL, scope string) (data []byte, failOpen bool, err error) {
+	var resp *http.Response
+
+	for attempt := 1; ; attempt++ {
+		var reqErr error
+		resp, reqErr = c.doManifestRequest(ctx, manifestURL, scope)
+		if reqErr != nil {
+			if strings.Contains(reqErr.Error(), "timeout") {
+				return nil, false, fmt.Errorf("request timeout: %w", reqErr)
+			}
+			return nil, true, nil
 		}
-		return []string{"amd64"}, nil // Fail open
+
+		if resp.StatusCode == http.StatusUnauthorized {
+			if authErr := c.authenticate(ctx, resp, scope); authErr != nil {
+				resp.Body.Close()
+				return nil, false, authErr
+			}
+			resp.Body.Close()
+
+			resp, reqErr = c.doManifestRequest(ctx, manifestURL, scope)
+			if reqErr != nil {
+				return nil, false, fmt.Errorf("retrying manifest request after authentication: %w", reqErr)
+			}
+		}
+
+		if retryableStatus(resp.StatusCode) && attempt < maxRetryAfterAttempts {
+			wait := retryAfterBackoff(resp.Header.Get("Retry-After"), attempt, c.maxRetryAfterWait)
+			resp.Body.Close()
+			if waitErr := sleepOrDone(ctx, wait); waitErr != nil {
+				return nil, false, waitErr
+			}
+			continue
+		}
+
+		break
 	}
 	defer resp.Body.Close()
 
-	// Handle different response codes
 	switch resp.StatusCode {
 	case http.StatusTooManyRequests:
-		return nil, fmt.Errorf("rate limit exceeded")
-	case http.StatusUnauthorized:
-		// Try to retry once (simulate token refresh)
-		return c.retryWithAuth(ctx, url)
+		return nil, false, fmt.Errorf("%w", ErrRateLimited)
 	case http.StatusForbidden:
-		return nil, fmt.Errorf("access denied to repository")
+		return nil, false, fmt.Errorf("%w", ErrUnauthorized)
 	case http.StatusNotFound:
-		return nil, fmt.Errorf("image not found")
+		return nil, false, fmt.Errorf("%w", ErrNotFound)
 	case http.StatusBadRequest:
-		return nil, fmt.Errorf("API version not supported")
+		return nil, false, fmt.Errorf("API version not supported")
 	case http.StatusOK:
 		// Continue processing
 	default:
-		return []string{"amd64"}, nil // Fail open
+		return nil, true, nil // Fail open
 	}
 
-	var manifest DockerHubManifest
-	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
-		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("reading manifest body: %w", err)
 	}
 
-	// Extract architectures
-	var architectures []string
-	seen := make(map[string]bool)
-	
-	for _, m := range manifest.Manifests {
-		arch := m.Platform.Architecture
-		if arch != "" && !seen[arch] {
-			architectures = append(architectures, arch)
-			seen[arch] = true
+	return body, false, nil
+}
+
+// fetchBlob GETs a repository blob by digest (e.g. the config blob a single
+// image manifest references), reusing the same bearer-token auth as
+// manifest requests since blob pulls are authorized under the same
+// "repository:<repo>:pull" scope.
+func (c *DockerHubClient) fetchBlob(ctx context.Context, repo, digest, scope string) ([]byte, error) {
+	blobURL := fmt.Sprintf("%s/v2/%s/blobs/%s", c.baseURL, repo, digest)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, blobURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building blob request: %w", err)
+	}
+	c.authorize(req, scope)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching blob %s: %w", digest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		if authErr := c.authenticate(ctx, resp, scope); authErr != nil {
+			return nil, authErr
+		}
+		resp.Body.Close()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, blobURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("building blob request: %w", err)
 		}
+		c.authorize(req, scope)
+
+		resp, err = c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("retrying blob request after authentication: %w", err)
+		}
+		defer resp.Body.Close()
 	}
 
-	if len(architectures) == 0 {
-		return []string{"amd64"}, nil // Default fallback
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("blob request for %s returned status %d", digest, resp.StatusCode)
 	}
 
-	return architectures, nil
+	return io.ReadAll(resp.Body)
+}
+
+func (c *DockerHubClient) doManifestRequest(ctx context.Context, manifestURL, scope string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building manifest request: %w", err)
+	}
+
+	req.Header.Set("Accept", strings.Join([]string{
+		"application/vnd.docker.distribution.manifest.list.v2+json",
+		"application/vnd.oci.image.index.v1+json",
+		"application/vnd.docker.distribution.manifest.v2+json",
+		"application/vnd.oci.image.manifest.v1+json",
+	}, ", "))
+
+	c.authorize(req, scope)
+
+	return c.httpClient.Do(req)
+}
+
+// authorize attaches whatever credential the client currently holds for
+// scope: a cached, unexpired bearer token if present, otherwise Basic auth
+// from the configured username/password.
+func (c *DockerHubClient) authorize(req *http.Request, scope string) {
+	if token := c.cachedToken(scope); token != nil {
+		req.Header.Set("Authorization", "Bearer "+token.value)
+		return
+	}
+
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+}
+
+func (c *DockerHubClient) cachedToken(scope string) *bearerToken {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	token := c.tokens[scope]
+	if token == nil || !time.Now().Before(token.expiry) {
+		return nil
+	}
+	return token
+}
+
+// authenticate parses the WWW-Authenticate challenge from a 401 response,
+// resolves a bearer token via the realm's OAuth2 token endpoint, and caches
+// it keyed by scope (which embeds the service, since Docker Hub's challenge
+// scope is already repository-qualified) so subsequent requests for the
+// same repository skip the round trip until the token expires.
+func (c *DockerHubClient) authenticate(ctx context.Context, resp *http.Response, scope string) error {
+	challenge := resp.Header.Get("WWW-Authenticate")
+	if challenge == "" {
+		return fmt.Errorf("authentication required but no challenge presented")
+	}
+
+	if strings.HasPrefix(strings.ToLower(challenge), "basic") {
+		if c.username == "" {
+			return fmt.Errorf("registry requires basic auth but no credential is available")
+		}
+		return nil
+	}
+
+	bc, err := parseBearerChallenge(challenge)
+	if err != nil {
+		return err
+	}
+	if bc.scope == "" {
+		bc.scope = scope
+	}
+
+	token, err := c.requestToken(ctx, bc)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.tokens[scope] = token
+	c.mu.Unlock()
+
+	return nil
 }
 
-func (c *DockerHubClient) retryWithAuth(ctx context.Context, url string) ([]string, error) {
-	// Simulate token refresh and retry
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+func (c *DockerHubClient) requestToken(ctx context.Context, bc bearerChallenge) (*bearerToken, error) {
+	tokenURL, err := url.Parse(bc.realm)
 	if err != nil {
-		return []string{"amd64"}, nil
+		return nil, fmt.Errorf("invalid token realm %q: %w", bc.realm, err)
+	}
+
+	q := tokenURL.Query()
+	if bc.service != "" {
+		q.Set("service", bc.service)
+	}
+	if bc.scope != "" {
+		q.Set("scope", bc.scope)
+	}
+	tokenURL.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("building token request: %w", err)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
 	}
 
-	req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.list.v2+json")
-	
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return []string{"amd64"}, nil
+		return nil, fmt.Errorf("requesting token: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return []string{"amd64"}, nil
+		return nil, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
 	}
 
-	var manifest DockerHubManifest
-	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
-		return []string{"amd64"}, nil
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding token response: %w", err)
 	}
 
-	var architectures []string
-	for _, m := range manifest.Manifests {
-		if m.Platform.Architecture != "" {
-			architectures = append(architectures, m.Platform.Architecture)
-		}
+	value := body.Token
+	if value == "" {
+		value = body.AccessToken
+	}
+	if value == "" {
+		return nil, fmt.Errorf("token response contained no token")
 	}
 
-	if len(architectures) == 0 {
-		return []string{"amd64"}, nil
+	expiresIn := body.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = 60
 	}
 
-	return architectures, nil
+	return &bearerToken{
+		value:  value,
+		expiry: time.Now().Add(time.Duration(expiresIn) * time.Second),
+	}, nil
 }
 
 func parseImageReference(image string) (string, string) {
@@ -153,7 +432,7 @@ func parseImageReference(image string) (string, string) {
 	parts := strings.Split(image, ":")
 	repo := parts[0]
 	tag := "latest"
-	
+
 	if len(parts) > 1 {
 		tag = parts[1]
 	}
@@ -164,4 +443,4 @@ func parseImageReference(image string) (string, string) {
 	}
 
 	return repo, tag
-}
\ No newline at end of file
+}
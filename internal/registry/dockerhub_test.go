@@ -2,33 +2,69 @@ package registry
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/lsdopen/archy/pkg/inspector"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestDockerHubClient_APIRateLimiting(t *testing.T) {
+	var requests int32
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
 		w.WriteHeader(http.StatusTooManyRequests)
 		w.Write([]byte(`{"errors":[{"code":"TOOMANYREQUESTS","message":"Too Many Requests"}]}`))
 	}))
 	defer server.Close()
 
 	client := &DockerHubClient{
-		baseURL:    server.URL,
-		httpClient: &http.Client{Timeout: 5 * time.Second},
+		baseURL:           server.URL,
+		httpClient:        &http.Client{Timeout: 5 * time.Second},
+		maxRetryAfterWait: 10 * time.Millisecond,
 	}
 
 	ctx := context.Background()
 	archs, err := client.GetSupportedArchitectures(ctx, "nginx:latest")
-	
+
 	require.Error(t, err)
 	assert.Nil(t, archs)
 	assert.Contains(t, err.Error(), "rate limit")
+	assert.EqualValues(t, maxRetryAfterAttempts, atomic.LoadInt32(&requests),
+		"expected the client to retry up to the attempt limit before giving up")
+}
+
+func TestDockerHubClient_APIRateLimiting_RetriesAfterRetryAfterHeader(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"manifests":[{"platform":{"architecture":"arm64"}}]}`))
+	}))
+	defer server.Close()
+
+	client := &DockerHubClient{
+		baseURL:           server.URL,
+		httpClient:        &http.Client{Timeout: 5 * time.Second},
+		maxRetryAfterWait: time.Second,
+	}
+
+	ctx := context.Background()
+	archs, err := client.GetSupportedArchitectures(ctx, "nginx:latest")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arm64"}, archs)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests))
 }
 
 func TestDockerHubClient_NetworkTimeouts(t *testing.T) {
@@ -45,7 +81,7 @@ func TestDockerHubClient_NetworkTimeouts(t *testing.T) {
 
 	ctx := context.Background()
 	archs, err := client.GetSupportedArchitectures(ctx, "nginx:latest")
-	
+
 	require.Error(t, err)
 	assert.Nil(t, archs)
 	assert.Contains(t, err.Error(), "timeout")
@@ -66,41 +102,169 @@ func TestDockerHubClient_MalformedJSONResponse(t *testing.T) {
 
 	ctx := context.Background()
 	archs, err := client.GetSupportedArchitectures(ctx, "nginx:latest")
-	
+
 	require.Error(t, err)
 	assert.Nil(t, archs)
 	assert.Contains(t, err.Error(), "invalid character")
 }
 
 func TestDockerHubClient_AuthenticationTokenExpiry(t *testing.T) {
-	callCount := 0
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		callCount++
-		if callCount == 1 {
-			// First call - token expired
+	var server *httptest.Server
+	var manifestCalls, tokenCalls int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		tokenCalls++
+		assert.Equal(t, "registry.docker.io", r.URL.Query().Get("service"))
+		assert.Equal(t, "repository:library/nginx:pull", r.URL.Query().Get("scope"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"token":"test-token","expires_in":60}`))
+	})
+	mux.HandleFunc("/v2/library/nginx/manifests/latest", func(w http.ResponseWriter, r *http.Request) {
+		manifestCalls++
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Bearer realm="%s/token",service="registry.docker.io",scope="repository:library/nginx:pull"`, server.URL))
 			w.WriteHeader(http.StatusUnauthorized)
 			w.Write([]byte(`{"errors":[{"code":"UNAUTHORIZED","message":"authentication required"}]}`))
-		} else {
-			// Second call - success after token refresh
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"manifests":[{"platform":{"architecture":"amd64"}}]}`))
+			return
 		}
-	}))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"manifests":[{"platform":{"architecture":"amd64"}}]}`))
+	})
+	server = httptest.NewServer(mux)
 	defer server.Close()
 
 	client := &DockerHubClient{
 		baseURL:    server.URL,
 		httpClient: &http.Client{Timeout: 5 * time.Second},
+		tokens:     make(map[string]*bearerToken),
 	}
 
 	ctx := context.Background()
 	archs, err := client.GetSupportedArchitectures(ctx, "nginx:latest")
-	
-	// Should retry and succeed
+
+	// Should resolve a bearer token from the challenge and retry
 	require.NoError(t, err)
 	assert.Equal(t, []string{"amd64"}, archs)
-	assert.Equal(t, 2, callCount)
+	assert.Equal(t, 2, manifestCalls)
+	assert.Equal(t, 1, tokenCalls)
+}
+
+func TestDockerHubClient_TokenCachedAcrossRequests(t *testing.T) {
+	var server *httptest.Server
+	var tokenCalls int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		tokenCalls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"token":"test-token","expires_in":60}`))
+	})
+	mux.HandleFunc("/v2/library/nginx/manifests/latest", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Bearer realm="%s/token",service="registry.docker.io",scope="repository:library/nginx:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"manifests":[{"platform":{"architecture":"amd64"}}]}`))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	client := &DockerHubClient{
+		baseURL:    server.URL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		tokens:     make(map[string]*bearerToken),
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		archs, err := client.GetSupportedArchitectures(ctx, "nginx:latest")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"amd64"}, archs)
+	}
+
+	assert.Equal(t, 1, tokenCalls, "expected the cached token to be reused across requests")
+}
+
+func TestDockerHubClient_RateLimitedIsErrRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := &DockerHubClient{
+		baseURL:    server.URL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		tokens:     make(map[string]*bearerToken),
+	}
+
+	_, err := client.GetSupportedArchitectures(context.Background(), "nginx:latest")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrRateLimited)
+}
+
+func TestDockerHubClient_ForbiddenIsErrUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := &DockerHubClient{
+		baseURL:    server.URL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		tokens:     make(map[string]*bearerToken),
+	}
+
+	_, err := client.GetSupportedArchitectures(context.Background(), "nginx:latest")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestDockerHubClient_NotFoundIsErrNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := &DockerHubClient{
+		baseURL:    server.URL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		tokens:     make(map[string]*bearerToken),
+	}
+
+	_, err := client.GetSupportedArchitectures(context.Background(), "nginx:latest")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDockerHubClient_ManifestRequestAcceptsOCIIndex(t *testing.T) {
+	var acceptHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptHeader = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"manifests":[{"platform":{"architecture":"amd64"}}]}`))
+	}))
+	defer server.Close()
+
+	client := &DockerHubClient{
+		baseURL:    server.URL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		tokens:     make(map[string]*bearerToken),
+	}
+
+	_, err := client.GetSupportedArchitectures(context.Background(), "nginx:latest")
+	require.NoError(t, err)
+	assert.Contains(t, acceptHeader, "application/vnd.docker.distribution.manifest.list.v2+json")
+	assert.Contains(t, acceptHeader, "application/vnd.oci.image.index.v1+json")
 }
 
 func TestDockerHubClient_PrivateRepositoryAccessDenied(t *testing.T) {
@@ -117,7 +281,7 @@ func TestDockerHubClient_PrivateRepositoryAccessDenied(t *testing.T) {
 
 	ctx := context.Background()
 	archs, err := client.GetSupportedArchitectures(ctx, "private/repo:latest")
-	
+
 	require.Error(t, err)
 	assert.Nil(t, archs)
 	assert.Contains(t, err.Error(), "access denied")
@@ -137,7 +301,7 @@ func TestDockerHubClient_NonExistentImage(t *testing.T) {
 
 	ctx := context.Background()
 	archs, err := client.GetSupportedArchitectures(ctx, "nonexistent/image:latest")
-	
+
 	require.Error(t, err)
 	assert.Nil(t, archs)
 	assert.Contains(t, err.Error(), "not found")
@@ -158,7 +322,7 @@ func TestDockerHubClient_RegistryAPIVersionChanges(t *testing.T) {
 
 	ctx := context.Background()
 	archs, err := client.GetSupportedArchitectures(ctx, "nginx:latest")
-	
+
 	require.Error(t, err)
 	assert.Nil(t, archs)
 	assert.Contains(t, err.Error(), "API version")
@@ -185,7 +349,7 @@ func TestDockerHubClient_ConcurrentAPICalls(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		go func() {
 			defer func() { done <- true }()
-			
+
 			ctx := context.Background()
 			_, err := client.GetSupportedArchitectures(ctx, "nginx:latest")
 			if err != nil {
@@ -233,7 +397,87 @@ func TestDockerHubClient_LargeManifestHandling(t *testing.T) {
 
 	ctx := context.Background()
 	archs, err := client.GetSupportedArchitectures(ctx, "nginx:latest")
-	
+
 	require.NoError(t, err)
 	assert.Equal(t, []string{"amd64"}, archs)
-}
\ No newline at end of file
+}
+
+func TestDockerHubClient_GetSupportedPlatforms_OCIIndex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"mediaType": "application/vnd.oci.image.index.v1+json",
+			"manifests": [
+				{"platform": {"architecture": "amd64", "os": "linux"}},
+				{"platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	client := &DockerHubClient{
+		baseURL:    server.URL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+
+	platforms, err := client.GetSupportedPlatforms(context.Background(), "nginx:latest")
+
+	require.NoError(t, err)
+	assert.Equal(t, []inspector.Platform{
+		{Architecture: "amd64", OS: "linux"},
+		{Architecture: "arm64", OS: "linux", Variant: "v8"},
+	}, platforms)
+}
+
+func TestDockerHubClient_GetSupportedPlatforms_SingleManifestFetchesConfigBlob(t *testing.T) {
+	var blobRequests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/library/nginx/manifests/latest", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"schemaVersion": 2,
+			"mediaType": "application/vnd.oci.image.manifest.v1+json",
+			"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": "sha256:deadbeef", "size": 123}
+		}`))
+	})
+	mux.HandleFunc("/v2/library/nginx/blobs/sha256:deadbeef", func(w http.ResponseWriter, r *http.Request) {
+		blobRequests = append(blobRequests, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"architecture": "arm64", "os": "linux", "variant": "v8"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := &DockerHubClient{
+		baseURL:    server.URL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+
+	platforms, err := client.GetSupportedPlatforms(context.Background(), "nginx:latest")
+
+	require.NoError(t, err)
+	require.Len(t, blobRequests, 1)
+	assert.Equal(t, []inspector.Platform{{Architecture: "arm64", OS: "linux", Variant: "v8"}}, platforms)
+}
+
+func TestDockerHubClient_GetSupportedPlatforms_RateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"errors":[{"code":"TOOMANYREQUESTS","message":"Too Many Requests"}]}`))
+	}))
+	defer server.Close()
+
+	client := &DockerHubClient{
+		baseURL:    server.URL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+
+	platforms, err := client.GetSupportedPlatforms(context.Background(), "nginx:latest")
+
+	require.Error(t, err)
+	assert.Nil(t, platforms)
+	assert.Contains(t, err.Error(), "rate limit")
+}
@@ -0,0 +1,566 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lsdopen/archy/internal/credentials"
+	"github.com/lsdopen/archy/internal/verify"
+)
+
+// GenericClient implements types.RegistryClient against any registry that
+// speaks the OCI Distribution Specification v2, including the bearer-token
+// challenge/response flow used by ECR, GCR, ACR, Quay, Harbor, GHCR and
+// self-hosted distribution instances.
+type GenericClient struct {
+	baseURL    string
+	httpClient *http.Client
+	credential *credentials.RegistryCredential
+
+	// maxRetryAfterWait caps how long getManifest will sleep between
+	// attempts when the registry responds 429/503, whether driven by its
+	// Retry-After header or our own backoff. Zero uses
+	// defaultMaxRetryAfterWait.
+	maxRetryAfterWait time.Duration
+
+	mu    sync.Mutex
+	token *bearerToken
+}
+
+type bearerToken struct {
+	value  string
+	expiry time.Time
+}
+
+type bearerChallenge struct {
+	realm   string
+	service string
+	scope   string
+}
+
+// NewGenericClient creates a client for an arbitrary OCI Distribution v2
+// registry reachable at baseURL (e.g. "https://ghcr.io").
+func NewGenericClient(baseURL string) *GenericClient {
+	return &GenericClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// NewGenericClientWithCredentials creates a generic client that presents the
+// given credential when the registry challenges for Basic or Bearer auth.
+func NewGenericClientWithCredentials(baseURL string, cred *credentials.RegistryCredential) *GenericClient {
+	c := NewGenericClient(baseURL)
+	c.credential = cred
+	return c
+}
+
+// GetSupportedArchitectures retrieves supported architectures for an image by
+// walking the OCI Distribution v2 manifest endpoint.
+func (c *GenericClient) GetSupportedArchitectures(ctx context.Context, image string) ([]string, error) {
+	repo, tag := parseImageReference(image)
+	if repo == "" {
+		return []string{"amd64"}, nil // Default fallback
+	}
+
+	scope := fmt.Sprintf("repository:%s:pull", repo)
+
+	manifest, err := c.fetchManifest(ctx, repo, tag, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var architectures []string
+	seen := make(map[string]bool)
+	for _, m := range manifest.Manifests {
+		arch := m.Platform.Architecture
+		if arch != "" && !seen[arch] {
+			architectures = append(architectures, arch)
+			seen[arch] = true
+		}
+	}
+
+	if len(architectures) == 0 {
+		return []string{"amd64"}, nil // Default fallback
+	}
+
+	return architectures, nil
+}
+
+// FetchSignature retrieves the Cosign signature bundle for image, stored at
+// the conventional "<repo>:sha256-<hex>.sig" tag (the legacy tag-based
+// Cosign layout), and returns the repository name and manifest digest it
+// covers alongside the raw signature manifest bytes. It implements
+// verify.SignatureFetcher.
+func (c *GenericClient) FetchSignature(ctx context.Context, image string) (string, string, []byte, error) {
+	repo, tag := parseImageReference(image)
+	if repo == "" {
+		return "", "", nil, fmt.Errorf("invalid image reference: %s", image)
+	}
+
+	scope := fmt.Sprintf("repository:%s:pull", repo)
+
+	digest, err := c.fetchManifestDigest(ctx, repo, tag, scope)
+	if err != nil {
+		return repo, "", nil, fmt.Errorf("resolving manifest digest: %w", err)
+	}
+
+	sigTag := verify.SigTag(digest)
+	bundle, err := c.fetchRawManifest(ctx, repo, sigTag, scope)
+	if err != nil {
+		return repo, digest, nil, fmt.Errorf("fetching signature manifest: %w", err)
+	}
+
+	return repo, digest, bundle, nil
+}
+
+// FetchAttestations retrieves the Cosign attestation bundle for image,
+// stored at the conventional "<repo>:sha256-<hex>.att" tag, mirroring
+// FetchSignature for the separate artifact Cosign stores SLSA/in-toto
+// attestations under. It implements verify.AttestationFetcher.
+func (c *GenericClient) FetchAttestations(ctx context.Context, image string) (string, string, []byte, error) {
+	repo, tag := parseImageReference(image)
+	if repo == "" {
+		return "", "", nil, fmt.Errorf("invalid image reference: %s", image)
+	}
+
+	scope := fmt.Sprintf("repository:%s:pull", repo)
+
+	digest, err := c.fetchManifestDigest(ctx, repo, tag, scope)
+	if err != nil {
+		return repo, "", nil, fmt.Errorf("resolving manifest digest: %w", err)
+	}
+
+	attTag := verify.AttestationTag(digest)
+	bundle, err := c.fetchRawManifest(ctx, repo, attTag, scope)
+	if err != nil {
+		return repo, digest, nil, fmt.Errorf("fetching attestation manifest: %w", err)
+	}
+
+	return repo, digest, bundle, nil
+}
+
+// FetchManifest retrieves image's raw manifest bytes and content digest,
+// letting callers (the internal/mirror pull-through cache) persist them
+// without re-deriving architectures through GetSupportedArchitectures. It
+// implements mirror.ManifestFetcher.
+func (c *GenericClient) FetchManifest(ctx context.Context, image string) (string, string, []byte, error) {
+	repo, tag := parseImageReference(image)
+	if repo == "" {
+		return "", "", nil, fmt.Errorf("invalid image reference: %s", image)
+	}
+
+	scope := fmt.Sprintf("repository:%s:pull", repo)
+
+	body, resp, err := c.getManifest(ctx, repo, tag, scope)
+	if err != nil {
+		return repo, "", nil, fmt.Errorf("fetching manifest: %w", err)
+	}
+
+	return repo, digestFromResponse(body, resp), body, nil
+}
+
+// digestFromResponse prefers the registry's Docker-Content-Digest response
+// header, falling back to hashing body when a registry omits it.
+func digestFromResponse(body []byte, resp *http.Response) string {
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest
+	}
+
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// fetchManifestDigest resolves the content digest of repo:ref, preferring the
+// registry's Docker-Content-Digest response header and falling back to
+// hashing the manifest body when a registry omits it.
+func (c *GenericClient) fetchManifestDigest(ctx context.Context, repo, ref, scope string) (string, error) {
+	body, resp, err := c.getManifest(ctx, repo, ref, scope)
+	if err != nil {
+		return "", err
+	}
+
+	return digestFromResponse(body, resp), nil
+}
+
+// fetchRawManifest fetches repo:ref and returns its manifest body verbatim.
+func (c *GenericClient) fetchRawManifest(ctx context.Context, repo, ref, scope string) ([]byte, error) {
+	body, _, err := c.getManifest(ctx, repo, ref, scope)
+	return body, err
+}
+
+// getManifest performs the /v2/ discovery HEAD, resolves a bearer token if
+// challenged, and GETs repo:ref, returning the raw response body alongside
+// the response so callers can inspect headers (e.g. Docker-Content-Digest).
+func (c *GenericClient) getManifest(ctx context.Context, repo, ref, scope string) ([]byte, *http.Response, error) {
+	if err := c.checkV2Support(ctx, scope); err != nil {
+		return nil, nil, err
+	}
+
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", c.baseURL, repo, ref)
+
+	var resp *http.Response
+	for attempt := 1; ; attempt++ {
+		var err error
+		resp, err = c.doManifestRequest(ctx, manifestURL, scope)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized {
+			if err := c.authenticate(ctx, resp, scope); err != nil {
+				resp.Body.Close()
+				return nil, nil, err
+			}
+			resp.Body.Close()
+
+			resp, err = c.doManifestRequest(ctx, manifestURL, scope)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if retryableStatus(resp.StatusCode) && attempt < maxRetryAfterAttempts {
+			wait := retryAfterBackoff(resp.Header.Get("Retry-After"), attempt, c.maxRetryAfterWait)
+			resp.Body.Close()
+			if waitErr := sleepOrDone(ctx, wait); waitErr != nil {
+				return nil, nil, waitErr
+			}
+			continue
+		}
+
+		break
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return nil, nil, fmt.Errorf("rate limit exceeded")
+	case http.StatusNotFound:
+		return nil, nil, fmt.Errorf("manifest not found")
+	case http.StatusOK:
+		// Continue processing
+	default:
+		return nil, nil, fmt.Errorf("unexpected status fetching manifest: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading manifest body: %w", err)
+	}
+
+	return body, resp, nil
+}
+
+// fetchManifest resolves the manifest for repo/ref via getManifest and
+// decodes it into a ManifestList.
+func (c *GenericClient) fetchManifest(ctx context.Context, repo, ref, scope string) (*ManifestList, error) {
+	body, _, err := c.getManifest(ctx, repo, ref, scope)
+	if err != nil {
+		if err.Error() == "manifest not found" {
+			return nil, fmt.Errorf("image not found")
+		}
+		return nil, err
+	}
+
+	var manifest ManifestList
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	return &manifest, nil
+}
+
+// checkV2Support resolves /v2/ with a HEAD request, surfacing a bearer
+// challenge (if any) so the first manifest request can be pre-authenticated.
+func (c *GenericClient) checkV2Support(ctx context.Context, scope string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.baseURL+"/v2/", nil)
+	if err != nil {
+		return fmt.Errorf("building /v2/ probe: %w", err)
+	}
+	c.authorize(req, scope)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("probing /v2/: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && resp.Header.Get("WWW-Authenticate") == "" {
+		return fmt.Errorf("registry does not support the v2 API")
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") != "" {
+		return c.authenticate(ctx, resp, scope)
+	}
+
+	return nil
+}
+
+func (c *GenericClient) doManifestRequest(ctx context.Context, manifestURL, scope string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building manifest request: %w", err)
+	}
+
+	req.Header.Set("Accept", strings.Join([]string{
+		"application/vnd.docker.distribution.manifest.list.v2+json",
+		"application/vnd.docker.distribution.manifest.v2+json",
+		"application/vnd.oci.image.index.v1+json",
+		"application/vnd.oci.image.manifest.v1+json",
+	}, ", "))
+
+	c.authorize(req, scope)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching manifest: %w", err)
+	}
+	return resp, nil
+}
+
+// authorize attaches whatever credential the client currently holds: the
+// cached bearer token if present and unexpired, otherwise Basic auth from the
+// resolved registry credential. The client caches a single token, the one
+// most recently resolved, whatever scope it was issued for; scope is not
+// used to select it.
+func (c *GenericClient) authorize(req *http.Request, scope string) {
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+
+	if token != nil && time.Now().Before(token.expiry) {
+		req.Header.Set("Authorization", "Bearer "+token.value)
+		return
+	}
+
+	if c.credential != nil && c.credential.Username != "" {
+		req.SetBasicAuth(c.credential.Username, c.credential.Password)
+	}
+}
+
+// authenticate parses the WWW-Authenticate challenge from a 401 response and
+// resolves a token (Bearer realm flow) or confirms Basic auth is in use.
+func (c *GenericClient) authenticate(ctx context.Context, resp *http.Response, scope string) error {
+	challenge := resp.Header.Get("WWW-Authenticate")
+	if challenge == "" {
+		return fmt.Errorf("authentication required but no challenge presented")
+	}
+
+	if strings.HasPrefix(strings.ToLower(challenge), "basic") {
+		if c.credential == nil {
+			return fmt.Errorf("registry requires basic auth but no credential is available")
+		}
+		return nil
+	}
+
+	bc, err := parseBearerChallenge(challenge)
+	if err != nil {
+		return err
+	}
+	if bc.scope == "" {
+		bc.scope = scope
+	}
+
+	token, err := c.requestToken(ctx, bc)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *GenericClient) requestToken(ctx context.Context, bc bearerChallenge) (*bearerToken, error) {
+	tokenURL, err := url.Parse(bc.realm)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token realm %q: %w", bc.realm, err)
+	}
+
+	q := tokenURL.Query()
+	if bc.service != "" {
+		q.Set("service", bc.service)
+	}
+	if bc.scope != "" {
+		q.Set("scope", bc.scope)
+	}
+	tokenURL.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("building token request: %w", err)
+	}
+	if c.credential != nil && c.credential.Username != "" {
+		req.SetBasicAuth(c.credential.Username, c.credential.Password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding token response: %w", err)
+	}
+
+	value := body.Token
+	if value == "" {
+		value = body.AccessToken
+	}
+	if value == "" {
+		return nil, fmt.Errorf("token response contained no token")
+	}
+
+	expiresIn := body.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = 60
+	}
+
+	return &bearerToken{
+		value:  value,
+		expiry: time.Now().Add(time.Duration(expiresIn) * time.Second),
+	}, nil
+}
+
+// parseBearerChallenge parses a "Bearer realm=\"...\",service=\"...\",scope=\"...\""
+// WWW-Authenticate header value into its component parts.
+func parseBearerChallenge(header string) (bearerChallenge, error) {
+	const prefix = "bearer "
+	lower := strings.ToLower(header)
+	if !strings.HasPrefix(lower, prefix) {
+		return bearerChallenge{}, fmt.Errorf("unsupported authentication challenge: %s", header)
+	}
+
+	var bc bearerChallenge
+	params := header[len(prefix):]
+	for _, part := range splitChallengeParams(params) {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(kv[0])
+		val := strings.Trim(strings.TrimSpace(kv[1]), `"`)
+
+		switch strings.ToLower(key) {
+		case "realm":
+			bc.realm = val
+		case "service":
+			bc.service = val
+		case "scope":
+			bc.scope = val
+		}
+	}
+
+	if bc.realm == "" {
+		return bearerChallenge{}, fmt.Errorf("bearer challenge missing realm: %s", header)
+	}
+
+	return bc, nil
+}
+
+// Transport returns an http.RoundTripper that authenticates requests the
+// same way c's own manifest fetches do: it attaches a cached bearer token or
+// Basic auth, and on a 401 resolves a new token (or confirms Basic auth) and
+// retries once. This lets other subsystems that speak HTTP directly to the
+// registry (e.g. a future pkg/inspector transport) share c's resolved
+// credential and cached token instead of re-implementing the challenge flow.
+func (c *GenericClient) Transport() http.RoundTripper {
+	return &clientTransport{client: c}
+}
+
+type clientTransport struct {
+	client *GenericClient
+}
+
+func (t *clientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	scope := scopeFromRequestPath(req.URL.Path)
+
+	authedReq := req.Clone(req.Context())
+	t.client.authorize(authedReq, scope)
+
+	resp, err := t.client.httpClient.Do(authedReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	if err := t.client.authenticate(req.Context(), resp, scope); err != nil {
+		return nil, err
+	}
+
+	retryReq := req.Clone(req.Context())
+	t.client.authorize(retryReq, scope)
+	return t.client.httpClient.Do(retryReq)
+}
+
+// scopeFromRequestPath derives an OCI distribution pull scope
+// ("repository:<repo>:pull") from a "/v2/<repo>/(manifests|blobs|tags)/..."
+// request path, matching the scope GenericClient itself requests a token
+// for when fetching manifests.
+func scopeFromRequestPath(path string) string {
+	const prefix = "/v2/"
+	if !strings.HasPrefix(path, prefix) {
+		return ""
+	}
+
+	rest := strings.TrimPrefix(path, prefix)
+	for _, marker := range []string{"/manifests/", "/blobs/", "/tags/"} {
+		if idx := strings.Index(rest, marker); idx != -1 {
+			return fmt.Sprintf("repository:%s:pull", rest[:idx])
+		}
+	}
+	return ""
+}
+
+// splitChallengeParams splits a comma-separated list of key="value" pairs
+// without breaking on commas embedded inside quoted values.
+func splitChallengeParams(s string) []string {
+	var parts []string
+	var current strings.Builder
+	inQuotes := false
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case r == ',' && !inQuotes:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		parts = append(parts, current.String())
+	}
+	return parts
+}
@@ -0,0 +1,212 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lsdopen/archy/internal/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericClient_AnonymousManifestList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && r.URL.Path == "/v2/" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"manifests":[{"platform":{"architecture":"amd64"}},{"platform":{"architecture":"arm64"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewGenericClient(server.URL)
+	archs, err := client.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"amd64", "arm64"}, archs)
+}
+
+func TestGenericClient_BearerChallengeAndRetry(t *testing.T) {
+	var tokenRequested bool
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/token":
+			tokenRequested = true
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"token":"test-token","expires_in":60}`))
+		case r.Header.Get("Authorization") == "Bearer test-token":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"manifests":[{"platform":{"architecture":"amd64"}}]}`))
+		default:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry.example.com",scope="repository:myorg/app:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGenericClientWithCredentials(server.URL, &credentials.RegistryCredential{Username: "user", Password: "pass"})
+	archs, err := client.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+
+	require.NoError(t, err)
+	assert.True(t, tokenRequested)
+	assert.Equal(t, []string{"amd64"}, archs)
+}
+
+func TestGenericClient_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewGenericClient(server.URL)
+	archs, err := client.GetSupportedArchitectures(context.Background(), "myorg/missing:latest")
+
+	require.Error(t, err)
+	assert.Nil(t, archs)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestGenericClient_FetchSignature(t *testing.T) {
+	const sigBundle = `{"layers":[{"annotations":{"dev.cosignproject.cosign/signature":"c2ln"}}]}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/v2/myorg/app/manifests/latest":
+			w.Header().Set("Docker-Content-Digest", "sha256:abc123")
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"manifests":[{"platform":{"architecture":"amd64"}}]}`))
+		case r.URL.Path == "/v2/myorg/app/manifests/sha256-abc123.sig":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(sigBundle))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGenericClient(server.URL)
+	repo, digest, bundle, err := client.FetchSignature(context.Background(), "myorg/app:latest")
+
+	require.NoError(t, err)
+	assert.Equal(t, "myorg/app", repo)
+	assert.Equal(t, "sha256:abc123", digest)
+	assert.JSONEq(t, sigBundle, string(bundle))
+}
+
+func TestGenericClient_FetchSignature_MissingSignature(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/v2/myorg/app/manifests/latest":
+			w.Header().Set("Docker-Content-Digest", "sha256:abc123")
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"manifests":[{"platform":{"architecture":"amd64"}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGenericClient(server.URL)
+	_, _, _, err := client.FetchSignature(context.Background(), "myorg/app:latest")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fetching signature manifest")
+}
+
+func TestGenericClient_FetchManifest(t *testing.T) {
+	const manifest = `{"manifests":[{"platform":{"architecture":"amd64"}}]}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/v2/myorg/app/manifests/latest":
+			w.Header().Set("Docker-Content-Digest", "sha256:abc123")
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(manifest))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGenericClient(server.URL)
+	repo, digest, body, err := client.FetchManifest(context.Background(), "myorg/app:latest")
+
+	require.NoError(t, err)
+	assert.Equal(t, "myorg/app", repo)
+	assert.Equal(t, "sha256:abc123", digest)
+	assert.JSONEq(t, manifest, string(body))
+}
+
+func TestGenericClient_FetchManifest_HashesBodyWhenDigestHeaderMissing(t *testing.T) {
+	const manifest = `{"manifests":[{"platform":{"architecture":"amd64"}}]}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/v2/myorg/app/manifests/latest":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(manifest))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGenericClient(server.URL)
+	_, digest, _, err := client.FetchManifest(context.Background(), "myorg/app:latest")
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, digest)
+	assert.Contains(t, digest, "sha256:")
+}
+
+func TestGenericClient_TransportAuthenticatesAndRetries(t *testing.T) {
+	var tokenRequested bool
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			tokenRequested = true
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"token":"test-token","expires_in":60}`))
+		case r.Header.Get("Authorization") == "Bearer test-token":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry.example.com",scope="repository:myorg/app:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGenericClientWithCredentials(server.URL, &credentials.RegistryCredential{Username: "user", Password: "pass"})
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v2/myorg/app/manifests/latest", nil)
+	require.NoError(t, err)
+
+	resp, err := client.Transport().RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, tokenRequested)
+}
@@ -1,22 +1,99 @@
 package registry
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math/big"
+
+	"github.com/lsdopen/archy/pkg/inspector"
 )
 
 // ManifestParser handles parsing of container manifests
 type ManifestParser struct{}
 
-// ManifestList represents a Docker manifest list
+// Media types ParsePlatforms dispatches on, in addition to (or instead of)
+// schemaVersion: the OCI image-spec makes mediaType authoritative and
+// schemaVersion optional on an image index, unlike Docker's manifest list.
+const (
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIImageIndex      = "application/vnd.oci.image.index.v1+json"
+)
+
+// ManifestList represents a Docker manifest list, an OCI image index, or a
+// single (non-list) image manifest. Exactly one of Manifests or Config is
+// populated for a given document: Manifests for a list/index, Config for a
+// single manifest (see ParsePlatformsWithConfigFetcher).
 type ManifestList struct {
-	SchemaVersion int `json:"schemaVersion"`
+	SchemaVersion int    `json:"schemaVersion"`
+	MediaType     string `json:"mediaType"`
 	Manifests     []struct {
 		Platform struct {
-			Architecture string `json:"architecture"`
-			OS           string `json:"os"`
+			Architecture string   `json:"architecture"`
+			OS           string   `json:"os"`
+			OSVersion    string   `json:"os.version"`
+			OSFeatures   []string `json:"os.features"`
+			Variant      string   `json:"variant"`
 		} `json:"platform"`
 	} `json:"manifests"`
+	Config *manifestConfigDescriptor `json:"config"`
+}
+
+// manifestConfigDescriptor is the "config" blob descriptor of a single
+// (non-list) image manifest: the architecture/os/variant a single manifest
+// declares support for lives in this blob, not in the manifest itself.
+type manifestConfigDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// ConfigFetcher retrieves the raw JSON config blob a single image manifest
+// references by digest (manifest.config.digest), so
+// ParsePlatformsWithConfigFetcher can read its .architecture/.os/.variant.
+// Registry clients (e.g. DockerHubClient) supply one backed by their own
+// blob-fetching and auth logic.
+type ConfigFetcher func(ctx context.Context, digest string) ([]byte, error)
+
+// schema1Manifest is the subset of a Docker Image Manifest V2, Schema 1
+// (signed) document ParsePlatforms needs: the single top-level architecture,
+// the embedded v1Compatibility config of the oldest history entry (for OS),
+// and the libtrust JWS signature block.
+type schema1Manifest struct {
+	Architecture string `json:"architecture"`
+	History      []struct {
+		V1Compatibility string `json:"v1Compatibility"`
+	} `json:"history"`
+	Signatures []schema1Signature `json:"signatures"`
+}
+
+// schema1Signature is one entry of a schema-1 manifest's "signatures" array,
+// per the libtrust JWS format: https://github.com/docker/libtrust.
+type schema1Signature struct {
+	Header struct {
+		JWK struct {
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"jwk"`
+		Alg string `json:"alg"`
+	} `json:"header"`
+	Signature string `json:"signature"`
+	Protected string `json:"protected"`
+}
+
+// schema1ProtectedHeader is the base64url-decoded "protected" field of a
+// schema1Signature: it tells the verifier how to reconstruct the exact bytes
+// that were signed from the manifest JSON with its "signatures" field
+// stripped.
+type schema1ProtectedHeader struct {
+	FormatLength int    `json:"formatLength"`
+	FormatTail   string `json:"formatTail"`
 }
 
 // NewManifestParser creates a new manifest parser
@@ -24,8 +101,26 @@ func NewManifestParser() *ManifestParser {
 	return &ManifestParser{}
 }
 
-// ParseArchitectures extracts supported architectures from a manifest
-func (p *ManifestParser) ParseArchitectures(manifestData []byte) ([]string, error) {
+// ParsePlatforms extracts the full set of supported platforms (architecture,
+// OS, OS version, OS features and variant) from a Docker/OCI manifest list,
+// deduplicating identical platform entries. Signed Docker Image Manifest V2
+// Schema 1 documents are also supported: they declare a single architecture,
+// so the JWS signature is verified against the embedded JWK and that one
+// platform is returned. A single (non-list) manifest isn't supported here
+// since its platform lives in a config blob this method has no way to
+// fetch; use ParsePlatformsWithConfigFetcher for that.
+func (p *ManifestParser) ParsePlatforms(manifestData []byte) ([]inspector.Platform, error) {
+	return p.ParsePlatformsWithConfigFetcher(context.Background(), manifestData, nil)
+}
+
+// ParsePlatformsWithConfigFetcher is ParsePlatforms, plus support for a
+// single (non-list) image manifest: a manifest with a "config" descriptor
+// and no "manifests" array declares support for exactly one platform, which
+// lives in the referenced config blob's .architecture/.os/.variant fields
+// rather than the manifest itself. fetchConfig retrieves that blob; pass nil
+// to fail with a clear error instead of silently returning no platforms when
+// a manifest turns out to be single-arch.
+func (p *ManifestParser) ParsePlatformsWithConfigFetcher(ctx context.Context, manifestData []byte, fetchConfig ConfigFetcher) ([]inspector.Platform, error) {
 	if len(manifestData) == 0 {
 		return nil, fmt.Errorf("manifest data is empty")
 	}
@@ -40,12 +135,31 @@ func (p *ManifestParser) ParseArchitectures(manifestData []byte) ([]string, erro
 		return nil, fmt.Errorf("manifest is null")
 	}
 
-	// Validate schema version
-	if manifest.SchemaVersion == 0 {
-		return nil, fmt.Errorf("missing schema version")
+	if manifest.SchemaVersion == 1 {
+		if platforms, handled, err := p.parseSchema1(manifestData); handled {
+			return platforms, err
+		}
+	}
+
+	// A manifest with a config descriptor and no manifests[] array is a
+	// single-arch image manifest, not a list/index: its platform lives in
+	// the config blob, not in this document.
+	isIndex := manifest.MediaType == mediaTypeOCIImageIndex || manifest.MediaType == mediaTypeDockerManifestList
+	if manifest.Config != nil && len(manifest.Manifests) == 0 && !isIndex {
+		return p.parseSingleManifest(ctx, manifest.Config, fetchConfig)
 	}
-	if manifest.SchemaVersion != 2 {
-		return nil, fmt.Errorf("unsupported schema version: %d", manifest.SchemaVersion)
+
+	// Validate schema version. The OCI image-spec makes mediaType
+	// authoritative for an index and schemaVersion optional there; anything
+	// else (a Docker manifest list, or a document we can't otherwise
+	// identify) still requires it.
+	if manifest.MediaType != mediaTypeOCIImageIndex {
+		if manifest.SchemaVersion == 0 {
+			return nil, fmt.Errorf("missing schema version")
+		}
+		if manifest.SchemaVersion != 2 {
+			return nil, fmt.Errorf("unsupported schema version: %d", manifest.SchemaVersion)
+		}
 	}
 
 	// Check if manifests exist
@@ -53,8 +167,8 @@ func (p *ManifestParser) ParseArchitectures(manifestData []byte) ([]string, erro
 		return nil, fmt.Errorf("no manifests found")
 	}
 
-	// Extract architectures
-	var architectures []string
+	// Extract platforms
+	var platforms []inspector.Platform
 	seen := make(map[string]bool)
 	validPlatforms := false
 
@@ -62,12 +176,29 @@ func (p *ManifestParser) ParseArchitectures(manifestData []byte) ([]string, erro
 		if m.Platform.Architecture == "" {
 			continue
 		}
+
+		// Per the OCI image-spec, an index entry whose platform is
+		// "unknown/unknown" isn't a real platform variant but an auxiliary
+		// artifact (e.g. a Buildkit provenance/SBOM attestation manifest),
+		// and must be skipped rather than reported as a supported platform.
+		if m.Platform.Architecture == "unknown" && m.Platform.OS == "unknown" {
+			continue
+		}
+
 		validPlatforms = true
-		
-		arch := m.Platform.Architecture
-		if !seen[arch] {
-			architectures = append(architectures, arch)
-			seen[arch] = true
+
+		platform := inspector.Platform{
+			Architecture: m.Platform.Architecture,
+			OS:           m.Platform.OS,
+			OSVersion:    m.Platform.OSVersion,
+			OSFeatures:   m.Platform.OSFeatures,
+			Variant:      m.Platform.Variant,
+		}
+
+		key := fmt.Sprintf("%s/%s/%s/%s", platform.Architecture, platform.OS, platform.Variant, platform.OSVersion)
+		if !seen[key] {
+			platforms = append(platforms, platform)
+			seen[key] = true
 		}
 	}
 
@@ -75,9 +206,163 @@ func (p *ManifestParser) ParseArchitectures(manifestData []byte) ([]string, erro
 		return nil, fmt.Errorf("no valid platforms found")
 	}
 
-	if len(architectures) == 0 {
+	if len(platforms) == 0 {
 		return nil, fmt.Errorf("no valid architectures found")
 	}
 
-	return architectures, nil
-}
\ No newline at end of file
+	return platforms, nil
+}
+
+// parseSingleManifest resolves the one platform a single (non-list) image
+// manifest declares support for by fetching and reading its config blob.
+func (p *ManifestParser) parseSingleManifest(ctx context.Context, config *manifestConfigDescriptor, fetchConfig ConfigFetcher) ([]inspector.Platform, error) {
+	if config.Digest == "" {
+		return nil, fmt.Errorf("single manifest has no config digest")
+	}
+	if fetchConfig == nil {
+		return nil, fmt.Errorf("single manifest's platform requires fetching config blob %s, but no config fetcher was provided", config.Digest)
+	}
+
+	configData, err := fetchConfig(ctx, config.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("fetching config blob %s: %w", config.Digest, err)
+	}
+
+	var imageConfig struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+		Variant      string `json:"variant"`
+	}
+	if err := json.Unmarshal(configData, &imageConfig); err != nil {
+		return nil, fmt.Errorf("parsing config blob %s: %w", config.Digest, err)
+	}
+	if imageConfig.Architecture == "" {
+		return nil, fmt.Errorf("config blob %s has no architecture", config.Digest)
+	}
+
+	return []inspector.Platform{{
+		Architecture: imageConfig.Architecture,
+		OS:           imageConfig.OS,
+		Variant:      imageConfig.Variant,
+	}}, nil
+}
+
+// parseSchema1 attempts to treat manifestData as a signed schema-1 manifest.
+// handled is false when manifestData doesn't have the shape of one (no
+// top-level architecture or no signatures), in which case the caller falls
+// back to reporting it as an unsupported schema version.
+func (p *ManifestParser) parseSchema1(manifestData []byte) (platforms []inspector.Platform, handled bool, err error) {
+	var manifest schema1Manifest
+	if jsonErr := json.Unmarshal(manifestData, &manifest); jsonErr != nil {
+		return nil, false, nil
+	}
+	if manifest.Architecture == "" || len(manifest.Signatures) == 0 {
+		return nil, false, nil
+	}
+	handled = true
+
+	var verifyErr error
+	verified := false
+	for _, sig := range manifest.Signatures {
+		if sigErr := verifySchema1Signature(manifestData, sig); sigErr != nil {
+			verifyErr = sigErr
+			continue
+		}
+		verified = true
+		break
+	}
+	if !verified {
+		return nil, true, fmt.Errorf("schema 1 manifest signature verification failed: %w", verifyErr)
+	}
+
+	platform := inspector.Platform{Architecture: manifest.Architecture}
+	if len(manifest.History) > 0 {
+		var v1Compat struct {
+			OS string `json:"os"`
+		}
+		if jsonErr := json.Unmarshal([]byte(manifest.History[0].V1Compatibility), &v1Compat); jsonErr == nil {
+			platform.OS = v1Compat.OS
+		}
+	}
+
+	return []inspector.Platform{platform}, true, nil
+}
+
+// verifySchema1Signature checks one libtrust JWS signature from a schema-1
+// manifest. Per the schema-1 signing spec, the payload that was actually
+// signed is not manifestData itself but manifestData with its "signatures"
+// field removed: the protected header records how many leading bytes of
+// manifestData to keep (formatLength) and the bytes to append in place of
+// the truncated remainder (formatTail) to reconstruct that payload.
+func verifySchema1Signature(manifestData []byte, sig schema1Signature) error {
+	protectedBytes, err := base64.RawURLEncoding.DecodeString(sig.Protected)
+	if err != nil {
+		return fmt.Errorf("decoding protected header: %w", err)
+	}
+
+	var protected schema1ProtectedHeader
+	if err := json.Unmarshal(protectedBytes, &protected); err != nil {
+		return fmt.Errorf("parsing protected header: %w", err)
+	}
+
+	if protected.FormatLength <= 0 || protected.FormatLength > len(manifestData) {
+		return fmt.Errorf("signed format length %d does not fit manifest of %d bytes", protected.FormatLength, len(manifestData))
+	}
+
+	tail, err := base64.RawURLEncoding.DecodeString(protected.FormatTail)
+	if err != nil {
+		return fmt.Errorf("decoding format tail: %w", err)
+	}
+
+	payload := make([]byte, 0, protected.FormatLength+len(tail))
+	payload = append(payload, manifestData[:protected.FormatLength]...)
+	payload = append(payload, tail...)
+	hash := sha256.Sum256(payload)
+
+	sigBytes, err := base64.RawURLEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return fmt.Errorf("decoding signature: %w", err)
+	}
+
+	pub, err := schema1JWKPublicKey(sig)
+	if err != nil {
+		return err
+	}
+
+	half := len(sigBytes) / 2
+	if half == 0 {
+		return fmt.Errorf("signature too short")
+	}
+	r := new(big.Int).SetBytes(sigBytes[:half])
+	s := new(big.Int).SetBytes(sigBytes[half:])
+
+	if !ecdsa.Verify(pub, hash[:], r, s) {
+		return fmt.Errorf("signature does not verify against embedded JWK")
+	}
+
+	return nil
+}
+
+// schema1JWKPublicKey reconstructs the ECDSA public key embedded in a
+// schema-1 signature's JWS header.
+func schema1JWKPublicKey(sig schema1Signature) (*ecdsa.PublicKey, error) {
+	jwk := sig.Header.JWK
+	if jwk.Kty != "EC" || jwk.Crv != "P-256" {
+		return nil, fmt.Errorf("unsupported JWK type %s/%s", jwk.Kty, jwk.Crv)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("decoding jwk x coordinate: %w", err)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("decoding jwk y coordinate: %w", err)
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}, nil
+}
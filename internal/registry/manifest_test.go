@@ -1,9 +1,17 @@
 package registry
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"testing"
 
+	"github.com/lsdopen/archy/pkg/inspector"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -39,10 +47,10 @@ func TestManifestParser_InvalidJSON(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			parser := NewManifestParser()
-			archs, err := parser.ParseArchitectures([]byte(tt.manifest))
-			
+			platforms, err := parser.ParsePlatforms([]byte(tt.manifest))
+
 			require.Error(t, err)
-			assert.Nil(t, archs)
+			assert.Nil(t, platforms)
 			assert.Contains(t, err.Error(), tt.wantErr)
 		})
 	}
@@ -79,10 +87,10 @@ func TestManifestParser_MissingRequiredFields(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			parser := NewManifestParser()
-			archs, err := parser.ParseArchitectures([]byte(tt.manifest))
-			
+			platforms, err := parser.ParsePlatforms([]byte(tt.manifest))
+
 			require.Error(t, err)
-			assert.Nil(t, archs)
+			assert.Nil(t, platforms)
 			assert.Contains(t, err.Error(), tt.wantErr)
 		})
 	}
@@ -114,10 +122,10 @@ func TestManifestParser_UnsupportedSchemaVersions(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			parser := NewManifestParser()
-			archs, err := parser.ParseArchitectures([]byte(tt.manifest))
-			
+			platforms, err := parser.ParsePlatforms([]byte(tt.manifest))
+
 			require.Error(t, err)
-			assert.Nil(t, archs)
+			assert.Nil(t, platforms)
 			assert.Contains(t, err.Error(), tt.wantErr)
 		})
 	}
@@ -133,10 +141,12 @@ func TestManifestParser_UnknownArchitectures(t *testing.T) {
 	}`
 
 	parser := NewManifestParser()
-	archs, err := parser.ParseArchitectures([]byte(manifest))
-	
+	platforms, err := parser.ParsePlatforms([]byte(manifest))
+
 	require.NoError(t, err)
-	assert.Equal(t, []string{"unknown-arch", "future-arch"}, archs)
+	require.Len(t, platforms, 2)
+	assert.Equal(t, "unknown-arch", platforms[0].Architecture)
+	assert.Equal(t, "future-arch", platforms[1].Architecture)
 }
 
 func TestManifestParser_MixedSchemaVersions(t *testing.T) {
@@ -150,9 +160,10 @@ func TestManifestParser_MixedSchemaVersions(t *testing.T) {
 	}`
 
 	parser := NewManifestParser()
-	archs, err := parser.ParseArchitectures([]byte(manifest))
-	
+	platforms, err := parser.ParsePlatforms([]byte(manifest))
+
 	require.NoError(t, err)
+	archs := platformArchitectures(platforms)
 	assert.Contains(t, archs, "amd64")
 	assert.Contains(t, archs, "arm64")
 }
@@ -173,10 +184,11 @@ func TestManifestParser_ExtremelyLargeManifest(t *testing.T) {
 	manifest += `]}`
 
 	parser := NewManifestParser()
-	archs, err := parser.ParseArchitectures([]byte(manifest))
-	
+	platforms, err := parser.ParsePlatforms([]byte(manifest))
+
 	require.NoError(t, err)
-	assert.Equal(t, []string{"amd64"}, archs) // Should deduplicate
+	require.Len(t, platforms, 1) // Should deduplicate
+	assert.Equal(t, "amd64", platforms[0].Architecture)
 }
 
 func TestManifestParser_CircularReferences(t *testing.T) {
@@ -193,10 +205,11 @@ func TestManifestParser_CircularReferences(t *testing.T) {
 	}`
 
 	parser := NewManifestParser()
-	archs, err := parser.ParseArchitectures([]byte(manifest))
-	
+	platforms, err := parser.ParsePlatforms([]byte(manifest))
+
 	require.NoError(t, err)
-	assert.Equal(t, []string{"amd64"}, archs)
+	require.Len(t, platforms, 1)
+	assert.Equal(t, "amd64", platforms[0].Architecture)
 }
 
 func TestManifestParser_ConcurrentParsing(t *testing.T) {
@@ -215,15 +228,15 @@ func TestManifestParser_ConcurrentParsing(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		go func() {
 			defer func() { done <- true }()
-			
-			archs, err := parser.ParseArchitectures([]byte(manifest))
+
+			platforms, err := parser.ParsePlatforms([]byte(manifest))
 			if err != nil {
 				errors <- err
 				return
 			}
-			
-			if len(archs) != 2 {
-				errors <- fmt.Errorf("expected 2 architectures, got %d", len(archs))
+
+			if len(platforms) != 2 {
+				errors <- fmt.Errorf("expected 2 platforms, got %d", len(platforms))
 			}
 		}()
 	}
@@ -243,7 +256,7 @@ func TestManifestParser_ValidManifests(t *testing.T) {
 	tests := []struct {
 		name     string
 		manifest string
-		expected []string
+		expected []inspector.Platform
 	}{
 		{
 			name: "single architecture",
@@ -253,7 +266,7 @@ func TestManifestParser_ValidManifests(t *testing.T) {
 					{"platform": {"architecture": "amd64", "os": "linux"}}
 				]
 			}`,
-			expected: []string{"amd64"},
+			expected: []inspector.Platform{{Architecture: "amd64", OS: "linux"}},
 		},
 		{
 			name: "multiple architectures",
@@ -262,31 +275,338 @@ func TestManifestParser_ValidManifests(t *testing.T) {
 				"manifests": [
 					{"platform": {"architecture": "amd64", "os": "linux"}},
 					{"platform": {"architecture": "arm64", "os": "linux"}},
-					{"platform": {"architecture": "arm", "os": "linux"}}
+					{"platform": {"architecture": "arm", "os": "linux", "variant": "v7"}}
+				]
+			}`,
+			expected: []inspector.Platform{
+				{Architecture: "amd64", OS: "linux"},
+				{Architecture: "arm64", OS: "linux"},
+				{Architecture: "arm", OS: "linux", Variant: "v7"},
+			},
+		},
+		{
+			name: "duplicate platform entries are deduplicated",
+			manifest: `{
+				"schemaVersion": 2,
+				"manifests": [
+					{"platform": {"architecture": "amd64", "os": "linux"}},
+					{"platform": {"architecture": "amd64", "os": "linux"}}
 				]
 			}`,
-			expected: []string{"amd64", "arm64", "arm"},
+			expected: []inspector.Platform{{Architecture: "amd64", OS: "linux"}},
 		},
 		{
-			name: "duplicate architectures",
+			name: "same architecture, different OS stays distinct",
 			manifest: `{
 				"schemaVersion": 2,
 				"manifests": [
 					{"platform": {"architecture": "amd64", "os": "linux"}},
-					{"platform": {"architecture": "amd64", "os": "windows"}}
+					{"platform": {"architecture": "amd64", "os": "windows", "os.version": "10.0.17763.1"}}
 				]
 			}`,
-			expected: []string{"amd64"},
+			expected: []inspector.Platform{
+				{Architecture: "amd64", OS: "linux"},
+				{Architecture: "amd64", OS: "windows", OSVersion: "10.0.17763.1"},
+			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			parser := NewManifestParser()
-			archs, err := parser.ParseArchitectures([]byte(tt.manifest))
-			
+			platforms, err := parser.ParsePlatforms([]byte(tt.manifest))
+
 			require.NoError(t, err)
-			assert.ElementsMatch(t, tt.expected, archs)
+			assert.Equal(t, tt.expected, platforms)
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestManifestParser_OCIImageIndex(t *testing.T) {
+	manifest := `{
+		"mediaType": "application/vnd.oci.image.index.v1+json",
+		"manifests": [
+			{"platform": {"architecture": "amd64", "os": "linux"}},
+			{"platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}}
+		]
+	}`
+
+	parser := NewManifestParser()
+	platforms, err := parser.ParsePlatforms([]byte(manifest))
+
+	require.NoError(t, err)
+	assert.Equal(t, []inspector.Platform{
+		{Architecture: "amd64", OS: "linux"},
+		{Architecture: "arm64", OS: "linux", Variant: "v8"},
+	}, platforms)
+}
+
+func TestManifestParser_OCIImageIndexWithoutSchemaVersionIsValid(t *testing.T) {
+	// schemaVersion is optional on an OCI image index, unlike a Docker
+	// manifest list where its absence is an error (see
+	// TestManifestParser_UnsupportedSchemaVersions).
+	manifest := `{
+		"mediaType": "application/vnd.oci.image.index.v1+json",
+		"manifests": [{"platform": {"architecture": "amd64", "os": "linux"}}]
+	}`
+
+	parser := NewManifestParser()
+	platforms, err := parser.ParsePlatforms([]byte(manifest))
+
+	require.NoError(t, err)
+	require.Len(t, platforms, 1)
+	assert.Equal(t, "amd64", platforms[0].Architecture)
+}
+
+func TestManifestParser_SkipsUnknownUnknownAttestationEntries(t *testing.T) {
+	// Buildkit publishes provenance/SBOM attestations as extra index
+	// entries with platform unknown/unknown; they aren't real platforms and
+	// must not show up as one.
+	manifest := `{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.oci.image.index.v1+json",
+		"manifests": [
+			{"platform": {"architecture": "amd64", "os": "linux"}},
+			{"platform": {"architecture": "unknown", "os": "unknown"}}
+		]
+	}`
+
+	parser := NewManifestParser()
+	platforms, err := parser.ParsePlatforms([]byte(manifest))
+
+	require.NoError(t, err)
+	require.Len(t, platforms, 1)
+	assert.Equal(t, "amd64", platforms[0].Architecture)
+}
+
+func TestManifestParser_SingleManifestRequiresConfigFetcher(t *testing.T) {
+	manifest := `{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": "sha256:deadbeef", "size": 123}
+	}`
+
+	parser := NewManifestParser()
+	platforms, err := parser.ParsePlatformsWithConfigFetcher(context.Background(), []byte(manifest), nil)
+
+	require.Error(t, err)
+	assert.Nil(t, platforms)
+	assert.Contains(t, err.Error(), "no config fetcher")
+}
+
+func TestManifestParser_SingleManifestFetchesConfigBlob(t *testing.T) {
+	manifest := `{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": "sha256:deadbeef", "size": 123}
+	}`
+
+	var fetchedDigest string
+	fetchConfig := func(ctx context.Context, digest string) ([]byte, error) {
+		fetchedDigest = digest
+		return []byte(`{"architecture": "arm64", "os": "linux", "variant": "v8"}`), nil
+	}
+
+	parser := NewManifestParser()
+	platforms, err := parser.ParsePlatformsWithConfigFetcher(context.Background(), []byte(manifest), fetchConfig)
+
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:deadbeef", fetchedDigest)
+	assert.Equal(t, []inspector.Platform{{Architecture: "arm64", OS: "linux", Variant: "v8"}}, platforms)
+}
+
+func TestManifestParser_SingleManifestConfigFetchError(t *testing.T) {
+	manifest := `{
+		"schemaVersion": 2,
+		"config": {"digest": "sha256:deadbeef"}
+	}`
+
+	fetchConfig := func(ctx context.Context, digest string) ([]byte, error) {
+		return nil, fmt.Errorf("blob not found")
+	}
+
+	parser := NewManifestParser()
+	platforms, err := parser.ParsePlatformsWithConfigFetcher(context.Background(), []byte(manifest), fetchConfig)
+
+	require.Error(t, err)
+	assert.Nil(t, platforms)
+	assert.Contains(t, err.Error(), "blob not found")
+}
+
+func platformArchitectures(platforms []inspector.Platform) []string {
+	archs := make([]string, len(platforms))
+	for i, p := range platforms {
+		archs[i] = p.Architecture
+	}
+	return archs
+}
+
+// signedSchema1Manifest builds a minimally-valid signed Docker Image
+// Manifest V2 Schema 1 document following the libtrust JWS construction:
+// the signature covers the manifest bytes with the "signatures" field
+// stripped, reconstructed at verification time from formatLength/formatTail.
+func signedSchema1Manifest(t *testing.T, architecture, osValue string) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	v1Compat, err := json.Marshal(map[string]string{"os": osValue})
+	require.NoError(t, err)
+
+	unsigned, err := json.Marshal(struct {
+		SchemaVersion int    `json:"schemaVersion"`
+		Architecture  string `json:"architecture"`
+		History       []struct {
+			V1Compatibility string `json:"v1Compatibility"`
+		} `json:"history"`
+	}{
+		SchemaVersion: 1,
+		Architecture:  architecture,
+		History: []struct {
+			V1Compatibility string `json:"v1Compatibility"`
+		}{{V1Compatibility: string(v1Compat)}},
+	})
+	require.NoError(t, err)
+
+	return appendSchema1Signature(t, key, unsigned)
+}
+
+// appendSchema1Signature signs unsigned (a schema-1 manifest without a
+// "signatures" field) and returns the full manifest with a valid
+// "signatures" entry appended.
+func appendSchema1Signature(t *testing.T, key *ecdsa.PrivateKey, unsigned []byte) []byte {
+	t.Helper()
+
+	formatLength := len(unsigned) - 1 // strip the trailing '}'
+	tail := []byte("}")
+
+	payload := append(append([]byte{}, unsigned[:formatLength]...), tail...)
+	hash := sha256.Sum256(payload)
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+	sigBytes := append(padTo32(r.Bytes()), padTo32(s.Bytes())...)
+
+	protected, err := json.Marshal(schema1ProtectedHeader{
+		FormatLength: formatLength,
+		FormatTail:   base64.RawURLEncoding.EncodeToString(tail),
+	})
+	require.NoError(t, err)
+
+	var sig schema1Signature
+	sig.Header.JWK.Kty = "EC"
+	sig.Header.JWK.Crv = "P-256"
+	sig.Header.JWK.X = base64.RawURLEncoding.EncodeToString(padTo32(key.PublicKey.X.Bytes()))
+	sig.Header.JWK.Y = base64.RawURLEncoding.EncodeToString(padTo32(key.PublicKey.Y.Bytes()))
+	sig.Header.Alg = "ES256"
+	sig.Signature = base64.RawURLEncoding.EncodeToString(sigBytes)
+	sig.Protected = base64.RawURLEncoding.EncodeToString(protected)
+
+	sigJSON, err := json.Marshal([]schema1Signature{sig})
+	require.NoError(t, err)
+
+	manifestData := append([]byte{}, unsigned[:formatLength]...)
+	manifestData = append(manifestData, []byte(`,"signatures":`)...)
+	manifestData = append(manifestData, sigJSON...)
+	manifestData = append(manifestData, '}')
+	return manifestData
+}
+
+func padTo32(b []byte) []byte {
+	if len(b) >= 32 {
+		return b
+	}
+	padded := make([]byte, 32)
+	copy(padded[32-len(b):], b)
+	return padded
+}
+
+func TestManifestParser_Schema1Signed(t *testing.T) {
+	manifest := signedSchema1Manifest(t, "amd64", "linux")
+
+	parser := NewManifestParser()
+	platforms, err := parser.ParsePlatforms(manifest)
+
+	require.NoError(t, err)
+	require.Len(t, platforms, 1)
+	assert.Equal(t, inspector.Platform{Architecture: "amd64", OS: "linux"}, platforms[0])
+}
+
+func TestManifestParser_Schema1TamperedPayload(t *testing.T) {
+	manifest := signedSchema1Manifest(t, "amd64", "linux")
+
+	// Flip a byte inside the signed portion (architecture value) so the
+	// reconstructed payload no longer matches what was signed.
+	idx := bytesIndex(manifest, []byte("amd64"))
+	require.GreaterOrEqual(t, idx, 0)
+	tampered := append([]byte{}, manifest...)
+	tampered[idx] = 'b'
+
+	parser := NewManifestParser()
+	platforms, err := parser.ParsePlatforms(tampered)
+
+	require.Error(t, err)
+	assert.Nil(t, platforms)
+	assert.Contains(t, err.Error(), "signature verification failed")
+}
+
+func TestManifestParser_Schema1FormatLengthMismatch(t *testing.T) {
+	manifest := signedSchema1Manifest(t, "amd64", "linux")
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(manifest, &decoded))
+	sigs, ok := decoded["signatures"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, sigs, 1)
+	sigMap := sigs[0].(map[string]interface{})
+
+	protectedRaw, err := base64.RawURLEncoding.DecodeString(sigMap["protected"].(string))
+	require.NoError(t, err)
+	var protected schema1ProtectedHeader
+	require.NoError(t, json.Unmarshal(protectedRaw, &protected))
+
+	// The reconstructed tail no longer lines up with what was signed: claim
+	// a formatLength far beyond the actual manifest bytes.
+	protected.FormatLength = len(manifest) + 1000
+	tamperedProtected, err := json.Marshal(protected)
+	require.NoError(t, err)
+	sigMap["protected"] = base64.RawURLEncoding.EncodeToString(tamperedProtected)
+
+	tamperedManifest, err := json.Marshal(decoded)
+	require.NoError(t, err)
+
+	parser := NewManifestParser()
+	platforms, err := parser.ParsePlatforms(tamperedManifest)
+
+	require.Error(t, err)
+	assert.Nil(t, platforms)
+	assert.Contains(t, err.Error(), "does not fit manifest")
+}
+
+func TestManifestParser_Schema1MissingArchitectureFallsBackToUnsupported(t *testing.T) {
+	manifest := `{"schemaVersion": 1, "history": [{"v1Compatibility": "{\"os\":\"linux\"}"}]}`
+
+	parser := NewManifestParser()
+	platforms, err := parser.ParsePlatforms([]byte(manifest))
+
+	require.Error(t, err)
+	assert.Nil(t, platforms)
+	assert.Contains(t, err.Error(), "unsupported schema version")
+}
+
+func bytesIndex(haystack, needle []byte) int {
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		match := true
+		for j := range needle {
+			if haystack[i+j] != needle[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,303 @@
+package registry
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lsdopen/archy/internal/metrics"
+	"github.com/lsdopen/archy/pkg/types"
+)
+
+// MirrorHost is one pull-through mirror endpoint for an upstream registry,
+// as declared by a `[host."https://mirror"]` section of a containerd-style
+// hosts.toml.
+type MirrorHost struct {
+	URL          string
+	Capabilities []string
+}
+
+// HostConfig is the parsed form of a containerd hosts.toml file: the
+// canonical origin server plus zero or more mirrors to try before it.
+type HostConfig struct {
+	Server  string
+	Mirrors []MirrorHost
+}
+
+// ParseHostsTOML parses the subset of containerd's hosts.toml format Archy
+// needs: a top-level `server = "https://origin"` key and any number of
+// `[host."https://mirror"]` sections carrying a `capabilities = [...]` array,
+// so existing containerd mirror configuration can be reused as-is. See
+// https://github.com/containerd/containerd/blob/main/docs/hosts.md.
+func ParseHostsTOML(data []byte) (*HostConfig, error) {
+	cfg := &HostConfig{}
+	var current *MirrorHost
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[host.") {
+			url, err := tomlSectionKey(line)
+			if err != nil {
+				return nil, err
+			}
+			cfg.Mirrors = append(cfg.Mirrors, MirrorHost{URL: url})
+			current = &cfg.Mirrors[len(cfg.Mirrors)-1]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "server":
+			s, err := tomlString(value)
+			if err != nil {
+				return nil, fmt.Errorf("parsing server: %w", err)
+			}
+			cfg.Server = s
+		case "capabilities":
+			if current == nil {
+				return nil, fmt.Errorf("capabilities outside a [host.*] section")
+			}
+			caps, err := tomlStringArray(value)
+			if err != nil {
+				return nil, fmt.Errorf("parsing capabilities: %w", err)
+			}
+			current.Capabilities = caps
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanning hosts.toml: %w", err)
+	}
+
+	if cfg.Server == "" {
+		return nil, fmt.Errorf("hosts.toml missing top-level server")
+	}
+
+	return cfg, nil
+}
+
+// tomlSectionKey extracts the quoted key of a `[host."..."]` section header.
+func tomlSectionKey(line string) (string, error) {
+	start := strings.Index(line, `"`)
+	end := strings.LastIndex(line, `"`)
+	if start == -1 || end <= start {
+		return "", fmt.Errorf("malformed section header: %s", line)
+	}
+	return line[start+1 : end], nil
+}
+
+// tomlString unquotes a double-quoted TOML string value.
+func tomlString(value string) (string, error) {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", fmt.Errorf("expected a quoted string, got %q", value)
+	}
+	return value[1 : len(value)-1], nil
+}
+
+// tomlStringArray parses a single-line TOML array of quoted strings, e.g.
+// `["pull", "resolve"]`.
+func tomlStringArray(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 2 || value[0] != '[' || value[len(value)-1] != ']' {
+		return nil, fmt.Errorf("expected an array, got %q", value)
+	}
+
+	inner := strings.TrimSpace(value[1 : len(value)-1])
+	if inner == "" {
+		return nil, nil
+	}
+
+	var result []string
+	for _, item := range strings.Split(inner, ",") {
+		s, err := tomlString(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// Circuit breaker tuning: after breakerThreshold consecutive 5xx/timeout
+// failures against a mirror within breakerWindow, the mirror is skipped for
+// breakerCooldown rather than retried on every admission request.
+const (
+	breakerThreshold = 3
+	breakerWindow    = time.Minute
+	breakerCooldown  = 30 * time.Second
+)
+
+// breaker is a simple consecutive-failure circuit breaker for one mirror
+// endpoint. It deliberately ignores 404/auth failures (see classifyMirrorErr)
+// since those are expected fallback triggers, not mirror outages.
+type breaker struct {
+	mu          sync.Mutex
+	failures    int
+	windowStart time.Time
+	openUntil   time.Time
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().After(b.openUntil)
+}
+
+func (b *breaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+func (b *breaker) recordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(b.windowStart) > breakerWindow {
+		b.windowStart = now
+		b.failures = 0
+	}
+	b.failures++
+	if b.failures >= breakerThreshold {
+		b.openUntil = now.Add(breakerCooldown)
+	}
+}
+
+// mirrorEntry pairs a mirror's client with its own circuit breaker.
+type mirrorEntry struct {
+	host    string
+	client  types.RegistryClient
+	breaker *breaker
+}
+
+// MirroredClient tries a configured list of pull-through mirrors, in order,
+// before falling back to the canonical origin registry, so admission
+// requests for a popular image don't all hammer the upstream directly.
+type MirroredClient struct {
+	origin     types.RegistryClient
+	originHost string
+	mirrors    []mirrorEntry
+	metrics    *metrics.Metrics
+}
+
+// NewMirroredClient builds a MirroredClient from cfg: origin reaches
+// cfg.Server directly, and each mirror in cfg.Mirrors advertising the "pull"
+// capability is tried first, in the order given.
+func NewMirroredClient(cfg *HostConfig, m *metrics.Metrics) (*MirroredClient, error) {
+	origin, err := NewClient(cfg.Server)
+	if err != nil {
+		return nil, fmt.Errorf("building origin client for %q: %w", cfg.Server, err)
+	}
+
+	mc := &MirroredClient{origin: origin, originHost: hostOf(cfg.Server), metrics: m}
+	for _, mirror := range cfg.Mirrors {
+		if !hasCapability(mirror.Capabilities, "pull") {
+			continue
+		}
+		client, err := NewClient(mirror.URL)
+		if err != nil {
+			return nil, fmt.Errorf("building client for mirror %q: %w", mirror.URL, err)
+		}
+		mc.mirrors = append(mc.mirrors, mirrorEntry{host: hostOf(mirror.URL), client: client, breaker: &breaker{}})
+	}
+	return mc, nil
+}
+
+func hasCapability(capabilities []string, want string) bool {
+	for _, c := range capabilities {
+		if c == want {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOf strips the scheme from a registry URL for use as a metrics label
+// and as the prefix MirroredClient rewrites out of an image reference.
+func hostOf(registryURL string) string {
+	host := strings.TrimPrefix(registryURL, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	return strings.TrimSuffix(host, "/")
+}
+
+// stripOriginHost removes an embedded origin-host prefix from image, since
+// each per-mirror/origin client is already scoped to its own host and (like
+// GenericClient) expects a bare "repo:tag" reference.
+func stripOriginHost(image, originHost string) string {
+	return strings.TrimPrefix(image, originHost+"/")
+}
+
+// GetSupportedArchitectures tries each configured mirror in order, skipping
+// any whose circuit breaker is open, and falls back to the origin registry
+// once every mirror has been skipped or has failed with a 404, an auth
+// failure, a 5xx or a timeout.
+func (c *MirroredClient) GetSupportedArchitectures(ctx context.Context, image string) ([]string, error) {
+	rewritten := stripOriginHost(image, c.originHost)
+
+	for _, entry := range c.mirrors {
+		if !entry.breaker.allow() {
+			c.metrics.RecordRegistryMirrorRequest(entry.host, "breaker_open")
+			continue
+		}
+
+		archs, err := entry.client.GetSupportedArchitectures(ctx, rewritten)
+		if err == nil {
+			entry.breaker.recordSuccess()
+			c.metrics.RecordRegistryMirrorRequest(entry.host, "success")
+			return archs, nil
+		}
+
+		result := classifyMirrorErr(ctx, err)
+		if result != "not_found" && result != "unauthorized" {
+			// 404/unauthorized are expected fallback triggers (the mirror is
+			// reachable but doesn't have the image, or needs different
+			// credentials); anything else (5xx, timeout, connection
+			// failure) counts against the breaker.
+			entry.breaker.recordFailure()
+		}
+		c.metrics.RecordRegistryMirrorRequest(entry.host, result)
+	}
+
+	archs, err := c.origin.GetSupportedArchitectures(ctx, rewritten)
+	if err != nil {
+		c.metrics.RecordRegistryMirrorRequest("origin", classifyMirrorErr(ctx, err))
+		return nil, err
+	}
+	c.metrics.RecordRegistryMirrorRequest("origin", "success")
+	return archs, nil
+}
+
+// classifyMirrorErr buckets err into a low-cardinality result label for
+// archy_registry_mirror_requests_total, and tells GetSupportedArchitectures
+// whether the failure should count against the mirror's circuit breaker.
+func classifyMirrorErr(ctx context.Context, err error) string {
+	if ctx.Err() == context.DeadlineExceeded {
+		return "timeout"
+	}
+
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "not found"):
+		return "not_found"
+	case strings.Contains(msg, "unauthorized") || strings.Contains(msg, "401") || strings.Contains(msg, "403"):
+		return "unauthorized"
+	case strings.Contains(msg, "unexpected status"):
+		return "server_error"
+	default:
+		return "error"
+	}
+}
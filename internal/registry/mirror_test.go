@@ -0,0 +1,178 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lsdopen/archy/internal/metrics"
+)
+
+const sampleHostsTOML = `
+server = "https://registry-1.docker.io"
+
+[host."https://mirror.internal.example.com"]
+capabilities = ["pull", "resolve"]
+
+[host."https://backup-mirror.example.com"]
+capabilities = ["pull"]
+
+[host."https://push-only.example.com"]
+capabilities = ["resolve"]
+`
+
+func TestParseHostsTOML_ParsesServerAndMirrors(t *testing.T) {
+	cfg, err := ParseHostsTOML([]byte(sampleHostsTOML))
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://registry-1.docker.io", cfg.Server)
+	require.Len(t, cfg.Mirrors, 3)
+	assert.Equal(t, "https://mirror.internal.example.com", cfg.Mirrors[0].URL)
+	assert.Equal(t, []string{"pull", "resolve"}, cfg.Mirrors[0].Capabilities)
+	assert.Equal(t, []string{"pull"}, cfg.Mirrors[1].Capabilities)
+	assert.Equal(t, []string{"resolve"}, cfg.Mirrors[2].Capabilities)
+}
+
+func TestParseHostsTOML_MissingServerErrors(t *testing.T) {
+	_, err := ParseHostsTOML([]byte(`[host."https://mirror.example.com"]
+capabilities = ["pull"]
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing top-level server")
+}
+
+func TestParseHostsTOML_CapabilitiesOutsideSectionErrors(t *testing.T) {
+	_, err := ParseHostsTOML([]byte(`server = "https://origin.example.com"
+capabilities = ["pull"]
+`))
+	require.Error(t, err)
+}
+
+func manifestHandler(arch string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"manifests":[{"platform":{"architecture":"` + arch + `"}}]}`))
+	}
+}
+
+func TestMirroredClient_UsesMirrorWhenAvailable(t *testing.T) {
+	var originCalled int32
+
+	mirror := httptest.NewServer(manifestHandler("arm64"))
+	defer mirror.Close()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&originCalled, 1)
+		manifestHandler("amd64")(w, r)
+	}))
+	defer origin.Close()
+
+	cfg := &HostConfig{
+		Server:  origin.URL,
+		Mirrors: []MirrorHost{{URL: mirror.URL, Capabilities: []string{"pull"}}},
+	}
+	mc, err := NewMirroredClient(cfg, metrics.NewMetrics())
+	require.NoError(t, err)
+
+	archs, err := mc.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arm64"}, archs)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&originCalled))
+}
+
+func TestMirroredClient_SkipsNonPullMirror(t *testing.T) {
+	mirror := httptest.NewServer(manifestHandler("arm64"))
+	defer mirror.Close()
+
+	origin := httptest.NewServer(manifestHandler("amd64"))
+	defer origin.Close()
+
+	cfg := &HostConfig{
+		Server:  origin.URL,
+		Mirrors: []MirrorHost{{URL: mirror.URL, Capabilities: []string{"resolve"}}},
+	}
+	mc, err := NewMirroredClient(cfg, metrics.NewMetrics())
+	require.NoError(t, err)
+	assert.Empty(t, mc.mirrors)
+
+	archs, err := mc.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"amd64"}, archs)
+}
+
+func TestMirroredClient_FallsBackToOriginOnMirrorNotFound(t *testing.T) {
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer mirror.Close()
+
+	origin := httptest.NewServer(manifestHandler("amd64"))
+	defer origin.Close()
+
+	cfg := &HostConfig{
+		Server:  origin.URL,
+		Mirrors: []MirrorHost{{URL: mirror.URL, Capabilities: []string{"pull"}}},
+	}
+	mc, err := NewMirroredClient(cfg, metrics.NewMetrics())
+	require.NoError(t, err)
+
+	archs, err := mc.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"amd64"}, archs)
+}
+
+func TestMirroredClient_BreakerOpensAfterConsecutiveServerErrors(t *testing.T) {
+	var mirrorCalled int32
+
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		atomic.AddInt32(&mirrorCalled, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer mirror.Close()
+
+	origin := httptest.NewServer(manifestHandler("amd64"))
+	defer origin.Close()
+
+	cfg := &HostConfig{
+		Server:  origin.URL,
+		Mirrors: []MirrorHost{{URL: mirror.URL, Capabilities: []string{"pull"}}},
+	}
+	mc, err := NewMirroredClient(cfg, metrics.NewMetrics())
+	require.NoError(t, err)
+
+	for i := 0; i < breakerThreshold; i++ {
+		archs, err := mc.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"amd64"}, archs)
+	}
+	require.Equal(t, int32(breakerThreshold), atomic.LoadInt32(&mirrorCalled))
+
+	// The breaker is now open: a further request must skip the mirror
+	// entirely rather than hitting it a 4th time.
+	archs, err := mc.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"amd64"}, archs)
+	assert.Equal(t, int32(breakerThreshold), atomic.LoadInt32(&mirrorCalled))
+}
+
+func TestStripOriginHost_RemovesEmbeddedHostPrefix(t *testing.T) {
+	assert.Equal(t, "myorg/app:latest", stripOriginHost("registry.example.com/myorg/app:latest", "registry.example.com"))
+	assert.Equal(t, "myorg/app:latest", stripOriginHost("myorg/app:latest", "registry.example.com"))
+}
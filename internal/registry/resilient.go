@@ -0,0 +1,383 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/lsdopen/archy/internal/metrics"
+	"github.com/lsdopen/archy/pkg/types"
+)
+
+// manifestFetcher is implemented by registry clients that can resolve an
+// image reference to its raw manifest bytes and content digest in one round
+// trip (see GenericClient.FetchManifest). ResilientClient uses it, when
+// available, to store each architecture result under the content digest as
+// well as under the image reference. Lookups go by the image reference only,
+// so content pushed to an existing tag is not seen until that tag's entry
+// expires (defaultPositiveTTL).
+type manifestFetcher interface {
+	FetchManifest(ctx context.Context, image string) (repo, digest string, body []byte, err error)
+}
+
+// positiveEntry is a cached, successfully resolved architecture list.
+type positiveEntry struct {
+	archs   []string
+	expires time.Time
+}
+
+// negativeEntry remembers that image recently failed to resolve, so repeated
+// admission requests for a broken or unreachable image don't each retry
+// upstream. retryAt backs off exponentially (with jitter) as failures
+// accumulate, up to negativeMaxTTL.
+type negativeEntry struct {
+	err      error
+	failures int
+	retryAt  time.Time
+}
+
+// hostLimiter is a token-bucket rate limiter for a single registry host,
+// guarding against tripping a registry's pull-rate limit (e.g. Docker Hub's
+// 100-pulls/6h for anonymous pulls) when many pods reference images on the
+// same host.
+type hostLimiter struct {
+	mu         sync.Mutex
+	tokens     float64
+	maxTokens  float64
+	refillRate float64 // tokens per second
+	last       time.Time
+}
+
+func newHostLimiter(maxTokens, refillPerSecond float64) *hostLimiter {
+	return &hostLimiter{
+		tokens:     maxTokens,
+		maxTokens:  maxTokens,
+		refillRate: refillPerSecond,
+		last:       time.Now(),
+	}
+}
+
+// Allow reports whether a request against the host may proceed right now,
+// consuming one token if so.
+func (l *hostLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.refillRate
+	if l.tokens > l.maxTokens {
+		l.tokens = l.maxTokens
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+const (
+	// defaultHostBucketSize and defaultHostRefillWindow model Docker Hub's
+	// anonymous pull limit (100 pulls per 6 hours) as a token bucket; other
+	// registries are generally more permissive, so this is a conservative
+	// shared default per host rather than a per-registry policy.
+	defaultHostBucketSize   = 100
+	defaultHostRefillWindow = 6 * time.Hour
+
+	defaultPositiveTTL     = 5 * time.Minute
+	defaultNegativeBaseTTL = 10 * time.Second
+	defaultNegativeMaxTTL  = 5 * time.Minute
+)
+
+// ResilientClient sits in front of any number of upstream types.RegistryClient
+// instances (one per registry host), adding:
+//
+//   - singleflight coalescing, so concurrent admission requests for the same
+//     image issue exactly one upstream manifest fetch;
+//   - a two-tier cache: positive results looked up by image reference (and
+//     also stored under the content digest when upstream resolves one) with
+//     a long TTL, and negative results (auth failures, 404s,
+//     malformed manifests) with a short TTL that backs off exponentially
+//     (with jitter) on repeated failures;
+//   - a token-bucket rate limiter per registry host.
+//
+// A single ResilientClient is meant to be shared across all admission
+// requests (see Mutator), not created per request, so its cache and
+// singleflight group actually coalesce concurrent callers.
+type ResilientClient struct {
+	metrics *metrics.Metrics
+
+	hostBucketSize   float64
+	hostRefillWindow time.Duration
+
+	group singleflight.Group
+
+	mu       sync.Mutex
+	positive map[string]positiveEntry
+	negative map[string]*negativeEntry
+	limiters map[string]*hostLimiter
+}
+
+// NewResilientClient creates a ResilientClient that records cache and
+// upstream metrics through m, rate-limiting each registry host to
+// defaultHostBucketSize requests per defaultHostRefillWindow.
+func NewResilientClient(m *metrics.Metrics) *ResilientClient {
+	return NewResilientClientWithLimiter(m, defaultHostBucketSize, defaultHostRefillWindow)
+}
+
+// NewResilientClientWithLimiter creates a ResilientClient like
+// NewResilientClient, but rate-limiting each registry host to bucketSize
+// requests per refillWindow instead of the Docker-Hub-shaped default, for
+// registries with a different or more permissive pull-rate policy.
+func NewResilientClientWithLimiter(m *metrics.Metrics, bucketSize float64, refillWindow time.Duration) *ResilientClient {
+	return &ResilientClient{
+		metrics:          m,
+		hostBucketSize:   bucketSize,
+		hostRefillWindow: refillWindow,
+		positive:         make(map[string]positiveEntry),
+		negative:         make(map[string]*negativeEntry),
+		limiters:         make(map[string]*hostLimiter),
+	}
+}
+
+// Wrap returns a types.RegistryClient that resolves images against upstream
+// (a client for registry host), coalescing and caching lookups through c.
+// Wrap this after any mirror/inspector wrapping so callers that need
+// upstream's FetchManifest/FetchSignature/FetchAttestations can still
+// type-assert the pre-wrap client directly (see resilientWrapped).
+func (c *ResilientClient) Wrap(upstream types.RegistryClient, host string) types.RegistryClient {
+	return &resilientWrapped{client: c, upstream: upstream, host: host}
+}
+
+func (c *ResilientClient) limiterFor(host string) *hostLimiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, ok := c.limiters[host]
+	if !ok {
+		l = newHostLimiter(c.hostBucketSize, c.hostBucketSize/c.hostRefillWindow.Seconds())
+		c.limiters[host] = l
+	}
+	return l
+}
+
+func (c *ResilientClient) lookupPositive(key string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.positive[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.positive, key)
+		return nil, false
+	}
+	return entry.archs, true
+}
+
+func (c *ResilientClient) storePositive(key string, archs []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.positive[key] = positiveEntry{archs: archs, expires: time.Now().Add(defaultPositiveTTL)}
+	delete(c.negative, key)
+}
+
+func (c *ResilientClient) lookupNegative(key string) (error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.negative[key]
+	if !ok || time.Now().After(entry.retryAt) {
+		return nil, false
+	}
+	return entry.err, true
+}
+
+// storeNegative records image's failure, doubling the backoff window on each
+// consecutive failure (capped at defaultNegativeMaxTTL) and adding up to 20%
+// jitter so a thundering herd of retries doesn't re-synchronize.
+func (c *ResilientClient) storeNegative(key string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.negative[key]
+	if !ok {
+		entry = &negativeEntry{}
+	}
+	entry.err = err
+	entry.failures++
+
+	backoff := defaultNegativeBaseTTL * (1 << uint(entry.failures-1))
+	if backoff > defaultNegativeMaxTTL {
+		backoff = defaultNegativeMaxTTL
+	}
+	jitter := time.Duration(rand.Int63n(int64(backoff) / 5)) // up to 20%
+	entry.retryAt = time.Now().Add(backoff + jitter)
+
+	c.negative[key] = entry
+	delete(c.positive, key)
+}
+
+// getSupportedArchitectures is the shared implementation behind
+// resilientWrapped.GetSupportedArchitectures.
+func (c *ResilientClient) getSupportedArchitectures(ctx context.Context, upstream types.RegistryClient, host, image string) ([]string, error) {
+	if err, found := c.lookupNegative(negativeCacheKey(host, image)); found {
+		c.metrics.RecordRegistryCacheHit(host)
+		return nil, err
+	}
+
+	digestFetcher, _ := upstream.(manifestFetcher)
+
+	// Positive results are looked up by the image reference itself; the
+	// digest-keyed entry stored below is not consulted here, so a re-pushed
+	// tag is only resolved again once its entry expires.
+	if archs, found := c.lookupPositive(image); found {
+		c.metrics.RecordRegistryCacheHit(host)
+		return archs, nil
+	}
+	c.metrics.RecordRegistryCacheMiss(host)
+
+	limiter := c.limiterFor(host)
+
+	result, err, shared := c.group.Do(host+"|"+image, func() (interface{}, error) {
+		if !limiter.Allow() {
+			c.metrics.RecordRegistryResponse(host, "rate_limited")
+			rlErr := fmt.Errorf("rate limit exceeded for registry host %s", host)
+			c.storeNegative(negativeCacheKey(host, image), rlErr)
+			return nil, rlErr
+		}
+
+		start := time.Now()
+		archs, digest, fetchErr := resolveArchitectures(ctx, upstream, digestFetcher, image)
+		c.metrics.RecordRegistryUpstreamLatency(host, time.Since(start))
+		c.metrics.RecordRegistryResponse(host, classifyOutcome(fetchErr))
+
+		if fetchErr != nil {
+			c.storeNegative(negativeCacheKey(host, image), fetchErr)
+			return nil, fetchErr
+		}
+
+		cacheKey := image
+		if digest != "" {
+			cacheKey = digest
+		}
+		c.storePositive(cacheKey, archs)
+		// Also cache under the tag so the next lookup for the same tag hits
+		// without re-resolving the digest, until the positive TTL expires.
+		if cacheKey != image {
+			c.storePositive(image, archs)
+		}
+
+		return archs, nil
+	})
+	if shared {
+		c.metrics.RecordRegistrySingleflightShared(host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]string), nil
+}
+
+// resolveArchitectures fetches image's manifest once and returns both its
+// architectures and (when digestFetcher is available) its content digest, so
+// callers can also cache the result under the digest.
+func resolveArchitectures(ctx context.Context, upstream types.RegistryClient, digestFetcher manifestFetcher, image string) ([]string, string, error) {
+	if digestFetcher == nil {
+		archs, err := upstream.GetSupportedArchitectures(ctx, image)
+		return archs, "", err
+	}
+
+	_, digest, body, err := digestFetcher.FetchManifest(ctx, image)
+	if err != nil {
+		return nil, "", err
+	}
+
+	archs, err := decodeArchitectures(body)
+	if err != nil {
+		return nil, digest, err
+	}
+	return archs, digest, nil
+}
+
+// decodeArchitectures extracts the set of platform architectures from a raw
+// OCI/Docker manifest list body. It intentionally doesn't require a
+// particular schemaVersion: GenericClient.GetSupportedArchitectures is
+// equally lenient, and this must agree with it since resolveArchitectures
+// uses whichever path is available for the same upstream.
+func decodeArchitectures(body []byte) ([]string, error) {
+	var manifest ManifestList
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("decoding manifest: %w", err)
+	}
+
+	var archs []string
+	seen := make(map[string]bool)
+	for _, m := range manifest.Manifests {
+		arch := m.Platform.Architecture
+		if arch != "" && !seen[arch] {
+			archs = append(archs, arch)
+			seen[arch] = true
+		}
+	}
+
+	if len(archs) == 0 {
+		return []string{"amd64"}, nil // Default fallback, mirroring GenericClient.
+	}
+
+	return archs, nil
+}
+
+// negativeCacheKey scopes negative entries by host as well as image, since a
+// rate-limit failure is host-specific even when the same image reference is
+// later resolved through a different client (e.g. after a credential refresh
+// changes which client NewClientWithCredentials returns).
+func negativeCacheKey(host, image string) string {
+	return host + "|" + image
+}
+
+// classifyOutcome buckets err into a low-cardinality outcome label for
+// archy_registry_responses_total.
+func classifyOutcome(err error) string {
+	if err == nil {
+		return "ok"
+	}
+
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "rate limit"):
+		return "rate_limited"
+	case strings.Contains(msg, "not found"):
+		return "not_found"
+	case strings.Contains(msg, "unexpected status"):
+		return "server_error"
+	default:
+		return "error"
+	}
+}
+
+// resilientWrapped is the types.RegistryClient ResilientClient.Wrap returns.
+// It deliberately implements only GetSupportedArchitectures: callers that
+// also need FetchManifest/FetchSignature/FetchAttestations (internal/mirror,
+// internal/verify) should type-assert the pre-wrap client instead, since a
+// passthrough here would make those type assertions succeed unconditionally
+// regardless of whether the wrapped upstream actually supports them.
+type resilientWrapped struct {
+	client   *ResilientClient
+	upstream types.RegistryClient
+	host     string
+}
+
+func (w *resilientWrapped) GetSupportedArchitectures(ctx context.Context, image string) ([]string, error) {
+	return w.client.getSupportedArchitectures(ctx, w.upstream, w.host, image)
+}
@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lsdopen/archy/internal/metrics"
+)
+
+// countingClient records how many times GetSupportedArchitectures was
+// actually invoked, optionally blocking until release is closed so tests can
+// assert concurrent callers coalesce into a single upstream call.
+type countingClient struct {
+	calls   int32
+	archs   []string
+	err     error
+	release chan struct{}
+}
+
+func (c *countingClient) GetSupportedArchitectures(ctx context.Context, image string) ([]string, error) {
+	atomic.AddInt32(&c.calls, 1)
+	if c.release != nil {
+		<-c.release
+	}
+	return c.archs, c.err
+}
+
+func TestResilientClient_CoalescesConcurrentLookups(t *testing.T) {
+	upstream := &countingClient{archs: []string{"amd64", "arm64"}, release: make(chan struct{})}
+	rc := NewResilientClient(metrics.NewMetrics())
+	wrapped := rc.Wrap(upstream, "registry.example.com")
+
+	var wg sync.WaitGroup
+	results := make([][]string, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			archs, err := wrapped.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+			require.NoError(t, err)
+			results[idx] = archs
+		}(i)
+	}
+
+	// Let every goroutine reach the upstream call before releasing it, so
+	// this genuinely exercises coalescing rather than serialized calls.
+	time.Sleep(50 * time.Millisecond)
+	close(upstream.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&upstream.calls))
+	for _, archs := range results {
+		assert.Equal(t, []string{"amd64", "arm64"}, archs)
+	}
+}
+
+func TestResilientClient_CachesPositiveResult(t *testing.T) {
+	upstream := &countingClient{archs: []string{"amd64"}}
+	rc := NewResilientClient(metrics.NewMetrics())
+	wrapped := rc.Wrap(upstream, "registry.example.com")
+
+	_, err := wrapped.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+	require.NoError(t, err)
+
+	archs, err := wrapped.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"amd64"}, archs)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&upstream.calls))
+}
+
+func TestResilientClient_NegativeCachesFailure(t *testing.T) {
+	upstream := &countingClient{err: fmt.Errorf("manifest not found")}
+	rc := NewResilientClient(metrics.NewMetrics())
+	wrapped := rc.Wrap(upstream, "registry.example.com")
+
+	_, err := wrapped.GetSupportedArchitectures(context.Background(), "myorg/missing:latest")
+	require.Error(t, err)
+
+	// A second lookup shortly after should be served from the negative
+	// cache rather than retrying upstream immediately.
+	_, err = wrapped.GetSupportedArchitectures(context.Background(), "myorg/missing:latest")
+	require.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&upstream.calls))
+}
+
+func TestResilientClient_RateLimitsPerHost(t *testing.T) {
+	upstream := &countingClient{archs: []string{"amd64"}}
+	rc := NewResilientClient(metrics.NewMetrics())
+
+	// Exhaust the bucket directly so the test doesn't depend on the default
+	// window (6h); the image is in neither the positive nor the negative
+	// cache, so the lookup reaches the limiter.
+	limiter := rc.limiterFor("registry.example.com")
+	for i := 0; i < defaultHostBucketSize; i++ {
+		limiter.Allow()
+	}
+
+	wrapped := rc.Wrap(upstream, "registry.example.com")
+	_, err := wrapped.GetSupportedArchitectures(context.Background(), "myorg/app:latest")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rate limit")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&upstream.calls))
+}
+
+func TestResilientClient_DistinctHostsHaveIndependentBuckets(t *testing.T) {
+	rc := NewResilientClient(metrics.NewMetrics())
+
+	a := rc.limiterFor("a.example.com")
+	for i := 0; i < defaultHostBucketSize; i++ {
+		a.Allow()
+	}
+	assert.False(t, a.Allow())
+
+	b := rc.limiterFor("b.example.com")
+	assert.True(t, b.Allow())
+}
+
+func TestResilientClient_CustomLimiterOverridesDefault(t *testing.T) {
+	rc := NewResilientClientWithLimiter(metrics.NewMetrics(), 1, time.Hour)
+
+	limiter := rc.limiterFor("registry.example.com")
+	assert.True(t, limiter.Allow())
+	assert.False(t, limiter.Allow())
+}
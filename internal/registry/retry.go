@@ -0,0 +1,97 @@
+package registry
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultMaxRetryAfterWait caps how long a single retry sleep (whether
+	// driven by a registry's own Retry-After header or our own backoff) is
+	// allowed to run, so a registry advertising an hours-long Retry-After
+	// doesn't stall an admission request indefinitely.
+	defaultMaxRetryAfterWait = 30 * time.Second
+
+	// maxRetryAfterAttempts bounds the total number of requests, the first
+	// one included, made while a registry answers 429/503 before the caller
+	// gives up and reports the failure.
+	maxRetryAfterAttempts = 3
+)
+
+// parseRetryAfter parses an HTTP Retry-After header value in either of its
+// two RFC 7231 forms: an integer number of seconds, or an HTTP-date. It
+// reports false if header is empty or matches neither form.
+func parseRetryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(header); err == nil {
+		if secs < 0 {
+			secs = 0
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if when, err := http.ParseTime(header); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// retryAfterBackoff returns how long to sleep before retrying a 429/503
+// response on the given 1-indexed attempt: the registry's own Retry-After
+// value when retryAfterHeader carries one, otherwise exponential backoff
+// from 500ms with up to 20% jitter so concurrent callers don't retry in
+// lockstep. Either way the result is capped at maxWait.
+func retryAfterBackoff(retryAfterHeader string, attempt int, maxWait time.Duration) time.Duration {
+	if maxWait <= 0 {
+		maxWait = defaultMaxRetryAfterWait
+	}
+
+	if d, ok := parseRetryAfter(retryAfterHeader); ok {
+		if d > maxWait {
+			return maxWait
+		}
+		return d
+	}
+
+	base := 500 * time.Millisecond * time.Duration(uint(1)<<uint(attempt-1))
+	jitter := time.Duration(rand.Int63n(int64(base)/5 + 1))
+	d := base + jitter
+	if d > maxWait {
+		return maxWait
+	}
+	return d
+}
+
+// sleepOrDone sleeps for d, returning early with ctx's error if ctx is
+// cancelled first.
+func sleepOrDone(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryableStatus reports whether statusCode is one a registry client
+// should retry (honoring Retry-After) rather than treat as final.
+func retryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
@@ -0,0 +1,57 @@
+package registry
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryAfter_IntegerSeconds(t *testing.T) {
+	d, ok := parseRetryAfter("120")
+
+	assert.True(t, ok)
+	assert.Equal(t, 120*time.Second, d)
+}
+
+func TestParseRetryAfter_HTTPDate(t *testing.T) {
+	when := time.Now().Add(90 * time.Second)
+
+	d, ok := parseRetryAfter(when.UTC().Format(http.TimeFormat))
+
+	assert.True(t, ok)
+	assert.InDelta(t, 90*time.Second, d, float64(2*time.Second))
+}
+
+func TestParseRetryAfter_Empty(t *testing.T) {
+	_, ok := parseRetryAfter("")
+
+	assert.False(t, ok)
+}
+
+func TestParseRetryAfter_Malformed(t *testing.T) {
+	_, ok := parseRetryAfter("not-a-valid-value")
+
+	assert.False(t, ok)
+}
+
+func TestRetryAfterBackoff_HonorsHeaderCappedAtMaxWait(t *testing.T) {
+	d := retryAfterBackoff("120", 1, 10*time.Second)
+
+	assert.Equal(t, 10*time.Second, d)
+}
+
+func TestRetryAfterBackoff_FallsBackToExponentialWithoutHeader(t *testing.T) {
+	d := retryAfterBackoff("", 1, time.Second)
+
+	assert.Greater(t, d, time.Duration(0))
+	assert.LessOrEqual(t, d, time.Second)
+}
+
+func TestRetryableStatus(t *testing.T) {
+	assert.True(t, retryableStatus(http.StatusTooManyRequests))
+	assert.True(t, retryableStatus(http.StatusServiceUnavailable))
+	assert.False(t, retryableStatus(http.StatusNotFound))
+	assert.False(t, retryableStatus(http.StatusOK))
+}
@@ -0,0 +1,462 @@
+// Package verify checks Cosign/Sigstore signatures over image manifests
+// before the webhook trusts the architectures they declare.
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mode controls how a Verifier reacts to a manifest that fails signature
+// verification.
+type Mode string
+
+const (
+	// ModeEnforce rejects manifests that fail verification.
+	ModeEnforce Mode = "enforce"
+	// ModeWarn records a failure but does not reject it.
+	ModeWarn Mode = "warn"
+	// ModeOff disables verification entirely.
+	ModeOff Mode = "off"
+)
+
+const signatureAnnotation = "dev.cosignproject.cosign/signature"
+
+// SignatureBundle is the subset of a Cosign signature manifest (the
+// "<repo>:sha256-<hex>.sig" tag, in the legacy tag-based layout) that
+// Verifier needs: one descriptor annotation per signature, optionally
+// carrying a Fulcio certificate for keyless verification.
+type SignatureBundle struct {
+	Layers []struct {
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+// SignatureFetcher is implemented by registry clients that can retrieve the
+// Cosign signature bundle for an image, alongside the repository name and
+// manifest digest it covers. Clients that don't implement it are treated as
+// unverifiable.
+type SignatureFetcher interface {
+	FetchSignature(ctx context.Context, image string) (repo, digest string, bundle []byte, err error)
+}
+
+// AttestationFetcher is implemented by registry clients that can retrieve
+// the Cosign in-toto attestation bundle for an image (the "<repo>:sha256-<hex>.att"
+// tag), mirroring SignatureFetcher for the separate artifact Cosign stores
+// SLSA/in-toto attestations under.
+type AttestationFetcher interface {
+	FetchAttestations(ctx context.Context, image string) (repo, digest string, bundle []byte, err error)
+}
+
+// Verifier checks Cosign signatures, caching the verification result per
+// manifest digest so the same image is never re-verified on every admission
+// request.
+type Verifier struct {
+	mode              Mode
+	publicKey         *ecdsa.PublicKey
+	keylessIdentities []*regexp.Regexp
+	oidcIssuer        string
+
+	mu    sync.RWMutex
+	cache map[string]bool
+	ttl   time.Duration
+}
+
+// NewVerifier creates a Verifier for mode. keyPath, when non-empty, is a PEM
+// file containing an ECDSA P-256 Cosign public key used for static-key
+// verification. keylessIdentities are regexes matched against a signing
+// certificate's SAN for keyless (Fulcio) verification; oidcIssuer, when
+// non-empty, additionally requires that certificate's issuer to match.
+//
+// Keyless verification cannot be configured with mode=ModeEnforce: it does
+// not chain the signing certificate to a trusted Fulcio root or check its
+// Rekor inclusion proof (this tree doesn't vendor that trust material yet),
+// so an attacker could mint a self-signed certificate with a SAN matching
+// keylessIdentities and defeat enforcement entirely. Until that chain-of-trust
+// check exists, keyless identities may only be configured alongside
+// ModeWarn/ModeOff, where a bypass costs nothing but a missed warning.
+func NewVerifier(mode Mode, keyPath string, keylessIdentities []string, oidcIssuer string) (*Verifier, error) {
+	v := &Verifier{
+		mode:       mode,
+		oidcIssuer: oidcIssuer,
+		cache:      make(map[string]bool),
+		ttl:        1 * time.Hour,
+	}
+
+	if keyPath != "" {
+		key, err := loadPublicKey(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cosign public key: %w", err)
+		}
+		v.publicKey = key
+	}
+
+	for _, pattern := range keylessIdentities {
+		if pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keyless identity pattern %q: %w", pattern, err)
+		}
+		v.keylessIdentities = append(v.keylessIdentities, re)
+	}
+
+	if mode == ModeEnforce && len(v.keylessIdentities) > 0 {
+		return nil, fmt.Errorf("keyless (Fulcio) verification cannot be enforced: no Fulcio root/Rekor chain-of-trust check is implemented yet, so enforce mode would be bypassable with a self-signed certificate; configure a static cosign key for enforce mode, or use warn mode with keyless identities")
+	}
+
+	return v, nil
+}
+
+func loadPublicKey(keyPath string) (*ecdsa.PublicKey, error) {
+	pemBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in %s", keyPath)
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key: %w", err)
+	}
+
+	ecKey, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("cosign key must be ECDSA, got %T", pub)
+	}
+
+	return ecKey, nil
+}
+
+// VerifyManifest checks the signature bundle fetched for image/digest and
+// reports whether it is trusted, consulting (and populating) the per-digest
+// cache. A non-nil error describes why verification could not be completed
+// even when the boolean result already reflects mode-appropriate behavior.
+func (v *Verifier) VerifyManifest(repo, digest string, bundleBytes []byte) (bool, error) {
+	if v.mode == ModeOff {
+		return true, nil
+	}
+
+	if ok, found := v.cachedResult(digest); found {
+		return ok, nil
+	}
+
+	ok, err := v.verifyBundle(repo, digest, bundleBytes)
+	v.setCachedResult(digest, ok)
+	return ok, err
+}
+
+// expectedPayload reconstructs the canonical Cosign "simple signing" payload
+// for repo/digest. Cosign signs this exact JSON document rather than the raw
+// manifest bytes, so verifying against it does not require fetching the
+// signature layer's blob content.
+func expectedPayload(repo, digest string) []byte {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"critical": map[string]interface{}{
+			"identity": map[string]string{"docker-reference": repo},
+			"image":    map[string]string{"docker-manifest-digest": digest},
+			"type":     "cosign container image signature",
+		},
+		"optional": nil,
+	})
+	return payload
+}
+
+func (v *Verifier) verifyBundle(repo, digest string, bundleBytes []byte) (bool, error) {
+	var bundle SignatureBundle
+	if err := json.Unmarshal(bundleBytes, &bundle); err != nil {
+		return false, fmt.Errorf("parsing signature bundle: %w", err)
+	}
+
+	payload := expectedPayload(repo, digest)
+	payloadHash := sha256.Sum256(payload)
+
+	for _, layer := range bundle.Layers {
+		sigB64 := layer.Annotations[signatureAnnotation]
+		if sigB64 == "" {
+			continue
+		}
+
+		sig, err := base64.StdEncoding.DecodeString(sigB64)
+		if err != nil {
+			continue
+		}
+
+		if cert, ok := layer.Annotations["dev.sigstore.cosign/certificate"]; ok && cert != "" {
+			if v.verifyKeyless(cert, payloadHash[:], sig) {
+				return true, nil
+			}
+			continue
+		}
+
+		if v.publicKey != nil && ecdsa.VerifyASN1(v.publicKey, payloadHash[:], sig) {
+			return true, nil
+		}
+	}
+
+	return false, fmt.Errorf("no signature in bundle verified against the configured key/identities")
+}
+
+// verifyKeyless checks a Fulcio-issued certificate's SAN against the
+// configured identity patterns and its signature over payloadHash.
+//
+// Known limitation: this does not verify the certificate chains up to a
+// trusted Fulcio root, nor does it check the attached Rekor inclusion proof
+// against the bundled Rekor public key (both require bundling the Fulcio and
+// Rekor root material this tree does not yet vendor). It only checks that
+// the embedded certificate's SAN/issuer match the operator's configuration
+// and that the signature itself verifies against that certificate's key.
+func (v *Verifier) verifyKeyless(certPEM string, payloadHash, sig []byte) bool {
+	if len(v.keylessIdentities) == 0 {
+		return false
+	}
+
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return false
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+
+	if !v.identityMatches(cert) {
+		return false
+	}
+
+	ecKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return false
+	}
+
+	return ecdsa.VerifyASN1(ecKey, payloadHash, sig)
+}
+
+// identityMatches reports whether cert's SAN matches one of the configured
+// keylessIdentities patterns and, if an oidcIssuer was configured, whether
+// the certificate's issuer also matches it.
+//
+// A real Fulcio certificate encodes its OIDC issuer in a dedicated
+// extension (OID 1.3.6.1.4.1.57264.1.1/.8), not the issuer distinguished
+// name; this checks the certificate's issuer organization instead, since
+// that's the only issuer-shaped field available without the Fulcio-specific
+// extension parsing this tree doesn't implement yet. Combined with
+// NewVerifier refusing keyless identities in enforce mode, this check is
+// advisory (it narrows what a warn-mode verification warns about) rather
+// than a trust boundary.
+func (v *Verifier) identityMatches(cert *x509.Certificate) bool {
+	if v.oidcIssuer != "" && !issuerMatches(cert, v.oidcIssuer) {
+		return false
+	}
+
+	candidates := append(append([]string{}, cert.EmailAddresses...), certURIs(cert)...)
+
+	for _, candidate := range candidates {
+		for _, re := range v.keylessIdentities {
+			if re.MatchString(candidate) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func issuerMatches(cert *x509.Certificate, oidcIssuer string) bool {
+	for _, org := range cert.Issuer.Organization {
+		if org == oidcIssuer {
+			return true
+		}
+	}
+	return false
+}
+
+func certURIs(cert *x509.Certificate) []string {
+	uris := make([]string, 0, len(cert.URIs))
+	for _, u := range cert.URIs {
+		uris = append(uris, u.String())
+	}
+	return uris
+}
+
+func (v *Verifier) cachedResult(digest string) (bool, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	ok, found := v.cache[digest]
+	return ok, found
+}
+
+func (v *Verifier) setCachedResult(digest string, ok bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.cache[digest] = ok
+}
+
+// Allow reports whether, given a verification result and the Verifier's
+// mode, the caller should proceed as if the manifest were trusted.
+func (v *Verifier) Allow(verified bool) bool {
+	if v.mode != ModeEnforce {
+		return true
+	}
+	return verified
+}
+
+// Mode returns the verifier's configured mode.
+func (v *Verifier) Mode() Mode {
+	return v.mode
+}
+
+// SigTag derives the conventional Cosign signature tag for a manifest
+// digest, e.g. "sha256:abcd..." -> "sha256-abcd....sig", so the tag
+// convention lives in one place shared by registry clients implementing
+// SignatureFetcher.
+func SigTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1) + ".sig"
+}
+
+// AttestationTag derives the conventional Cosign attestation tag for a
+// manifest digest, mirroring SigTag but for the ".att"-suffixed tag Cosign
+// uses to store in-toto/SLSA attestations separately from signatures.
+func AttestationTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1) + ".att"
+}
+
+// dsseEnvelope is the subset of a DSSE (Dead Simple Signing Envelope) that
+// VerifyAttestations needs: the base64-encoded in-toto statement payload and
+// the signatures over it.
+type dsseEnvelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+	Signatures  []struct {
+		Sig string `json:"sig"`
+	} `json:"signatures"`
+}
+
+// inTotoStatement is the subset of an in-toto attestation statement
+// VerifyAttestations needs to enforce a predicate-type policy (e.g. SLSA
+// provenance).
+type inTotoStatement struct {
+	PredicateType string `json:"predicateType"`
+}
+
+// VerifyAttestations checks that bundleBytes (a Cosign attestation bundle,
+// in the same tag-based manifest layout as a signature bundle) contains at
+// least one DSSE-enveloped in-toto statement whose predicate type is in
+// requiredPredicateTypes (e.g. "https://slsa.dev/provenance/v1"), verifying
+// its signature against the Verifier's configured static key. An empty
+// requiredPredicateTypes always passes, since no attestation policy is
+// configured to enforce.
+//
+// Known limitation: unlike VerifyManifest, this only supports static-key
+// verification, not keyless/Fulcio-issued certificates — the DSSE envelope
+// format cosign uses for attestations doesn't embed the signing certificate
+// the way a simple-signing payload's annotations do, and verifying it
+// keylessly would additionally require the Rekor-bundle plumbing noted on
+// verifyKeyless.
+func (v *Verifier) VerifyAttestations(repo, digest string, bundleBytes []byte, requiredPredicateTypes []string) (bool, error) {
+	if v.mode == ModeOff || len(requiredPredicateTypes) == 0 {
+		return true, nil
+	}
+
+	if v.publicKey == nil {
+		return false, fmt.Errorf("no cosign public key configured for attestation verification")
+	}
+
+	var bundle SignatureBundle
+	if err := json.Unmarshal(bundleBytes, &bundle); err != nil {
+		return false, fmt.Errorf("parsing attestation bundle: %w", err)
+	}
+
+	required := make(map[string]bool, len(requiredPredicateTypes))
+	for _, t := range requiredPredicateTypes {
+		required[t] = true
+	}
+
+	for _, layer := range bundle.Layers {
+		envelopeB64 := layer.Annotations[signatureAnnotation]
+		if envelopeB64 == "" {
+			continue
+		}
+
+		envelopeBytes, err := base64.StdEncoding.DecodeString(envelopeB64)
+		if err != nil {
+			continue
+		}
+
+		var envelope dsseEnvelope
+		if err := json.Unmarshal(envelopeBytes, &envelope); err != nil {
+			continue
+		}
+
+		statementBytes, err := base64.StdEncoding.DecodeString(envelope.Payload)
+		if err != nil {
+			continue
+		}
+
+		var statement inTotoStatement
+		if err := json.Unmarshal(statementBytes, &statement); err != nil || !required[statement.PredicateType] {
+			continue
+		}
+
+		if v.verifyDSSESignature(envelope, statementBytes) {
+			return true, nil
+		}
+	}
+
+	return false, fmt.Errorf("no attestation matching the configured predicate types verified")
+}
+
+// verifyDSSESignature checks envelope's signatures against the PAE
+// (pre-authentication encoding) of its own payload type and payload, the
+// construction DSSE signs rather than the raw payload bytes.
+func (v *Verifier) verifyDSSESignature(envelope dsseEnvelope, payload []byte) bool {
+	pae := dssePAE(envelope.PayloadType, payload)
+	hash := sha256.Sum256(pae)
+
+	for _, s := range envelope.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err != nil {
+			continue
+		}
+		if ecdsa.VerifyASN1(v.publicKey, hash[:], sig) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dssePAE computes the DSSE pre-authentication encoding for payloadType and
+// payload, per the DSSE spec: "DSSEv1" SP LEN(payloadType) SP payloadType SP
+// LEN(payload) SP payload.
+func dssePAE(payloadType string, payload []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("DSSEv1 ")
+	buf.WriteString(strconv.Itoa(len(payloadType)))
+	buf.WriteString(" ")
+	buf.WriteString(payloadType)
+	buf.WriteString(" ")
+	buf.WriteString(strconv.Itoa(len(payload)))
+	buf.WriteString(" ")
+	buf.Write(payload)
+	return buf.Bytes()
+}
@@ -0,0 +1,327 @@
+package verify
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeyFile(t *testing.T, pub *ecdsa.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+
+	f, err := os.CreateTemp(t.TempDir(), "cosign-*.pub")
+	require.NoError(t, err)
+	defer f.Close()
+
+	require.NoError(t, pem.Encode(f, &pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	return f.Name()
+}
+
+func signBundle(t *testing.T, key *ecdsa.PrivateKey, repo, digest string, extraAnnotations map[string]string) []byte {
+	t.Helper()
+	payload := expectedPayload(repo, digest)
+	hash := sha256.Sum256(payload)
+
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+
+	annotations := map[string]string{signatureAnnotation: base64.StdEncoding.EncodeToString(sig)}
+	for k, v := range extraAnnotations {
+		annotations[k] = v
+	}
+
+	bundle := SignatureBundle{
+		Layers: []struct {
+			Annotations map[string]string `json:"annotations"`
+		}{{Annotations: annotations}},
+	}
+
+	raw, err := json.Marshal(bundle)
+	require.NoError(t, err)
+	return raw
+}
+
+func TestVerifier_ModeOffAlwaysVerified(t *testing.T) {
+	v, err := NewVerifier(ModeOff, "", nil, "")
+	require.NoError(t, err)
+
+	ok, err := v.VerifyManifest("myorg/app", "sha256:deadbeef", []byte("not even json"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestVerifier_StaticKeyVerifiesSignedManifest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyPath := writeKeyFile(t, &key.PublicKey)
+
+	v, err := NewVerifier(ModeEnforce, keyPath, nil, "")
+	require.NoError(t, err)
+
+	bundle := signBundle(t, key, "myorg/app", "sha256:abc123", nil)
+
+	ok, err := v.VerifyManifest("myorg/app", "sha256:abc123", bundle)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestVerifier_StaticKeyRejectsWrongKey(t *testing.T) {
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyPath := writeKeyFile(t, &otherKey.PublicKey)
+
+	v, err := NewVerifier(ModeEnforce, keyPath, nil, "")
+	require.NoError(t, err)
+
+	bundle := signBundle(t, signingKey, "myorg/app", "sha256:abc123", nil)
+
+	ok, err := v.VerifyManifest("myorg/app", "sha256:abc123", bundle)
+	require.Error(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifier_RejectsDigestMismatch(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyPath := writeKeyFile(t, &key.PublicKey)
+
+	v, err := NewVerifier(ModeEnforce, keyPath, nil, "")
+	require.NoError(t, err)
+
+	bundle := signBundle(t, key, "myorg/app", "sha256:abc123", nil)
+
+	// The signature was produced for a different digest.
+	ok, err := v.VerifyManifest("myorg/app", "sha256:different", bundle)
+	require.Error(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifier_CachesResultPerDigest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyPath := writeKeyFile(t, &key.PublicKey)
+
+	v, err := NewVerifier(ModeEnforce, keyPath, nil, "")
+	require.NoError(t, err)
+
+	bundle := signBundle(t, key, "myorg/app", "sha256:abc123", nil)
+	ok, err := v.VerifyManifest("myorg/app", "sha256:abc123", bundle)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// A malformed bundle on the same digest should hit the cache rather
+	// than re-verify and fail.
+	ok, err = v.VerifyManifest("myorg/app", "sha256:abc123", []byte("garbage"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func selfSignedCert(t *testing.T, key *ecdsa.PrivateKey, email string, issuer string) string {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber:   big.NewInt(1),
+		Subject:        pkix.Name{Organization: []string{issuer}},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		EmailAddresses: []string{email},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	var buf []byte
+	buf = append(buf, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	return string(buf)
+}
+
+// Keyless identities can only be configured with ModeWarn/ModeOff (see
+// NewVerifier), since this tree doesn't yet chain the signing certificate to
+// a trusted Fulcio root or check its Rekor inclusion proof. These tests use
+// ModeWarn to exercise the SAN/issuer matching logic without claiming that
+// match is an enforceable trust boundary.
+func TestVerifier_KeylessVerifiesMatchingIdentity(t *testing.T) {
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	certPEM := selfSignedCert(t, certKey, "ci@example.com", "https://issuer.example.com")
+
+	v, err := NewVerifier(ModeWarn, "", []string{`^ci@example\.com$`}, "https://issuer.example.com")
+	require.NoError(t, err)
+
+	bundle := signBundle(t, certKey, "myorg/app", "sha256:abc123", map[string]string{
+		"dev.sigstore.cosign/certificate": certPEM,
+	})
+
+	ok, err := v.VerifyManifest("myorg/app", "sha256:abc123", bundle)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestVerifier_KeylessRejectsNonMatchingIdentity(t *testing.T) {
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	certPEM := selfSignedCert(t, certKey, "attacker@evil.example", "untrusted-issuer")
+
+	v, err := NewVerifier(ModeWarn, "", []string{`^ci@example\.com$`}, "https://issuer.example.com")
+	require.NoError(t, err)
+
+	bundle := signBundle(t, certKey, "myorg/app", "sha256:abc123", map[string]string{
+		"dev.sigstore.cosign/certificate": certPEM,
+	})
+
+	ok, err := v.VerifyManifest("myorg/app", "sha256:abc123", bundle)
+	require.Error(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifier_KeylessRejectsMismatchedIssuerEvenWithMatchingIdentity(t *testing.T) {
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	certPEM := selfSignedCert(t, certKey, "ci@example.com", "https://attacker-controlled-issuer.example")
+
+	v, err := NewVerifier(ModeWarn, "", []string{`^ci@example\.com$`}, "https://issuer.example.com")
+	require.NoError(t, err)
+
+	bundle := signBundle(t, certKey, "myorg/app", "sha256:abc123", map[string]string{
+		"dev.sigstore.cosign/certificate": certPEM,
+	})
+
+	ok, err := v.VerifyManifest("myorg/app", "sha256:abc123", bundle)
+	require.Error(t, err)
+	assert.False(t, ok)
+}
+
+func TestNewVerifier_RejectsKeylessIdentitiesInEnforceMode(t *testing.T) {
+	_, err := NewVerifier(ModeEnforce, "", []string{`^ci@example\.com$`}, "")
+	require.Error(t, err)
+}
+
+func TestVerifier_Allow(t *testing.T) {
+	enforce, err := NewVerifier(ModeEnforce, "", nil, "")
+	require.NoError(t, err)
+	assert.True(t, enforce.Allow(true))
+	assert.False(t, enforce.Allow(false))
+
+	warn, err := NewVerifier(ModeWarn, "", nil, "")
+	require.NoError(t, err)
+	assert.True(t, warn.Allow(true))
+	assert.True(t, warn.Allow(false))
+}
+
+func TestNewVerifier_InvalidKeylessPattern(t *testing.T) {
+	_, err := NewVerifier(ModeEnforce, "", []string{"("}, "")
+	require.Error(t, err)
+}
+
+func TestSigTag(t *testing.T) {
+	assert.Equal(t, "sha256-abc123.sig", SigTag("sha256:abc123"))
+}
+
+func TestAttestationTag(t *testing.T) {
+	assert.Equal(t, "sha256-abc123.att", AttestationTag("sha256:abc123"))
+}
+
+func signAttestationBundle(t *testing.T, key *ecdsa.PrivateKey, predicateType string) []byte {
+	t.Helper()
+
+	statement, err := json.Marshal(map[string]string{"predicateType": predicateType})
+	require.NoError(t, err)
+
+	payloadType := "application/vnd.in-toto+json"
+	pae := dssePAE(payloadType, statement)
+	hash := sha256.Sum256(pae)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+
+	envelope, err := json.Marshal(dsseEnvelope{
+		PayloadType: payloadType,
+		Payload:     base64.StdEncoding.EncodeToString(statement),
+		Signatures: []struct {
+			Sig string `json:"sig"`
+		}{{Sig: base64.StdEncoding.EncodeToString(sig)}},
+	})
+	require.NoError(t, err)
+
+	annotations := map[string]string{signatureAnnotation: base64.StdEncoding.EncodeToString(envelope)}
+	bundle := SignatureBundle{
+		Layers: []struct {
+			Annotations map[string]string `json:"annotations"`
+		}{{Annotations: annotations}},
+	}
+
+	raw, err := json.Marshal(bundle)
+	require.NoError(t, err)
+	return raw
+}
+
+func TestVerifier_VerifyAttestations_EmptyPolicyAlwaysPasses(t *testing.T) {
+	v, err := NewVerifier(ModeEnforce, "", nil, "")
+	require.NoError(t, err)
+
+	ok, err := v.VerifyAttestations("myorg/app", "sha256:abc123", []byte("not even json"), nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestVerifier_VerifyAttestations_MatchingPredicateTypeVerifies(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyPath := writeKeyFile(t, &key.PublicKey)
+
+	v, err := NewVerifier(ModeEnforce, keyPath, nil, "")
+	require.NoError(t, err)
+
+	bundle := signAttestationBundle(t, key, "https://slsa.dev/provenance/v1")
+
+	ok, err := v.VerifyAttestations("myorg/app", "sha256:abc123", bundle, []string{"https://slsa.dev/provenance/v1"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestVerifier_VerifyAttestations_RejectsUnlistedPredicateType(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyPath := writeKeyFile(t, &key.PublicKey)
+
+	v, err := NewVerifier(ModeEnforce, keyPath, nil, "")
+	require.NoError(t, err)
+
+	bundle := signAttestationBundle(t, key, "https://example.com/some-other-predicate")
+
+	ok, err := v.VerifyAttestations("myorg/app", "sha256:abc123", bundle, []string{"https://slsa.dev/provenance/v1"})
+	require.Error(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifier_VerifyAttestations_RejectsWrongKey(t *testing.T) {
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyPath := writeKeyFile(t, &otherKey.PublicKey)
+
+	v, err := NewVerifier(ModeEnforce, keyPath, nil, "")
+	require.NoError(t, err)
+
+	bundle := signAttestationBundle(t, signingKey, "https://slsa.dev/provenance/v1")
+
+	ok, err := v.VerifyAttestations("myorg/app", "sha256:abc123", bundle, []string{"https://slsa.dev/provenance/v1"})
+	require.Error(t, err)
+	assert.False(t, ok)
+}
@@ -4,15 +4,40 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"time"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	runtimejson "k8s.io/apimachinery/pkg/runtime/serializer/json"
 	"k8s.io/client-go/kubernetes"
 )
 
 const maxRequestSize = 1024 * 1024 // 1MB
 
+var (
+	// scheme and codecs know about both admission.k8s.io/v1 and its
+	// deprecated v1beta1 predecessor, so ServeHTTP can decode (and answer)
+	// whichever GroupVersion the API server is configured to send, rather
+	// than hard-coding v1.
+	scheme = runtime.NewScheme()
+	codecs = serializer.NewCodecFactory(scheme)
+
+	jsonDeserializer = codecs.UniversalDeserializer()
+	yamlSerializer   = runtimejson.NewYAMLSerializer(runtimejson.DefaultMetaFactory, scheme, scheme)
+
+	typeConverter = TypeConverter{}
+)
+
+func init() {
+	admissionv1.AddToScheme(scheme)
+	admissionv1beta1.AddToScheme(scheme)
+}
+
 // AdmissionHandler handles Kubernetes admission webhook requests
 type AdmissionHandler struct {
 	mutator *Mutator
@@ -25,6 +50,17 @@ func NewAdmissionHandler(kubeClient kubernetes.Interface) *AdmissionHandler {
 	}
 }
 
+// decoderForContentType picks the runtime.Decoder to use for a request body,
+// based on its Content-Type: "application/yaml" decodes YAML, everything
+// else (including no header at all) decodes JSON.
+func decoderForContentType(contentType string) runtime.Decoder {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && mediaType == "application/yaml" {
+		return yamlSerializer
+	}
+	return jsonDeserializer
+}
+
 func (h *AdmissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Limit request size
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
@@ -39,49 +75,84 @@ func (h *AdmissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var admissionReview admissionv1.AdmissionReview
-	if err := json.Unmarshal(body, &admissionReview); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to unmarshal admission review: %v", err), http.StatusBadRequest)
+	decoder := decoderForContentType(r.Header.Get("Content-Type"))
+	obj, gvk, err := decoder.Decode(body, nil, nil)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to decode admission review: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	if &admissionReview == nil {
+	switch gvk.GroupVersion() {
+	case admissionv1.SchemeGroupVersion:
+		h.serveV1(w, obj)
+	case admissionv1beta1.SchemeGroupVersion:
+		h.serveV1beta1(w, obj)
+	default:
+		http.Error(w, fmt.Sprintf("unsupported group version: %s", gvk.GroupVersion()), http.StatusBadRequest)
+	}
+}
+
+// serveV1 handles a decoded admission.k8s.io/v1 AdmissionReview end to end.
+func (h *AdmissionHandler) serveV1(w http.ResponseWriter, obj runtime.Object) {
+	review, ok := obj.(*admissionv1.AdmissionReview)
+	if !ok || review == nil {
 		http.Error(w, "admission review is nil", http.StatusBadRequest)
 		return
 	}
-
-	if admissionReview.Request == nil {
+	if review.Request == nil {
 		http.Error(w, "admission request is nil", http.StatusBadRequest)
 		return
 	}
-
-	if admissionReview.Request.UID == "" {
+	if review.Request.UID == "" {
 		http.Error(w, "admission request UID is empty", http.StatusBadRequest)
 		return
 	}
 
-	if admissionReview.APIVersion == "" {
-		http.Error(w, "API version is required", http.StatusBadRequest)
+	h.mutator.Metrics().RecordAdmissionRequest("v1")
+
+	h.writeResponse(w, &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionv1.SchemeGroupVersion.String(),
+			Kind:       "AdmissionReview",
+		},
+		Response: h.processAdmissionRequest(review.Request),
+	})
+}
+
+// serveV1beta1 handles a decoded admission.k8s.io/v1beta1 AdmissionReview by
+// converting it to v1, running it through the same pipeline as serveV1, and
+// converting the response back, so callers on either version share one
+// admission code path.
+func (h *AdmissionHandler) serveV1beta1(w http.ResponseWriter, obj runtime.Object) {
+	review, ok := obj.(*admissionv1beta1.AdmissionReview)
+	if !ok || review == nil {
+		http.Error(w, "admission review is nil", http.StatusBadRequest)
 		return
 	}
-
-	if admissionReview.APIVersion != "admission.k8s.io/v1" {
-		http.Error(w, fmt.Sprintf("unsupported API version: %s", admissionReview.APIVersion), http.StatusBadRequest)
+	if review.Request == nil {
+		http.Error(w, "admission request is nil", http.StatusBadRequest)
 		return
 	}
+	if review.Request.UID == "" {
+		http.Error(w, "admission request UID is empty", http.StatusBadRequest)
+		return
+	}
+
+	h.mutator.Metrics().RecordAdmissionRequest("v1beta1")
 
-	// Process the admission request
-	response := h.processAdmissionRequest(admissionReview.Request)
+	v1Response := h.processAdmissionRequest(typeConverter.RequestToV1(review.Request))
 
-	admissionResponse := &admissionv1.AdmissionReview{
+	h.writeResponse(w, &admissionv1beta1.AdmissionReview{
 		TypeMeta: metav1.TypeMeta{
-			APIVersion: "admission.k8s.io/v1",
+			APIVersion: admissionv1beta1.SchemeGroupVersion.String(),
 			Kind:       "AdmissionReview",
 		},
-		Response: response,
-	}
+		Response: typeConverter.ResponseFromV1(v1Response),
+	})
+}
 
-	responseBytes, err := json.Marshal(admissionResponse)
+func (h *AdmissionHandler) writeResponse(w http.ResponseWriter, review runtime.Object) {
+	responseBytes, err := json.Marshal(review)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to marshal admission response: %v", err), http.StatusInternalServerError)
 		return
@@ -93,6 +164,11 @@ func (h *AdmissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AdmissionHandler) processAdmissionRequest(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	start := time.Now()
+	defer func() {
+		h.mutator.Metrics().RecordAdmissionDuration(time.Since(start))
+	}()
+
 	// Always fail open - allow requests even if processing fails
 	response := &admissionv1.AdmissionResponse{
 		UID:     req.UID,
@@ -100,11 +176,12 @@ func (h *AdmissionHandler) processAdmissionRequest(req *admissionv1.AdmissionReq
 	}
 
 	// Try to process the request, but don't fail if it errors
-	patches, err := h.mutator.Mutate(req)
+	patches, warnings, err := h.mutator.Mutate(req)
 	if err != nil {
 		// Log error but allow request to proceed
 		return response
 	}
+	response.Warnings = warnings
 
 	if len(patches) > 0 {
 		patchBytes, err := json.Marshal(patches)
@@ -119,4 +196,4 @@ func (h *AdmissionHandler) processAdmissionRequest(req *admissionv1.AdmissionReq
 	}
 
 	return response
-}
\ No newline at end of file
+}
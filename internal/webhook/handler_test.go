@@ -12,15 +12,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func TestHandler_MalformedAdmissionReview(t *testing.T) {
-	handler := NewAdmissionHandler()
+	handler := NewAdmissionHandler(nil)
 
 	tests := []struct {
 		name    string
@@ -30,17 +32,17 @@ func TestHandler_MalformedAdmissionReview(t *testing.T) {
 		{
 			name:    "invalid JSON",
 			body:    `{invalid json}`,
-			wantErr: "invalid character",
+			wantErr: "Failed to decode admission review",
 		},
 		{
 			name:    "empty body",
 			body:    "",
-			wantErr: "unexpected end of JSON input",
+			wantErr: "Failed to decode admission review",
 		},
 		{
 			name:    "null body",
 			body:    "null",
-			wantErr: "admission review is nil",
+			wantErr: "Failed to decode admission review",
 		},
 	}
 
@@ -59,7 +61,7 @@ func TestHandler_MalformedAdmissionReview(t *testing.T) {
 }
 
 func TestHandler_MissingRequiredFields(t *testing.T) {
-	handler := NewAdmissionHandler()
+	handler := NewAdmissionHandler(nil)
 
 	tests := []struct {
 		name    string
@@ -107,7 +109,7 @@ func TestHandler_MissingRequiredFields(t *testing.T) {
 }
 
 func TestHandler_InvalidKubernetesAPIVersions(t *testing.T) {
-	handler := NewAdmissionHandler()
+	handler := NewAdmissionHandler(nil)
 
 	tests := []struct {
 		name       string
@@ -117,12 +119,12 @@ func TestHandler_InvalidKubernetesAPIVersions(t *testing.T) {
 		{
 			name:       "unsupported API version",
 			apiVersion: "admission.k8s.io/v2",
-			wantErr:    "unsupported API version",
+			wantErr:    "Failed to decode admission review",
 		},
 		{
 			name:       "empty API version",
 			apiVersion: "",
-			wantErr:    "API version is required",
+			wantErr:    "Failed to decode admission review",
 		},
 	}
 
@@ -154,7 +156,7 @@ func TestHandler_InvalidKubernetesAPIVersions(t *testing.T) {
 }
 
 func TestHandler_OversizedPayload(t *testing.T) {
-	handler := NewAdmissionHandler()
+	handler := NewAdmissionHandler(nil)
 
 	// Create a large payload (> 1MB)
 	largeData := make([]byte, 2*1024*1024) // 2MB
@@ -172,7 +174,7 @@ func TestHandler_OversizedPayload(t *testing.T) {
 }
 
 func TestHandler_ConcurrentRequests(t *testing.T) {
-	handler := NewAdmissionHandler()
+	handler := NewAdmissionHandler(nil)
 
 	review := &admissionv1.AdmissionReview{
 		TypeMeta: metav1.TypeMeta{
@@ -234,7 +236,7 @@ func TestHandler_ConcurrentRequests(t *testing.T) {
 }
 
 func TestHandler_RequestTimeout(t *testing.T) {
-	handler := NewAdmissionHandler()
+	handler := NewAdmissionHandler(nil)
 
 	review := &admissionv1.AdmissionReview{
 		TypeMeta: metav1.TypeMeta{
@@ -268,7 +270,7 @@ func TestHandler_RequestTimeout(t *testing.T) {
 }
 
 func TestHandler_AdmissionResponseSerialization(t *testing.T) {
-	handler := NewAdmissionHandler()
+	handler := NewAdmissionHandler(nil)
 
 	review := &admissionv1.AdmissionReview{
 		TypeMeta: metav1.TypeMeta{
@@ -305,7 +307,7 @@ func TestHandler_AdmissionResponseSerialization(t *testing.T) {
 }
 
 func TestHandler_WebhookFailurePolicy(t *testing.T) {
-	handler := NewAdmissionHandler()
+	handler := NewAdmissionHandler(nil)
 
 	// Test that webhook fails open (allows requests even on internal errors)
 	review := &admissionv1.AdmissionReview{
@@ -338,4 +340,154 @@ func TestHandler_WebhookFailurePolicy(t *testing.T) {
 
 	// Should fail open (allow the request)
 	assert.True(t, response.Response.Allowed)
-}
\ No newline at end of file
+}
+
+func TestHandler_V1beta1AdmissionReview(t *testing.T) {
+	handler := NewAdmissionHandler(nil)
+
+	review := &admissionv1beta1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admission.k8s.io/v1beta1",
+			Kind:       "AdmissionReview",
+		},
+		Request: &admissionv1beta1.AdmissionRequest{
+			UID: "test-uid",
+			Object: runtime.RawExtension{
+				Raw: []byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"test"},"spec":{"containers":[{"name":"test","image":"nginx"}]}}`),
+			},
+		},
+	}
+
+	body, err := json.Marshal(review)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/mutate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response admissionv1beta1.AdmissionReview
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+
+	assert.Equal(t, "admission.k8s.io/v1beta1", response.APIVersion)
+	require.NotNil(t, response.Response)
+	assert.Equal(t, "test-uid", string(response.Response.UID))
+	assert.True(t, response.Response.Allowed)
+}
+
+func TestHandler_YAMLContentType(t *testing.T) {
+	handler := NewAdmissionHandler(nil)
+
+	body := []byte(`
+apiVersion: admission.k8s.io/v1
+kind: AdmissionReview
+request:
+  uid: test-uid
+  object:
+    apiVersion: v1
+    kind: Pod
+    metadata:
+      name: test
+    spec:
+      containers:
+        - name: test
+          image: nginx
+`)
+
+	req := httptest.NewRequest("POST", "/mutate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/yaml")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response admissionv1.AdmissionReview
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+
+	require.NotNil(t, response.Response)
+	assert.Equal(t, "test-uid", string(response.Response.UID))
+	assert.True(t, response.Response.Allowed)
+}
+
+func TestHandler_RecordsAdmissionRequestsByVersion(t *testing.T) {
+	handler := NewAdmissionHandler(nil)
+
+	v1Review := &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  &admissionv1.AdmissionRequest{UID: "v1-uid"},
+	}
+	v1Body, err := json.Marshal(v1Review)
+	require.NoError(t, err)
+
+	v1beta1Review := &admissionv1beta1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1beta1", Kind: "AdmissionReview"},
+		Request:  &admissionv1beta1.AdmissionRequest{UID: "v1beta1-uid"},
+	}
+	v1beta1Body, err := json.Marshal(v1beta1Review)
+	require.NoError(t, err)
+
+	for _, body := range [][]byte{v1Body, v1beta1Body} {
+		req := httptest.NewRequest("POST", "/mutate", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	metricsReq := httptest.NewRequest("GET", "/metrics", nil)
+	metricsW := httptest.NewRecorder()
+	promhttp.HandlerFor(handler.mutator.Metrics().Registry(), promhttp.HandlerOpts{}).ServeHTTP(metricsW, metricsReq)
+
+	metricsBody := metricsW.Body.String()
+	assert.Contains(t, metricsBody, `archy_admission_requests_total{version="v1"} 1`)
+	assert.Contains(t, metricsBody, `archy_admission_requests_total{version="v1beta1"} 1`)
+}
+
+func TestTypeConverter_RequestResponseRoundTrip(t *testing.T) {
+	dryRun := true
+	v1beta1Request := &admissionv1beta1.AdmissionRequest{
+		UID:       "round-trip-uid",
+		Name:      "test-pod",
+		Namespace: "default",
+		Operation: admissionv1beta1.Create,
+		DryRun:    &dryRun,
+		Object: runtime.RawExtension{
+			Raw: []byte(`{"kind":"Pod"}`),
+		},
+	}
+
+	var converter TypeConverter
+	v1Request := converter.RequestToV1(v1beta1Request)
+	require.NotNil(t, v1Request)
+	assert.Equal(t, v1beta1Request.UID, v1Request.UID)
+	assert.Equal(t, v1beta1Request.Name, v1Request.Name)
+	assert.Equal(t, v1beta1Request.Namespace, v1Request.Namespace)
+	assert.Equal(t, admissionv1.Create, v1Request.Operation)
+	assert.Equal(t, v1beta1Request.Object.Raw, v1Request.Object.Raw)
+	require.NotNil(t, v1Request.DryRun)
+	assert.True(t, *v1Request.DryRun)
+
+	patchType := admissionv1.PatchTypeJSONPatch
+	v1Response := &admissionv1.AdmissionResponse{
+		UID:       "round-trip-uid",
+		Allowed:   true,
+		Patch:     []byte(`[]`),
+		PatchType: &patchType,
+		Warnings:  []string{"warned"},
+	}
+
+	v1beta1Response := converter.ResponseFromV1(v1Response)
+	require.NotNil(t, v1beta1Response)
+	assert.Equal(t, v1Response.UID, v1beta1Response.UID)
+	assert.Equal(t, v1Response.Allowed, v1beta1Response.Allowed)
+	assert.Equal(t, v1Response.Patch, v1beta1Response.Patch)
+	require.NotNil(t, v1beta1Response.PatchType)
+	assert.Equal(t, admissionv1beta1.PatchTypeJSONPatch, *v1beta1Response.PatchType)
+	assert.Equal(t, v1Response.Warnings, v1beta1Response.Warnings)
+}
@@ -3,19 +3,32 @@ package webhook
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"github.com/lsdopen/archy/internal/cache"
 	"github.com/lsdopen/archy/internal/credentials"
 	"github.com/lsdopen/archy/internal/metrics"
+	"github.com/lsdopen/archy/internal/mirror"
+	"github.com/lsdopen/archy/internal/ratelimit"
 	"github.com/lsdopen/archy/internal/registry"
+	"github.com/lsdopen/archy/internal/verify"
+	"github.com/lsdopen/archy/pkg/inspector"
 	"github.com/lsdopen/archy/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
+// rateLimitMaxWait bounds how long detectArchitectures will wait for a
+// configured limiter to free up a token before treating the lookup as
+// denied, so a sustained burst degrades admission latency by at most this
+// much instead of blocking indefinitely.
+const rateLimitMaxWait = 2 * time.Second
+
 // JSONPatch represents a JSON Patch operation
 type JSONPatch struct {
 	Op    string      `json:"op"`
@@ -25,32 +38,150 @@ type JSONPatch struct {
 
 // Mutator handles pod mutations
 type Mutator struct {
+	kubeClient         kubernetes.Interface
 	defaultArch        string
+	affinityMode       string
 	registryClient     types.RegistryClient
-	cache              *cache.MemoryCache
+	cache              cache.Cache
 	metrics            *metrics.Metrics
 	credentialResolver *credentials.Resolver
+	verifier           *verify.Verifier
+	mirror             *mirror.Client
+	localInspector     types.RegistryClient
+	resilient          *registry.ResilientClient
+
+	// limiter, when set, gates a cache-miss registry lookup in
+	// detectArchitectures behind Allow(ctx, "namespace/repo"), so an
+	// admission burst can't hammer the registry or the credential
+	// resolver's Kubernetes API calls. A denied lookup admits the pod with
+	// defaultArch and a warning instead of blocking the response.
+	limiter ratelimit.Limiter
+
+	// requiredAttestationTypes, when non-empty, are the in-toto predicate
+	// types (e.g. "https://slsa.dev/provenance/v1") verifyArchitectures
+	// requires a signed attestation for, alongside the Cosign signature
+	// check verifier already performs.
+	requiredAttestationTypes []string
+}
+
+// Cache returns the shared manifest/architecture cache so other admission
+// paths (e.g. Validator) can reuse it instead of maintaining their own.
+func (m *Mutator) Cache() cache.Cache {
+	return m.cache
+}
+
+// RegistryClient returns the default registry client used when no
+// registry-specific client can be constructed for an image.
+func (m *Mutator) RegistryClient() types.RegistryClient {
+	return m.registryClient
+}
+
+// CredentialResolver returns the shared credential resolver.
+func (m *Mutator) CredentialResolver() *credentials.Resolver {
+	return m.credentialResolver
+}
+
+// Metrics returns the shared Prometheus metrics instance so the server can
+// expose it on /metrics.
+func (m *Mutator) Metrics() *metrics.Metrics {
+	return m.metrics
 }
 
 // NewMutator creates a new mutator
 func NewMutator(kubeClient kubernetes.Interface) *Mutator {
 	// Create Docker Hub client as default
 	client := registry.NewDockerHubClient()
-	cache := cache.NewMemoryCache(1000, 5*time.Minute)
 	metrics := metrics.NewMetrics()
+	cache := cache.NewMemoryCacheWithMetrics(1000, 5*time.Minute, metrics)
 	credResolver := credentials.NewResolver(kubeClient)
-	
+
 	return &Mutator{
+		kubeClient:         kubeClient,
 		defaultArch:        "amd64",
+		affinityMode:       "nodeAffinity",
 		registryClient:     client,
 		cache:              cache,
 		metrics:            metrics,
 		credentialResolver: credResolver,
+		resilient:          registry.NewResilientClient(metrics),
 	}
 }
 
-// Mutate processes an admission request and returns JSON patches
-func (m *Mutator) Mutate(req *admissionv1.AdmissionRequest) ([]JSONPatch, error) {
+// NewMutatorWithAffinityMode creates a mutator that patches pods using the
+// given affinity mode ("nodeSelector" for the legacy single-arch selector,
+// "nodeAffinity" for the multi-arch required node affinity) instead of the
+// default.
+func NewMutatorWithAffinityMode(kubeClient kubernetes.Interface, affinityMode string) *Mutator {
+	m := NewMutator(kubeClient)
+	m.affinityMode = affinityMode
+	return m
+}
+
+// NewMutatorWithVerifier creates a mutator that checks Cosign signatures via
+// verifier before trusting a manifest's declared architectures.
+func NewMutatorWithVerifier(kubeClient kubernetes.Interface, verifier *verify.Verifier) *Mutator {
+	m := NewMutator(kubeClient)
+	m.verifier = verifier
+	return m
+}
+
+// NewMutatorWithAttestationPolicy creates a mutator that, alongside
+// verifier's Cosign signature check, also requires a signed in-toto
+// attestation whose predicate type is in requiredPredicateTypes (e.g.
+// SLSA provenance) before trusting an image's declared architectures.
+func NewMutatorWithAttestationPolicy(kubeClient kubernetes.Interface, verifier *verify.Verifier, requiredPredicateTypes []string) *Mutator {
+	m := NewMutatorWithVerifier(kubeClient, verifier)
+	m.requiredAttestationTypes = requiredPredicateTypes
+	return m
+}
+
+// NewMutatorWithMirror creates a mutator that routes manifest lookups through
+// mirrorClient's local store first, shielding the upstream registry from an
+// admission storm across many pods referencing the same image.
+func NewMutatorWithMirror(kubeClient kubernetes.Interface, mirrorClient *mirror.Client) *Mutator {
+	m := NewMutator(kubeClient)
+	m.mirror = mirrorClient
+	return m
+}
+
+// NewMutatorWithLocalInspector creates a mutator that checks the local
+// runtime (a containerd/CRI or Docker daemon image inspection, see
+// pkg/inspector) before falling back to a remote registry pull, so images
+// the kubelet has already pulled are served without a network round-trip
+// or a pull secret.
+func NewMutatorWithLocalInspector(kubeClient kubernetes.Interface, local types.RegistryClient) *Mutator {
+	m := NewMutator(kubeClient)
+	m.localInspector = local
+	return m
+}
+
+// NewMutatorWithCache creates a mutator that resolves/caches architectures
+// through sharedCache instead of its own private MemoryCache, e.g. a
+// cache.RedisCache (or cache.TwoTierCache wrapping one) so every replica of
+// an HA deployment shares the same cached results instead of each cold-
+// starting its own on restart.
+func NewMutatorWithCache(kubeClient kubernetes.Interface, sharedCache cache.Cache) *Mutator {
+	m := NewMutator(kubeClient)
+	m.cache = sharedCache
+	return m
+}
+
+// NewMutatorWithRateLimiter creates a mutator that gates cache-miss registry
+// lookups behind limiter, keyed by "namespace/repo" (see rateLimitKey), so a
+// burst of pods (e.g. a Deployment scaling from 0 to 500) can't hammer the
+// registry or the credential resolver's Kubernetes API. A denied lookup
+// admits the pod with defaultArch and a warning rather than blocking the
+// admission response.
+func NewMutatorWithRateLimiter(kubeClient kubernetes.Interface, limiter ratelimit.Limiter) *Mutator {
+	m := NewMutator(kubeClient)
+	m.limiter = limiter
+	return m
+}
+
+// Mutate processes an admission request, returning JSON patches alongside
+// any warnings to surface in the admission response (e.g. a rate-limited
+// registry lookup that fell back to defaultArch).
+func (m *Mutator) Mutate(req *admissionv1.AdmissionRequest) ([]JSONPatch, []string, error) {
 	start := time.Now()
 	var success bool
 	var selectedArch string
@@ -63,34 +194,73 @@ func (m *Mutator) Mutate(req *admissionv1.AdmissionRequest) ([]JSONPatch, error)
 	var pod corev1.Pod
 	if err := json.Unmarshal(req.Object.Raw, &pod); err != nil {
 		// Return empty patches on unmarshal error (fail open)
-		return []JSONPatch{}, nil
+		return []JSONPatch{}, nil, nil
 	}
 
-	// Check if pod already has architecture selector
-	if pod.Spec.NodeSelector != nil {
-		if _, exists := pod.Spec.NodeSelector["kubernetes.io/arch"]; exists {
-			return []JSONPatch{}, nil // No mutation needed
-		}
+	// Check if pod already has an architecture constraint
+	if hasArchConstraint(&pod) {
+		return []JSONPatch{}, nil, nil // No mutation needed
 	}
 
 	// Get all container images
 	images := m.extractImages(&pod)
 	if len(images) == 0 {
-		return []JSONPatch{}, nil // No containers to process
+		return []JSONPatch{}, nil, nil // No containers to process
 	}
 
-	// Detect architecture from first image
-	arch := m.detectArchitecture(&pod, images[0])
-	selectedArch = arch
+	// Detect supported architectures from first image
+	archs, warnings := m.detectArchitectures(&pod, images[0])
+	selectedArch = archs[0]
 
-	// Create patches to add node selector
-	patches := m.createNodeSelectorPatches(&pod, arch)
+	// Create patches constraining scheduling to a supported architecture
+	var patches []JSONPatch
+	if m.affinityMode == "nodeSelector" {
+		patches = m.createNodeSelectorPatches(&pod, archs[0])
+	} else {
+		patches = m.createNodeAffinityPatches(&pod, archs)
+	}
 	success = len(patches) > 0
 
-	return patches, nil
+	return patches, warnings, nil
+}
+
+// hasArchConstraint reports whether a pod already constrains scheduling to a
+// specific architecture, either via nodeSelector or node affinity.
+func hasArchConstraint(pod *corev1.Pod) bool {
+	if pod.Spec.NodeSelector != nil {
+		if _, exists := pod.Spec.NodeSelector["kubernetes.io/arch"]; exists {
+			return true
+		}
+	}
+
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil {
+		return false
+	}
+
+	required := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil {
+		return false
+	}
+
+	for _, term := range required.NodeSelectorTerms {
+		for _, expr := range term.MatchExpressions {
+			if expr.Key == "kubernetes.io/arch" {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
 func (m *Mutator) extractImages(pod *corev1.Pod) []string {
+	return extractPodImages(pod)
+}
+
+// extractPodImages collects every container and init-container image
+// reference from a pod spec. Shared with Validator so both admission paths
+// agree on which images participate in architecture detection.
+func extractPodImages(pod *corev1.Pod) []string {
 	var images []string
 
 	// Extract from regular containers
@@ -135,45 +305,306 @@ func (m *Mutator) createNodeSelectorPatches(pod *corev1.Pod, arch string) []JSON
 	return patches
 }
 
-// detectArchitecture detects the architecture for an image using cache and registry
-func (m *Mutator) detectArchitecture(pod *corev1.Pod, image string) string {
+// createNodeAffinityPatches constrains scheduling to nodes advertising one of
+// archs via a required kubernetes.io/arch node affinity term, preserving any
+// affinity the pod already declares instead of overwriting it.
+//
+// Note: types.RegistryClient only reports architecture names today, not
+// the os/variant fields an OCI image index can carry, so matching is limited
+// to kubernetes.io/arch until the registry client exposes that data.
+func (m *Mutator) createNodeAffinityPatches(pod *corev1.Pod, archs []string) []JSONPatch {
+	archExpr := corev1.NodeSelectorRequirement{
+		Key:      "kubernetes.io/arch",
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   archs,
+	}
+
+	if pod.Spec.Affinity == nil {
+		return []JSONPatch{{
+			Op:   "add",
+			Path: "/spec/affinity",
+			Value: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{
+							{MatchExpressions: []corev1.NodeSelectorRequirement{archExpr}},
+						},
+					},
+				},
+			},
+		}}
+	}
+
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		return []JSONPatch{{
+			Op:   "add",
+			Path: "/spec/affinity/nodeAffinity",
+			Value: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{MatchExpressions: []corev1.NodeSelectorRequirement{archExpr}},
+					},
+				},
+			},
+		}}
+	}
+
+	required := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil || len(required.NodeSelectorTerms) == 0 {
+		return []JSONPatch{{
+			Op:   "add",
+			Path: "/spec/affinity/nodeAffinity/requiredDuringSchedulingIgnoredDuringExecution",
+			Value: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{archExpr}},
+				},
+			},
+		}}
+	}
+
+	// The pod already declares required node affinity terms (NodeSelectorTerms
+	// are OR'd together), so AND our architecture requirement into each one
+	// instead of appending a new term, which would relax rather than narrow
+	// the pod's existing placement rules.
+	var patches []JSONPatch
+	for i := range required.NodeSelectorTerms {
+		patches = append(patches, JSONPatch{
+			Op:    "add",
+			Path:  fmt.Sprintf("/spec/affinity/nodeAffinity/requiredDuringSchedulingIgnoredDuringExecution/nodeSelectorTerms/%d/matchExpressions/-", i),
+			Value: archExpr,
+		})
+	}
+	return patches
+}
+
+// detectArchitectures returns every architecture an image supports, using the
+// cache and falling back to the registry, alongside any warning to surface
+// in the admission response. Always returns at least one architecture
+// (m.defaultArch) so callers can safely index archs[0].
+func (m *Mutator) detectArchitectures(pod *corev1.Pod, image string) ([]string, []string) {
 	// Check cache first
 	if archs, found := m.cache.Get(image); found {
 		m.metrics.RecordCacheHit(image)
 		if len(archs) > 0 {
-			return archs[0] // Return first supported architecture
+			return archs, nil
 		}
 	}
 
 	m.metrics.RecordCacheMiss(image)
 
+	if m.limiter != nil {
+		key := rateLimitKey(pod.Namespace, image)
+
+		waitCtx, cancel := context.WithTimeout(context.Background(), rateLimitMaxWait)
+		start := time.Now()
+		allowed := m.limiter.Allow(waitCtx, key)
+		m.metrics.RecordRateLimitWait(time.Since(start))
+		cancel()
+
+		if !allowed {
+			m.metrics.RecordRateLimitDecision(key, "deny")
+			msg := fmt.Sprintf("registry lookup for image %q rate-limited; admitted with the default architecture (%s) assumed", image, m.defaultArch)
+			return []string{m.defaultArch}, []string{msg}
+		}
+		m.metrics.RecordRateLimitDecision(key, "allow")
+	}
+
 	// Resolve credentials for this image
 	cred, _ := m.credentialResolver.ResolveCredentials(pod, image)
 
-	// Create registry client with credentials if available
-	client := m.registryClient
-	if cred != nil {
-		// Use authenticated client (implementation would create client with credentials)
-		client = registry.NewDockerHubClientWithCredentials(cred.Username, cred.Password)
+	// Select a client for the image's registry host, wiring in any resolved
+	// credentials so basic-auth or bearer tokens flow to the Authorization
+	// header. Falls back to the default Docker Hub client on error.
+	registryHost := credentials.ExtractRegistry(image)
+	client, err := registry.NewClientWithCredentials(registryHost, cred)
+	if err != nil {
+		client = m.registryClient
+	}
+
+	if m.mirror != nil {
+		client = m.mirror.Wrap(client)
+	}
+
+	if m.localInspector != nil {
+		// Try the local runtime before the (possibly mirror-wrapped) remote
+		// client: an image the kubelet already pulled needs no network
+		// round-trip and no pull secret, stale or otherwise.
+		client = inspector.NewChainedClient(m.localInspector, client)
 	}
 
+	// verifyClient keeps whatever FetchSignature/FetchAttestations/
+	// FetchManifest capability the chain above exposes; resilientClient
+	// (wrapped below) only implements GetSupportedArchitectures, so
+	// verification must type-assert the pre-resilience client.
+	verifyClient := client
+
+	// Coalesce concurrent lookups for the same image, cache positive and
+	// negative results, and rate-limit per registry host, so an admission
+	// storm across many pods referencing the same image issues at most one
+	// upstream manifest fetch.
+	client = m.resilient.Wrap(client, registryHost)
+
 	// Query registry
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	lookupStart := time.Now()
 	archs, err := client.GetSupportedArchitectures(ctx, image)
+	m.metrics.RecordRegistryLookupDuration(registryHost, time.Since(lookupStart))
+
 	if err != nil {
+		m.metrics.RecordRegistryLookupError(registryHost, lookupErrorReason(err))
 		// Fail open with default architecture
-		return m.defaultArch
+		return []string{m.defaultArch}, nil
 	}
-
 	if len(archs) == 0 {
-		return m.defaultArch
+		m.metrics.RecordRegistryLookupError(registryHost, "empty_result")
+		// Fail open with default architecture
+		return []string{m.defaultArch}, nil
+	}
+
+	if !m.verifyArchitectures(ctx, verifyClient, image, pod.Namespace) {
+		// Signature/attestation verification failed and the effective fail
+		// policy for this namespace is closed; don't trust or cache the
+		// architectures this manifest claims.
+		return []string{m.defaultArch}, nil
 	}
 
 	// Cache the result
 	m.cache.Set(image, archs)
 
-	// Return first supported architecture
-	return archs[0]
-}
\ No newline at end of file
+	return archs, nil
+}
+
+// rateLimitKey derives the key m.limiter is consulted under for image in
+// namespace: namespace plus image stripped of its registry host and
+// tag/digest, so the same repository pulled via different tags, or through
+// a mirror that rewrites the host, still shares one bucket. Host detection
+// reuses credentials.ExtractRegistry, the same rule detectArchitectures uses
+// a few lines below to pick a registry client, so the two never disagree
+// about where a given image's host ends and its repository path begins.
+func rateLimitKey(namespace, image string) string {
+	repo := image
+	if i := strings.IndexByte(repo, '@'); i != -1 {
+		repo = repo[:i]
+	}
+	repo = metrics.StripImageTag(repo)
+
+	if host := credentials.ExtractRegistry(repo); host != "docker.io" {
+		repo = strings.TrimPrefix(repo, host+"/")
+	}
+
+	return namespace + "/" + repo
+}
+
+// lookupErrorReason classifies a GetSupportedArchitectures failure into the
+// low-cardinality reason label archy_registry_lookup_errors_total records,
+// using the sentinel errors registry.DockerHubClient returns. GenericClient
+// returns plain errors instead: its 429 error still matches the rate-limit
+// text check below, and its other failures are reported as "other".
+// ResilientClient's own
+// per-host rate limiter doesn't wrap registry.ErrRateLimited (it denies
+// before ever reaching the upstream client), so it's matched on its error
+// text instead, mirroring the substring check registry.classifyOutcome
+// applies to the same message for archy_registry_responses_total.
+func lookupErrorReason(err error) string {
+	switch {
+	case errors.Is(err, registry.ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, registry.ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, registry.ErrNotFound):
+		return "not_found"
+	case strings.Contains(err.Error(), "rate limit"):
+		return "rate_limited"
+	default:
+		return "other"
+	}
+}
+
+// verifyArchitectures checks image's Cosign signature, and (when
+// requiredAttestationTypes is set) its in-toto attestations, when a
+// verifier is configured, recording a metric on each failure. On failure it
+// honors namespace's fail-open/fail-closed override (see allowOnFailure)
+// instead of always following the verifier's global mode.
+func (m *Mutator) verifyArchitectures(ctx context.Context, client types.RegistryClient, image, namespace string) bool {
+	if m.verifier == nil || m.verifier.Mode() == verify.ModeOff {
+		return true
+	}
+
+	if fetcher, ok := client.(verify.SignatureFetcher); ok {
+		repo, digest, bundle, err := fetcher.FetchSignature(ctx, image)
+		verified := false
+		if err == nil {
+			verified, _ = m.verifier.VerifyManifest(repo, digest, bundle)
+		}
+		if !verified {
+			m.metrics.RecordVerificationFailure(image)
+			if !m.allowOnFailure(namespace) {
+				return false
+			}
+		}
+	}
+	// When the client doesn't implement SignatureFetcher, there's nothing to
+	// verify a signature against; proceed to the attestation check (if any)
+	// rather than blocking on it.
+
+	if len(m.requiredAttestationTypes) == 0 {
+		return true
+	}
+
+	fetcher, ok := client.(verify.AttestationFetcher)
+	if !ok {
+		return true
+	}
+
+	repo, digest, bundle, err := fetcher.FetchAttestations(ctx, image)
+	verified := false
+	if err == nil {
+		verified, _ = m.verifier.VerifyAttestations(repo, digest, bundle, m.requiredAttestationTypes)
+	}
+	if !verified {
+		m.metrics.RecordVerificationFailure(image)
+		if !m.allowOnFailure(namespace) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// failPolicyAnnotation, set on a Namespace, overrides the verifier's global
+// mode for that namespace's pods: "open" always admits on a verification
+// failure, "closed" always rejects. Any other value (including unset) falls
+// back to the verifier's own enforce/warn/off mode.
+const failPolicyAnnotation = "archy.io/fail-policy"
+
+// allowOnFailure reports whether a verification failure for a pod in
+// namespace should still admit it, honoring failPolicyAnnotation before
+// falling back to the verifier's global mode.
+func (m *Mutator) allowOnFailure(namespace string) bool {
+	switch m.namespaceFailPolicy(namespace) {
+	case "open":
+		return true
+	case "closed":
+		return false
+	default:
+		return m.verifier.Mode() != verify.ModeEnforce
+	}
+}
+
+// namespaceFailPolicy returns namespace's failPolicyAnnotation value, or ""
+// if unset or the namespace can't be read, in which case callers fall back
+// to the verifier's own mode.
+func (m *Mutator) namespaceFailPolicy(namespace string) string {
+	if m.kubeClient == nil {
+		return ""
+	}
+
+	ns, err := m.kubeClient.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err != nil {
+		return ""
+	}
+
+	return ns.Annotations[failPolicyAnnotation]
+}
@@ -1,8 +1,17 @@
 package webhook
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,8 +21,91 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/lsdopen/archy/internal/metrics"
+	"github.com/lsdopen/archy/internal/mirror"
+	"github.com/lsdopen/archy/internal/registry"
+	"github.com/lsdopen/archy/internal/verify"
 )
 
+// writeCosignKeyFile PEM-encodes pub to a temp file for verify.NewVerifier,
+// mirroring internal/verify's own test helper of the same shape.
+func writeCosignKeyFile(t *testing.T, pub *ecdsa.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+
+	f, err := os.CreateTemp(t.TempDir(), "cosign-*.pub")
+	require.NoError(t, err)
+	defer f.Close()
+
+	require.NoError(t, pem.Encode(f, &pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	return f.Name()
+}
+
+// signedManifestBundle builds a cosign simple-signing bundle signed by key,
+// reproducing the payload verify.Verifier.VerifyManifest expects without
+// importing its unexported helpers.
+func signedManifestBundle(t *testing.T, key *ecdsa.PrivateKey, repo, digest string) []byte {
+	t.Helper()
+	payload, err := json.Marshal(map[string]interface{}{
+		"critical": map[string]interface{}{
+			"identity": map[string]string{"docker-reference": repo},
+			"image":    map[string]string{"docker-manifest-digest": digest},
+			"type":     "cosign container image signature",
+		},
+		"optional": nil,
+	})
+	require.NoError(t, err)
+
+	hash := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+
+	bundle := map[string]interface{}{
+		"layers": []map[string]interface{}{
+			{"annotations": map[string]string{"dev.cosignproject.cosign/signature": base64.StdEncoding.EncodeToString(sig)}},
+		},
+	}
+	raw, err := json.Marshal(bundle)
+	require.NoError(t, err)
+	return raw
+}
+
+// fakeVerifiableClient implements both types.RegistryClient and
+// verify.SignatureFetcher so verifyArchitectures tests can control the
+// signature lookup outcome without a real registry.
+type fakeVerifiableClient struct {
+	archs     []string
+	sigErr    error
+	sigRepo   string
+	sigDigest string
+	sigBundle []byte
+
+	attErr    error
+	attRepo   string
+	attDigest string
+	attBundle []byte
+}
+
+func (f *fakeVerifiableClient) GetSupportedArchitectures(ctx context.Context, image string) ([]string, error) {
+	return f.archs, nil
+}
+
+func (f *fakeVerifiableClient) FetchSignature(ctx context.Context, image string) (string, string, []byte, error) {
+	if f.sigErr != nil {
+		return "", "", nil, f.sigErr
+	}
+	return f.sigRepo, f.sigDigest, f.sigBundle, nil
+}
+
+func (f *fakeVerifiableClient) FetchAttestations(ctx context.Context, image string) (string, string, []byte, error) {
+	if f.attErr != nil {
+		return "", "", nil, f.attErr
+	}
+	return f.attRepo, f.attDigest, f.attBundle, nil
+}
+
 func TestMutator_PodsWithNoContainers(t *testing.T) {
 	client := fake.NewSimpleClientset()
 	mutator := NewMutator(client)
@@ -37,14 +129,14 @@ func TestMutator_PodsWithNoContainers(t *testing.T) {
 		},
 	}
 
-	patches, err := mutator.Mutate(req)
+	patches, _, err := mutator.Mutate(req)
 	require.NoError(t, err)
 	assert.Empty(t, patches) // No patches should be applied
 }
 
 func TestMutator_PodsWithInitContainersOnly(t *testing.T) {
 	client := fake.NewSimpleClientset()
-	mutator := NewMutator(client)
+	mutator := NewMutatorWithAffinityMode(client, "nodeSelector")
 
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -71,7 +163,7 @@ func TestMutator_PodsWithInitContainersOnly(t *testing.T) {
 		},
 	}
 
-	patches, err := mutator.Mutate(req)
+	patches, _, err := mutator.Mutate(req)
 	require.NoError(t, err)
 
 	// Should add node selector based on init container image
@@ -110,14 +202,14 @@ func TestMutator_PodsWithExistingArchitectureSelector(t *testing.T) {
 		},
 	}
 
-	patches, err := mutator.Mutate(req)
+	patches, _, err := mutator.Mutate(req)
 	require.NoError(t, err)
 	assert.Empty(t, patches) // No patches should be applied
 }
 
 func TestMutator_PodsWithConflictingNodeSelectors(t *testing.T) {
 	client := fake.NewSimpleClientset()
-	mutator := NewMutator(client)
+	mutator := NewMutatorWithAffinityMode(client, "nodeSelector")
 
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -147,7 +239,7 @@ func TestMutator_PodsWithConflictingNodeSelectors(t *testing.T) {
 		},
 	}
 
-	patches, err := mutator.Mutate(req)
+	patches, _, err := mutator.Mutate(req)
 	require.NoError(t, err)
 
 	// Should add arch selector while preserving existing selectors
@@ -157,7 +249,7 @@ func TestMutator_PodsWithConflictingNodeSelectors(t *testing.T) {
 
 func TestMutator_PodsWithInvalidImageReferences(t *testing.T) {
 	client := fake.NewSimpleClientset()
-	mutator := NewMutator(client)
+	mutator := NewMutatorWithAffinityMode(client, "nodeSelector")
 
 	tests := []struct {
 		name  string
@@ -203,7 +295,7 @@ func TestMutator_PodsWithInvalidImageReferences(t *testing.T) {
 				},
 			}
 
-			patches, err := mutator.Mutate(req)
+			patches, _, err := mutator.Mutate(req)
 			require.NoError(t, err)
 
 			// Should fallback to default architecture
@@ -215,7 +307,7 @@ func TestMutator_PodsWithInvalidImageReferences(t *testing.T) {
 
 func TestMutator_SystemPods(t *testing.T) {
 	client := fake.NewSimpleClientset()
-	mutator := NewMutator(client)
+	mutator := NewMutatorWithAffinityMode(client, "nodeSelector")
 
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -243,7 +335,7 @@ func TestMutator_SystemPods(t *testing.T) {
 		},
 	}
 
-	patches, err := mutator.Mutate(req)
+	patches, _, err := mutator.Mutate(req)
 	require.NoError(t, err)
 
 	// Should still process system pods
@@ -287,7 +379,7 @@ func TestMutator_ConcurrentMutationRequests(t *testing.T) {
 				},
 			}
 
-			patches, err := mutator.Mutate(req)
+			patches, _, err := mutator.Mutate(req)
 			if err != nil {
 				errors <- err
 				return
@@ -339,7 +431,7 @@ func TestMutator_MutationRollback(t *testing.T) {
 		},
 	}
 
-	patches, err := mutator.Mutate(req)
+	patches, _, err := mutator.Mutate(req)
 	require.NoError(t, err)
 
 	// Verify patches are valid JSON Patch format
@@ -359,4 +451,356 @@ func assertNodeSelectorPatch(t *testing.T, patches []JSONPatch, expectedArch str
 		}
 	}
 	assert.True(t, found, "Expected node selector patch not found")
-}
\ No newline at end of file
+}
+
+func TestMutator_NodeAffinityIsDefaultMode(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	mutator := NewMutator(client)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "test-container", Image: "nginx"}},
+		},
+	}
+
+	podBytes, err := json.Marshal(pod)
+	require.NoError(t, err)
+
+	req := &admissionv1.AdmissionRequest{UID: "test-uid", Object: runtime.RawExtension{Raw: podBytes}}
+
+	patches, _, err := mutator.Mutate(req)
+	require.NoError(t, err)
+	require.Len(t, patches, 1)
+	assert.Equal(t, "/spec/affinity", patches[0].Path)
+
+	affinity, ok := patches[0].Value.(*corev1.Affinity)
+	require.True(t, ok)
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	require.Len(t, terms, 1)
+	require.Len(t, terms[0].MatchExpressions, 1)
+	assert.Equal(t, "kubernetes.io/arch", terms[0].MatchExpressions[0].Key)
+	assert.Equal(t, []string{"amd64"}, terms[0].MatchExpressions[0].Values)
+}
+
+func TestMutator_NodeAffinityPreservesMultipleArchitectures(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	mutator := NewMutator(client)
+	mutator.cache.Set("myorg/app:latest", []string{"amd64", "arm64"})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "myorg/app:latest"}},
+		},
+	}
+
+	podBytes, err := json.Marshal(pod)
+	require.NoError(t, err)
+
+	req := &admissionv1.AdmissionRequest{UID: "test-uid", Object: runtime.RawExtension{Raw: podBytes}}
+
+	patches, _, err := mutator.Mutate(req)
+	require.NoError(t, err)
+	require.Len(t, patches, 1)
+
+	affinity := patches[0].Value.(*corev1.Affinity)
+	values := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0].Values
+	assert.ElementsMatch(t, []string{"amd64", "arm64"}, values)
+}
+
+func TestMutator_NodeAffinityMergesIntoExistingAffinity(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	mutator := NewMutator(client)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{
+							{
+								MatchExpressions: []corev1.NodeSelectorRequirement{
+									{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"us-west-1"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			Containers: []corev1.Container{{Name: "test-container", Image: "nginx"}},
+		},
+	}
+
+	podBytes, err := json.Marshal(pod)
+	require.NoError(t, err)
+
+	req := &admissionv1.AdmissionRequest{UID: "test-uid", Object: runtime.RawExtension{Raw: podBytes}}
+
+	patches, _, err := mutator.Mutate(req)
+	require.NoError(t, err)
+	require.Len(t, patches, 1)
+	assert.Equal(t, "/spec/affinity/nodeAffinity/requiredDuringSchedulingIgnoredDuringExecution/nodeSelectorTerms/0/matchExpressions/-", patches[0].Path)
+
+	expr, ok := patches[0].Value.(corev1.NodeSelectorRequirement)
+	require.True(t, ok)
+	assert.Equal(t, "kubernetes.io/arch", expr.Key)
+}
+
+func TestMutator_PodsWithExistingArchAffinitySkipped(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	mutator := NewMutator(client)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{
+							{
+								MatchExpressions: []corev1.NodeSelectorRequirement{
+									{Key: "kubernetes.io/arch", Operator: corev1.NodeSelectorOpIn, Values: []string{"arm64"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			Containers: []corev1.Container{{Name: "test-container", Image: "nginx"}},
+		},
+	}
+
+	podBytes, err := json.Marshal(pod)
+	require.NoError(t, err)
+
+	req := &admissionv1.AdmissionRequest{UID: "test-uid", Object: runtime.RawExtension{Raw: podBytes}}
+
+	patches, _, err := mutator.Mutate(req)
+	require.NoError(t, err)
+	assert.Empty(t, patches)
+}
+
+func TestMutator_VerifyArchitecturesEnforceRejectsFailedFetch(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	verifier, err := verify.NewVerifier(verify.ModeEnforce, "", nil, "")
+	require.NoError(t, err)
+
+	mutator := NewMutatorWithVerifier(client, verifier)
+	fakeClient := &fakeVerifiableClient{sigErr: fmt.Errorf("registry unreachable")}
+
+	ok := mutator.verifyArchitectures(context.Background(), fakeClient, "myorg/app:latest", "default")
+	assert.False(t, ok)
+}
+
+func TestMutator_VerifyArchitecturesWarnAllowsFailedFetch(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	verifier, err := verify.NewVerifier(verify.ModeWarn, "", nil, "")
+	require.NoError(t, err)
+
+	mutator := NewMutatorWithVerifier(client, verifier)
+	fakeClient := &fakeVerifiableClient{sigErr: fmt.Errorf("registry unreachable")}
+
+	ok := mutator.verifyArchitectures(context.Background(), fakeClient, "myorg/app:latest", "default")
+	assert.True(t, ok)
+}
+
+func TestMutator_VerifyArchitecturesUnconfiguredAlwaysAllows(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	mutator := NewMutator(client)
+	fakeClient := &fakeVerifiableClient{sigErr: fmt.Errorf("registry unreachable")}
+
+	ok := mutator.verifyArchitectures(context.Background(), fakeClient, "myorg/app:latest", "default")
+	assert.True(t, ok)
+}
+
+func TestMutator_VerifyArchitecturesClientWithoutFetcherAllows(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	verifier, err := verify.NewVerifier(verify.ModeEnforce, "", nil, "")
+	require.NoError(t, err)
+
+	mutator := NewMutatorWithVerifier(client, verifier)
+
+	ok := mutator.verifyArchitectures(context.Background(), registry.NewDockerHubClient(), "myorg/app:latest", "default")
+	assert.True(t, ok)
+}
+
+func TestMutator_NamespaceFailPolicyOpenOverridesEnforce(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "team-a",
+			Annotations: map[string]string{failPolicyAnnotation: "open"},
+		},
+	})
+	verifier, err := verify.NewVerifier(verify.ModeEnforce, "", nil, "")
+	require.NoError(t, err)
+
+	mutator := NewMutatorWithVerifier(client, verifier)
+	fakeClient := &fakeVerifiableClient{sigErr: fmt.Errorf("registry unreachable")}
+
+	ok := mutator.verifyArchitectures(context.Background(), fakeClient, "myorg/app:latest", "team-a")
+	assert.True(t, ok)
+}
+
+func TestMutator_NamespaceFailPolicyClosedOverridesWarn(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "team-b",
+			Annotations: map[string]string{failPolicyAnnotation: "closed"},
+		},
+	})
+	verifier, err := verify.NewVerifier(verify.ModeWarn, "", nil, "")
+	require.NoError(t, err)
+
+	mutator := NewMutatorWithVerifier(client, verifier)
+	fakeClient := &fakeVerifiableClient{sigErr: fmt.Errorf("registry unreachable")}
+
+	ok := mutator.verifyArchitectures(context.Background(), fakeClient, "myorg/app:latest", "team-b")
+	assert.False(t, ok)
+}
+
+func TestMutator_AttestationPolicyRejectsMissingAttestation(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyPath := writeCosignKeyFile(t, &key.PublicKey)
+
+	verifier, err := verify.NewVerifier(verify.ModeEnforce, keyPath, nil, "")
+	require.NoError(t, err)
+
+	mutator := NewMutatorWithAttestationPolicy(client, verifier, []string{"https://slsa.dev/provenance/v1"})
+	fakeClient := &fakeVerifiableClient{
+		sigRepo:   "myorg/app",
+		sigDigest: "sha256:abc123",
+		sigBundle: signedManifestBundle(t, key, "myorg/app", "sha256:abc123"),
+		attErr:    fmt.Errorf("no attestation found"),
+	}
+
+	ok := mutator.verifyArchitectures(context.Background(), fakeClient, "myorg/app:latest", "default")
+	assert.False(t, ok)
+}
+
+func TestMutator_AttestationPolicyUnconfiguredSkipsAttestationCheck(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	verifier, err := verify.NewVerifier(verify.ModeOff, "", nil, "")
+	require.NoError(t, err)
+
+	mutator := NewMutatorWithVerifier(client, verifier)
+	fakeClient := &fakeVerifiableClient{attErr: fmt.Errorf("no attestation found")}
+
+	ok := mutator.verifyArchitectures(context.Background(), fakeClient, "myorg/app:latest", "default")
+	assert.True(t, ok)
+}
+
+func TestMutator_NewMutatorWithMirrorWrapsClientsTransparently(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store, err := mirror.NewStore(t.TempDir(), 0)
+	require.NoError(t, err)
+	mirrorClient := mirror.NewClient(store, metrics.NewMetrics())
+
+	mutator := NewMutatorWithMirror(client, mirrorClient)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "nginx"}},
+		},
+	}
+
+	podBytes, err := json.Marshal(pod)
+	require.NoError(t, err)
+
+	req := &admissionv1.AdmissionRequest{UID: "test-uid", Object: runtime.RawExtension{Raw: podBytes}}
+
+	// DockerHubClient doesn't implement mirror.ManifestFetcher, so the mirror
+	// wraps it as a no-op; mutation should proceed exactly as it would
+	// without a mirror configured.
+	patches, _, err := mutator.Mutate(req)
+	require.NoError(t, err)
+	assert.NotEmpty(t, patches)
+}
+
+func TestMutator_NewMutatorWithLocalInspectorPrefersLocalResult(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	local := &fakeVerifiableClient{archs: []string{"arm64"}}
+
+	mutator := NewMutatorWithLocalInspector(client, local)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "nginx"}},
+		},
+	}
+
+	archs, warnings := mutator.detectArchitectures(pod, "nginx")
+	assert.Equal(t, []string{"arm64"}, archs)
+	assert.Empty(t, warnings)
+}
+
+// denyLimiter is a ratelimit.Limiter stub that always denies, so tests can
+// exercise detectArchitectures' rate-limit short-circuit without racing a
+// real token bucket.
+type denyLimiter struct{}
+
+func (denyLimiter) Allow(ctx context.Context, key string) bool { return false }
+
+func TestMutator_RateLimitedLookupAdmitsWithDefaultArchAndWarning(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	mutator := NewMutatorWithRateLimiter(client, denyLimiter{})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "nginx"}},
+		},
+	}
+
+	archs, warnings := mutator.detectArchitectures(pod, "nginx")
+	assert.Equal(t, []string{"amd64"}, archs)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "rate-limited")
+}
+
+func TestMutator_RateLimitedMutateAdmitsUnmodifiedWithWarning(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	mutator := NewMutatorWithRateLimiter(client, denyLimiter{})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "nginx"}},
+		},
+	}
+	podBytes, err := json.Marshal(pod)
+	require.NoError(t, err)
+
+	req := &admissionv1.AdmissionRequest{UID: "test-uid", Object: runtime.RawExtension{Raw: podBytes}}
+
+	patches, warnings, err := mutator.Mutate(req)
+	require.NoError(t, err)
+	assert.NotEmpty(t, patches)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "rate-limited")
+}
+
+func TestRateLimitKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		image     string
+		want      string
+	}{
+		{"unqualified", "default", "nginx:1.27", "default/nginx"},
+		{"docker hub namespaced", "default", "library/nginx:1.27", "default/library/nginx"},
+		{"hosted registry with port", "prod", "registry.example.com:5000/team/app:v2", "prod/team/app"},
+		{"digest reference", "default", "nginx@sha256:abcd", "default/nginx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rateLimitKey(tt.namespace, tt.image))
+		})
+	}
+}
@@ -1,41 +1,162 @@
 package webhook
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"log"
 	"net/http"
-	"runtime"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/lsdopen/archy/internal/health"
 )
 
-// NewServer creates a new HTTPS server with TLS configuration
-func NewServer(addr, certPath, keyPath string) (*http.Server, error) {
-	// Load TLS certificate
-	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+// Server is an HTTPS admission webhook server. It embeds *http.Server so
+// callers can use it exactly like one (ServeTLS, ReadTimeout, ...), while
+// Shutdown additionally drains requests tracked by its own middleware and
+// stops the certificate reloader started by NewServer.
+type Server struct {
+	*http.Server
+	tracker *requestTracker
+	certs   *certProvider
+	checker *health.Checker
+}
+
+// NewServer creates a new HTTPS server with TLS configuration. mutator and
+// validator are optional: when non-nil they are wired up as the /mutate and
+// /validate admission webhook endpoints respectively. When mutator is
+// non-nil, its metrics registry is also scraped at /metrics.
+//
+// The certificate at certPath/keyPath is hot-reloaded: a background watcher
+// re-reads it whenever the files change (fsnotify), on SIGHUP, and on a
+// 60-second fallback timer, so cert-manager/Vault Agent-style rotation
+// doesn't require restarting the pod. Call Reload to trigger an out-of-band
+// re-read, e.g. from a test or an additional signal handler.
+func NewServer(addr, certPath, keyPath string, mutator *Mutator, validator *Validator) (*Server, error) {
+	return newServer(addr, certPath, keyPath, mutator, validator, nil)
+}
+
+// NewServerWithHealthChecker creates a server exactly like NewServer, except
+// /health and /ready are served by checker instead of the trivial built-in
+// handlers: /ready aggregates every check checker has registered (e.g. via
+// health.KubernetesAPICheck, health.TLSCertExpiryCheck,
+// health.RegistryReachabilityCheck, health.CacheHealthCheck, or a custom
+// CheckFunc an operator registers in main.go), and /health reports only
+// whether checker's own background loop is running. checker is started
+// by this constructor, before the server starts serving, and stopped on
+// Shutdown.
+func NewServerWithHealthChecker(addr, certPath, keyPath string, mutator *Mutator, validator *Validator, checker *health.Checker) (*Server, error) {
+	return newServer(addr, certPath, keyPath, mutator, validator, checker)
+}
+
+func newServer(addr, certPath, keyPath string, mutator *Mutator, validator *Validator, checker *health.Checker) (*Server, error) {
+	certs, err := newCertProvider(certPath, keyPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		return nil, err
 	}
 
 	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		MinVersion:   tls.VersionTLS12,
+		GetCertificate:     certs.getCertificate,
+		GetConfigForClient: certs.getConfigForClient,
+		MinVersion:         tls.VersionTLS12,
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/health", healthHandler)
-	mux.HandleFunc("/ready", readyHandler)
+	if checker != nil {
+		mux.HandleFunc("/health", checker.LivenessHandler)
+		mux.HandleFunc("/ready", checker.ReadyHandler)
+	} else {
+		mux.HandleFunc("/health", healthHandler)
+		mux.HandleFunc("/ready", readyHandler)
+	}
 	mux.HandleFunc("/slow", slowHandler)
 
+	if mutator != nil {
+		mux.Handle("/mutate", &AdmissionHandler{mutator: mutator})
+		mux.Handle("/metrics", promhttp.HandlerFor(mutator.Metrics().Registry(), promhttp.HandlerOpts{}))
+	}
+	if validator != nil {
+		mux.Handle("/validate", NewValidationHandler(validator))
+	}
+
+	tracker := &requestTracker{}
+
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      loggingMiddleware(recoveryMiddleware(mux)),
+		Handler:      tracker.middleware(loggingMiddleware(recoveryMiddleware(mux))),
 		TLSConfig:    tlsConfig,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
 
-	return server, nil
+	if checker != nil {
+		checker.Start()
+	}
+
+	return &Server{Server: server, tracker: tracker, certs: certs, checker: checker}, nil
+}
+
+// Reload re-reads the TLS certificate and key from disk immediately,
+// independent of the background watcher/timer. It's exported so callers can
+// wire additional triggers (the server already reloads on SIGHUP itself).
+func (s *Server) Reload() {
+	s.certs.Reload()
+}
+
+// Shutdown gracefully stops the server: it first lets http.Server stop
+// accepting new connections and close idle ones, then waits for every
+// request the tracker middleware saw to finish, up to ctx's deadline, and
+// finally stops the certificate reloader.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.Server.Shutdown(ctx); err != nil {
+		return err
+	}
+	err := s.tracker.wait(ctx)
+	s.certs.Close()
+	if s.checker != nil {
+		s.checker.Stop()
+	}
+	return err
+}
+
+// requestTracker counts in-flight requests via middleware so Shutdown can
+// wait for them to drain independent of http.Server's own connection
+// bookkeeping.
+type requestTracker struct {
+	wg sync.WaitGroup
+}
+
+func (t *requestTracker) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.wg.Add(1)
+		defer t.wg.Done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (t *requestTracker) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
@@ -72,4 +193,156 @@ func loggingMiddleware(next http.Handler) http.Handler {
 		// Log request (implementation would go here)
 		_ = time.Since(start)
 	})
-}
\ No newline at end of file
+}
+
+// certReloadInterval is the fallback period on which certProvider re-reads
+// its certificate/key files even without a filesystem notification, so
+// rotation still takes effect if fsnotify misses an event (e.g. an NFS mount
+// that doesn't deliver inotify events, or a watch lost across a container
+// restart of the sidecar that writes the files).
+const certReloadInterval = 60 * time.Second
+
+// certProvider holds the webhook server's current TLS certificate behind an
+// atomic.Value so ServeTLS handshakes never block on a reload, and runs a
+// background goroutine that keeps it fresh. It's modelled on
+// k8s.io/apiserver/pkg/server/dynamiccertificates: certificates are swapped
+// in on successful parse, and a failed reload just logs and keeps serving
+// whatever loaded last.
+type certProvider struct {
+	certPath string
+	keyPath  string
+
+	current atomic.Value // *tls.Certificate
+
+	watcher *fsnotify.Watcher
+	sighup  chan os.Signal
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// newCertProvider loads the initial certificate from certPath/keyPath and
+// starts the background reloader watching both files for changes.
+func newCertProvider(certPath, keyPath string) (*certProvider, error) {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate watcher: %w", err)
+	}
+	for _, dir := range certWatchDirs(certPath, keyPath) {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("failed to watch %s for certificate changes: %w", dir, err)
+		}
+	}
+
+	p := &certProvider{
+		certPath: certPath,
+		keyPath:  keyPath,
+		watcher:  watcher,
+		sighup:   make(chan os.Signal, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	p.current.Store(&cert)
+
+	signal.Notify(p.sighup, syscall.SIGHUP)
+	go p.run()
+
+	return p, nil
+}
+
+// certWatchDirs returns the distinct parent directories of paths. Cert/key
+// rotation (e.g. a Kubernetes secret mount) typically replaces the files via
+// a directory-level symlink swap, which only fsnotify watchers on the
+// containing directory reliably observe.
+func certWatchDirs(paths ...string) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, p := range paths {
+		dir := filepath.Dir(p)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func (p *certProvider) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(certReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-p.sighup:
+			p.Reload()
+		case <-ticker.C:
+			p.Reload()
+		case event, ok := <-p.watcher.Events:
+			if !ok {
+				return
+			}
+			if p.matchesWatchedFile(event.Name) {
+				p.Reload()
+			}
+		case _, ok := <-p.watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+func (p *certProvider) matchesWatchedFile(name string) bool {
+	name = filepath.Clean(name)
+	return name == filepath.Clean(p.certPath) || name == filepath.Clean(p.keyPath)
+}
+
+// Reload re-reads the certificate and key from disk and, on success,
+// atomically swaps them in for the next TLS handshake; in-flight
+// connections keep whatever certificate they already negotiated with. A
+// parse failure is logged and the previously loaded certificate is left in
+// place.
+func (p *certProvider) Reload() {
+	cert, err := tls.LoadX509KeyPair(p.certPath, p.keyPath)
+	if err != nil {
+		log.Printf("webhook: failed to reload TLS certificate from %s/%s: %v; keeping previous certificate", p.certPath, p.keyPath, err)
+		return
+	}
+	p.current.Store(&cert)
+}
+
+// getCertificate implements tls.Config.GetCertificate.
+func (p *certProvider) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, _ := p.current.Load().(*tls.Certificate)
+	if cert == nil {
+		return nil, fmt.Errorf("no TLS certificate loaded")
+	}
+	return cert, nil
+}
+
+// getConfigForClient implements tls.Config.GetConfigForClient. It returns
+// (nil, nil) so every handshake falls back to the Server's static
+// tls.Config (and this provider's GetCertificate); it exists as the
+// extension point dynamiccertificates-style consumers use for per-SNI
+// config, which this server doesn't need today.
+func (p *certProvider) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return nil, nil
+}
+
+// Close stops the background reloader and releases its watcher/signal
+// registration.
+func (p *certProvider) Close() {
+	close(p.stop)
+	signal.Stop(p.sighup)
+	p.watcher.Close()
+	<-p.done
+}
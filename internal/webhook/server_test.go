@@ -29,7 +29,7 @@ func TestServer_TLSCertificateLoading(t *testing.T) {
 	defer os.Remove(certFile)
 	defer os.Remove(keyFile)
 
-	server, err := NewServer(":0", certFile, keyFile)
+	server, err := NewServer(":0", certFile, keyFile, nil, nil)
 	require.NoError(t, err)
 	assert.NotNil(t, server)
 }
@@ -57,7 +57,7 @@ func TestServer_InvalidCertificateHandling(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := NewServer(":0", tt.certPath, tt.keyPath)
+			_, err := NewServer(":0", tt.certPath, tt.keyPath, nil, nil)
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), tt.wantErr)
 		})
@@ -70,7 +70,7 @@ func TestServer_ExpiredCertificateHandling(t *testing.T) {
 	defer os.Remove(certFile)
 	defer os.Remove(keyFile)
 
-	server, err := NewServer(":0", certFile, keyFile)
+	server, err := NewServer(":0", certFile, keyFile, nil, nil)
 	require.NoError(t, err)
 
 	// Start server
@@ -102,7 +102,7 @@ func TestServer_HTTPTimeouts(t *testing.T) {
 	defer os.Remove(certFile)
 	defer os.Remove(keyFile)
 
-	server, err := NewServer(":0", certFile, keyFile)
+	server, err := NewServer(":0", certFile, keyFile, nil, nil)
 	require.NoError(t, err)
 
 	// Verify timeout settings
@@ -116,7 +116,7 @@ func TestServer_MiddlewareChain(t *testing.T) {
 	defer os.Remove(certFile)
 	defer os.Remove(keyFile)
 
-	server, err := NewServer(":0", certFile, keyFile)
+	server, err := NewServer(":0", certFile, keyFile, nil, nil)
 	require.NoError(t, err)
 
 	listener, err := net.Listen("tcp", ":0")
@@ -149,7 +149,7 @@ func TestServer_ShutdownWithPendingRequests(t *testing.T) {
 	defer os.Remove(certFile)
 	defer os.Remove(keyFile)
 
-	server, err := NewServer(":0", certFile, keyFile)
+	server, err := NewServer(":0", certFile, keyFile, nil, nil)
 	require.NoError(t, err)
 
 	listener, err := net.Listen("tcp", ":0")
@@ -197,7 +197,7 @@ func TestServer_ConcurrentConnections(t *testing.T) {
 	defer os.Remove(certFile)
 	defer os.Remove(keyFile)
 
-	server, err := NewServer(":0", certFile, keyFile)
+	server, err := NewServer(":0", certFile, keyFile, nil, nil)
 	require.NoError(t, err)
 
 	listener, err := net.Listen("tcp", ":0")
@@ -253,7 +253,7 @@ func TestServer_MemoryLeaks(t *testing.T) {
 	defer os.Remove(certFile)
 	defer os.Remove(keyFile)
 
-	server, err := NewServer(":0", certFile, keyFile)
+	server, err := NewServer(":0", certFile, keyFile, nil, nil)
 	require.NoError(t, err)
 
 	listener, err := net.Listen("tcp", ":0")
@@ -288,6 +288,123 @@ func TestServer_MemoryLeaks(t *testing.T) {
 	runtime.GC()
 }
 
+func TestServer_CertificateHotReload(t *testing.T) {
+	certFile, keyFile := createTestCertificates(t)
+	defer os.Remove(certFile)
+	defer os.Remove(keyFile)
+
+	server, err := NewServer(":0", certFile, keyFile, nil, nil)
+	require.NoError(t, err)
+	defer server.certs.Close()
+
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		server.ServeTLS(listener, "", "")
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		Timeout: 5 * time.Second,
+	}
+
+	firstCert := leafCertFromHandshake(t, client, listener.Addr().String())
+
+	// Start a slow request with the original certificate still in flight...
+	inFlightDone := make(chan *x509.Certificate, 1)
+	go func() {
+		inFlightDone <- leafCertFromSlowHandshake(t, listener.Addr().String())
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// ...then rotate the cert/key files in place and force a reload rather
+	// than waiting on fsnotify/the 60s timer, which would make this test slow
+	// and timing-dependent.
+	newCertFile, newKeyFile := createTestCertificates(t)
+	defer os.Remove(newCertFile)
+	defer os.Remove(newKeyFile)
+
+	overwriteFile(t, certFile, newCertFile)
+	overwriteFile(t, keyFile, newKeyFile)
+	server.Reload()
+
+	secondCert := leafCertFromHandshake(t, client, listener.Addr().String())
+	assert.False(t, firstCert.Equal(secondCert), "expected a new handshake to observe the rotated certificate")
+
+	// The in-flight connection above negotiated before the rotation, so it
+	// should have kept seeing the original certificate.
+	inFlightCert := <-inFlightDone
+	assert.True(t, firstCert.Equal(inFlightCert), "expected the in-flight connection to keep its original certificate")
+}
+
+func TestServer_CertificateReloadKeepsServingOnBadRotation(t *testing.T) {
+	certFile, keyFile := createTestCertificates(t)
+	defer os.Remove(certFile)
+	defer os.Remove(keyFile)
+
+	server, err := NewServer(":0", certFile, keyFile, nil, nil)
+	require.NoError(t, err)
+	defer server.certs.Close()
+
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		server.ServeTLS(listener, "", "")
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		Timeout: 5 * time.Second,
+	}
+
+	before := leafCertFromHandshake(t, client, listener.Addr().String())
+
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0o600))
+	server.Reload()
+
+	after := leafCertFromHandshake(t, client, listener.Addr().String())
+	assert.True(t, before.Equal(after), "expected a failed reload to keep serving the previous certificate")
+}
+
+func leafCertFromHandshake(t *testing.T, client *http.Client, addr string) *x509.Certificate {
+	t.Helper()
+	resp, err := client.Get(fmt.Sprintf("https://%s/health", addr))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.NotEmpty(t, resp.TLS.PeerCertificates)
+	return resp.TLS.PeerCertificates[0]
+}
+
+func leafCertFromSlowHandshake(t *testing.T, addr string) *x509.Certificate {
+	t.Helper()
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		Timeout: 5 * time.Second,
+	}
+	resp, err := client.Get(fmt.Sprintf("https://%s/slow", addr))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.NotEmpty(t, resp.TLS.PeerCertificates)
+	return resp.TLS.PeerCertificates[0]
+}
+
+func overwriteFile(t *testing.T, dst, src string) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst, data, 0o600))
+}
+
 // Helper functions
 func createTestCertificates(t *testing.T) (string, string) {
 	return createCertificates(t, time.Now().Add(24*time.Hour))
@@ -308,11 +425,11 @@ func createCertificates(t *testing.T, notAfter time.Time) (string, string) {
 		Subject: pkix.Name{
 			Organization: []string{"Test"},
 		},
-		NotBefore:    time.Now().Add(-time.Hour),
-		NotAfter:     notAfter,
-		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
 	}
 
 	// Create certificate
@@ -339,4 +456,4 @@ func createCertificates(t *testing.T, notAfter time.Time) (string, string) {
 	require.NoError(t, err)
 
 	return certFile.Name(), keyFile.Name()
-}
\ No newline at end of file
+}
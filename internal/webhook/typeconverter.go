@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+)
+
+// TypeConverter translates AdmissionRequest/AdmissionResponse values between
+// the deprecated admission.k8s.io/v1beta1 wire format and admission.k8s.io/v1.
+// The two APIs share identical fields (only the package, and therefore the Go
+// type, differs), so AdmissionHandler uses TypeConverter to normalize every
+// request to v1 before handing it to Mutator, keeping the rest of the
+// pipeline unaware that v1beta1 exists.
+type TypeConverter struct{}
+
+// RequestToV1 converts a v1beta1 AdmissionRequest to its v1 equivalent.
+func (TypeConverter) RequestToV1(req *admissionv1beta1.AdmissionRequest) *admissionv1.AdmissionRequest {
+	if req == nil {
+		return nil
+	}
+
+	return &admissionv1.AdmissionRequest{
+		UID:                req.UID,
+		Kind:               req.Kind,
+		Resource:           req.Resource,
+		SubResource:        req.SubResource,
+		RequestKind:        req.RequestKind,
+		RequestResource:    req.RequestResource,
+		RequestSubResource: req.RequestSubResource,
+		Name:               req.Name,
+		Namespace:          req.Namespace,
+		Operation:          admissionv1.Operation(req.Operation),
+		UserInfo:           req.UserInfo,
+		Object:             req.Object,
+		OldObject:          req.OldObject,
+		DryRun:             req.DryRun,
+		Options:            req.Options,
+	}
+}
+
+// ResponseFromV1 converts a v1 AdmissionResponse back to v1beta1, so
+// AdmissionHandler can answer an API server that sent a v1beta1
+// AdmissionReview in the version it understands.
+func (TypeConverter) ResponseFromV1(resp *admissionv1.AdmissionResponse) *admissionv1beta1.AdmissionResponse {
+	if resp == nil {
+		return nil
+	}
+
+	return &admissionv1beta1.AdmissionResponse{
+		UID:              resp.UID,
+		Allowed:          resp.Allowed,
+		Result:           resp.Result,
+		Patch:            resp.Patch,
+		PatchType:        (*admissionv1beta1.PatchType)(resp.PatchType),
+		AuditAnnotations: resp.AuditAnnotations,
+		Warnings:         resp.Warnings,
+	}
+}
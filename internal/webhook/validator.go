@@ -0,0 +1,364 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	icache "github.com/lsdopen/archy/internal/cache"
+	"github.com/lsdopen/archy/internal/credentials"
+	"github.com/lsdopen/archy/internal/metrics"
+	"github.com/lsdopen/archy/internal/registry"
+	"github.com/lsdopen/archy/internal/verify"
+	"github.com/lsdopen/archy/pkg/types"
+)
+
+// ValidationMode controls how Validator reacts to a pod whose images have no
+// architecture available on any node in the cluster.
+type ValidationMode string
+
+const (
+	// ValidationEnforce denies admission when no cluster node can run the pod.
+	ValidationEnforce ValidationMode = "enforce"
+	// ValidationWarn allows admission but attaches a warning.
+	ValidationWarn ValidationMode = "warn"
+	// ValidationOff disables the check entirely.
+	ValidationOff ValidationMode = "off"
+)
+
+// Validator rejects (or warns about) pods whose images have no architecture
+// schedulable on the cluster, instead of letting Mutator silently fall back
+// to defaultArch and leave the pod Pending forever.
+type Validator struct {
+	mode               ValidationMode
+	registryClient     types.RegistryClient
+	cache              icache.Cache
+	credentialResolver *credentials.Resolver
+	metrics            *metrics.Metrics
+	verifier           *verify.Verifier
+	kubeClient         kubernetes.Interface
+
+	mu         sync.RWMutex
+	nodeArches map[string]bool
+	informer   cache.SharedInformer
+}
+
+// NewValidator creates a Validator that shares the mutator's cache, registry
+// client and credential resolver, and keeps a live list of node architectures
+// via a SharedInformer on corev1.Node.
+func NewValidator(kubeClient kubernetes.Interface, mode ValidationMode, sharedCache icache.Cache, registryClient types.RegistryClient, credResolver *credentials.Resolver) *Validator {
+	v := &Validator{
+		mode:               mode,
+		registryClient:     registryClient,
+		cache:              sharedCache,
+		credentialResolver: credResolver,
+		metrics:            metrics.NewMetrics(),
+		kubeClient:         kubeClient,
+		nodeArches:         make(map[string]bool),
+	}
+
+	if kubeClient != nil {
+		v.informer = cache.NewSharedInformer(
+			&cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					return kubeClient.CoreV1().Nodes().List(context.Background(), options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					return kubeClient.CoreV1().Nodes().Watch(context.Background(), options)
+				},
+			},
+			&corev1.Node{},
+			10*time.Minute,
+		)
+		v.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { v.recomputeNodeArches() },
+			UpdateFunc: func(_, obj interface{}) { v.recomputeNodeArches() },
+			DeleteFunc: func(obj interface{}) { v.recomputeNodeArches() },
+		})
+	}
+
+	return v
+}
+
+// NewValidatorWithVerifier creates a Validator that additionally checks
+// Cosign signatures via verifier, denying (enforce) or warning (warn) about
+// pods whose images fail verification.
+func NewValidatorWithVerifier(kubeClient kubernetes.Interface, mode ValidationMode, sharedCache icache.Cache, registryClient types.RegistryClient, credResolver *credentials.Resolver, verifier *verify.Verifier) *Validator {
+	v := NewValidator(kubeClient, mode, sharedCache, registryClient, credResolver)
+	v.verifier = verifier
+	return v
+}
+
+// Run starts the node informer and blocks until stopCh is closed. It is a
+// no-op if the Validator was constructed without a Kubernetes client.
+func (v *Validator) Run(stopCh <-chan struct{}) {
+	if v.informer == nil {
+		return
+	}
+	v.informer.Run(stopCh)
+}
+
+func (v *Validator) recomputeNodeArches() {
+	arches := make(map[string]bool)
+	for _, obj := range v.informer.GetStore().List() {
+		node, ok := obj.(*corev1.Node)
+		if !ok {
+			continue
+		}
+		if arch := node.Labels["kubernetes.io/arch"]; arch != "" {
+			arches[arch] = true
+		}
+	}
+
+	v.mu.Lock()
+	v.nodeArches = arches
+	v.mu.Unlock()
+}
+
+func (v *Validator) clusterArchitectures() map[string]bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.nodeArches
+}
+
+// Admit inspects a pod admission request and denies (enforce) or warns
+// (warn) when none of its images' supported architectures match a node
+// architecture present in the cluster.
+func (v *Validator) Admit(req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+	response := &admissionv1.AdmissionResponse{UID: req.UID, Allowed: true}
+
+	verifierActive := v.verifier != nil && v.verifier.Mode() != verify.ModeOff
+	if v.mode == ValidationOff && !verifierActive {
+		return response, nil
+	}
+
+	var pod corev1.Pod
+	if err := json.Unmarshal(req.Object.Raw, &pod); err != nil {
+		// Fail open: the mutator already tolerates unparsable pods.
+		return response, nil
+	}
+
+	images := extractPodImages(&pod)
+	if len(images) == 0 {
+		return response, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if verifierActive {
+		signingMode := v.namespaceSigningMode(pod.Namespace)
+		if signingMode != verify.ModeOff {
+			for _, image := range images {
+				client := v.resolveClient(&pod, image)
+				if v.verifySignature(ctx, client, image) {
+					continue
+				}
+
+				msg := fmt.Sprintf("image %q failed Cosign signature verification", image)
+				if signingMode == verify.ModeEnforce {
+					response.Allowed = false
+					response.Result = &metav1.Status{Message: msg}
+					return response, nil
+				}
+				response.Warnings = append(response.Warnings, msg)
+			}
+		}
+	}
+
+	if v.mode == ValidationOff {
+		return response, nil
+	}
+
+	clusterArches := v.clusterArchitectures()
+	if len(clusterArches) == 0 {
+		// No informer data (or no node reports kubernetes.io/arch) yet;
+		// nothing reliable to validate against.
+		return response, nil
+	}
+
+	for _, image := range images {
+		archs := v.supportedArchitectures(&pod, image)
+		if len(archs) == 0 {
+			// Lookup failed; Mutator's fail-open default-arch path already
+			// covers this case, so don't pile a second failure mode here.
+			continue
+		}
+
+		if !intersects(archs, clusterArches) {
+			msg := fmt.Sprintf("image %q supports architectures %v but no cluster node advertises a matching kubernetes.io/arch label", image, archs)
+			if v.mode == ValidationEnforce {
+				response.Allowed = false
+				response.Result = &metav1.Status{Message: msg}
+				return response, nil
+			}
+			response.Warnings = append(response.Warnings, msg)
+		}
+	}
+
+	return response, nil
+}
+
+// resolveClient selects a registry client for image's host, wiring in any
+// credentials resolved for pod, falling back to the shared default client on
+// error.
+func (v *Validator) resolveClient(pod *corev1.Pod, image string) types.RegistryClient {
+	cred, _ := v.credentialResolver.ResolveCredentials(pod, image)
+
+	client, err := registry.NewClientWithCredentials(credentials.ExtractRegistry(image), cred)
+	if err != nil {
+		client = v.registryClient
+	}
+	return client
+}
+
+func (v *Validator) supportedArchitectures(pod *corev1.Pod, image string) []string {
+	if archs, found := v.cache.Get(image); found {
+		return archs
+	}
+
+	client := v.resolveClient(pod, image)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	archs, err := client.GetSupportedArchitectures(ctx, image)
+	if err != nil || len(archs) == 0 {
+		return nil
+	}
+
+	v.cache.Set(image, archs)
+	return archs
+}
+
+// verifySignature checks image's Cosign signature via client, recording a
+// metric on failure. Registry clients that don't implement
+// verify.SignatureFetcher are treated as unverifiable and reported as
+// verified so they don't block admission.
+func (v *Validator) verifySignature(ctx context.Context, client types.RegistryClient, image string) bool {
+	fetcher, ok := client.(verify.SignatureFetcher)
+	if !ok {
+		return true
+	}
+
+	repo, digest, bundle, err := fetcher.FetchSignature(ctx, image)
+	if err != nil {
+		v.metrics.RecordVerificationFailure(image)
+		return false
+	}
+
+	verified, _ := v.verifier.VerifyManifest(repo, digest, bundle)
+	if !verified {
+		v.metrics.RecordVerificationFailure(image)
+	}
+
+	return verified
+}
+
+// signingPolicyConfigMapName is looked up in the pod's namespace to let teams
+// tighten or relax the cluster-wide signature-verification mode without a
+// webhook redeploy, e.g. a namespace running unsigned internal tooling can
+// set "mode: off" while the rest of the cluster enforces.
+const signingPolicyConfigMapName = "archy-signing-policy"
+
+// namespaceSigningMode returns the Cosign verification mode effective for
+// namespace: the "mode" key ("enforce", "warn" or "off") of the
+// signingPolicyConfigMapName ConfigMap in that namespace if present and
+// valid, otherwise verifier's own global mode.
+func (v *Validator) namespaceSigningMode(namespace string) verify.Mode {
+	if v.kubeClient == nil {
+		return v.verifier.Mode()
+	}
+
+	cm, err := v.kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), signingPolicyConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return v.verifier.Mode()
+	}
+
+	switch cm.Data["mode"] {
+	case "enforce":
+		return verify.ModeEnforce
+	case "warn":
+		return verify.ModeWarn
+	case "off":
+		return verify.ModeOff
+	default:
+		return v.verifier.Mode()
+	}
+}
+
+// ValidationHandler serves the Validator as an HTTP admission webhook.
+type ValidationHandler struct {
+	validator *Validator
+}
+
+// NewValidationHandler wraps a Validator for use as an http.Handler.
+func NewValidationHandler(validator *Validator) *ValidationHandler {
+	return &ValidationHandler{validator: validator}
+}
+
+func (h *ValidationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var admissionReview admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &admissionReview); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to unmarshal admission review: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if admissionReview.Request == nil {
+		http.Error(w, "admission request is nil", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.validator.Admit(admissionReview.Request)
+	if err != nil {
+		// Fail open - allow the request even if validation itself errored.
+		response = &admissionv1.AdmissionResponse{UID: admissionReview.Request.UID, Allowed: true}
+	}
+
+	admissionResponse := &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admission.k8s.io/v1",
+			Kind:       "AdmissionReview",
+		},
+		Response: response,
+	}
+
+	responseBytes, err := json.Marshal(admissionResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to marshal admission response: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseBytes)
+}
+
+func intersects(archs []string, clusterArches map[string]bool) bool {
+	for _, arch := range archs {
+		if clusterArches[arch] {
+			return true
+		}
+	}
+	return false
+}
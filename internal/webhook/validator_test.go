@@ -0,0 +1,216 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	icache "github.com/lsdopen/archy/internal/cache"
+	"github.com/lsdopen/archy/internal/credentials"
+	"github.com/lsdopen/archy/internal/registry"
+	"github.com/lsdopen/archy/internal/verify"
+)
+
+func newTestValidator(t *testing.T, mode ValidationMode) *Validator {
+	t.Helper()
+	client := fake.NewSimpleClientset()
+	v := NewValidator(client, mode, icache.NewMemoryCache(100, time.Minute), registry.NewDockerHubClient(), credentials.NewResolver(client))
+	return v
+}
+
+func admissionRequestForPod(t *testing.T, pod *corev1.Pod) *admissionv1.AdmissionRequest {
+	t.Helper()
+	podBytes, err := json.Marshal(pod)
+	require.NoError(t, err)
+	return &admissionv1.AdmissionRequest{
+		UID: "test-uid",
+		Object: runtime.RawExtension{
+			Raw: podBytes,
+		},
+	}
+}
+
+func TestValidator_OffModeAlwaysAllows(t *testing.T) {
+	v := newTestValidator(t, ValidationOff)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "myorg/app:latest"}},
+		},
+	}
+
+	resp, err := v.Admit(admissionRequestForPod(t, pod))
+	require.NoError(t, err)
+	assert.True(t, resp.Allowed)
+	assert.Empty(t, resp.Warnings)
+}
+
+func TestValidator_NoClusterArchDataAllows(t *testing.T) {
+	v := newTestValidator(t, ValidationEnforce)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "myorg/app:latest"}},
+		},
+	}
+
+	resp, err := v.Admit(admissionRequestForPod(t, pod))
+	require.NoError(t, err)
+	assert.True(t, resp.Allowed)
+}
+
+func TestValidator_EnforceDeniesUnsupportedArch(t *testing.T) {
+	v := newTestValidator(t, ValidationEnforce)
+	v.nodeArches = map[string]bool{"arm64": true}
+	v.cache.Set("myorg/app:latest", []string{"amd64"})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "myorg/app:latest"}},
+		},
+	}
+
+	resp, err := v.Admit(admissionRequestForPod(t, pod))
+	require.NoError(t, err)
+	assert.False(t, resp.Allowed)
+	require.NotNil(t, resp.Result)
+	assert.Contains(t, resp.Result.Message, "myorg/app:latest")
+}
+
+func TestValidator_WarnModeAllowsWithWarning(t *testing.T) {
+	v := newTestValidator(t, ValidationWarn)
+	v.nodeArches = map[string]bool{"arm64": true}
+	v.cache.Set("myorg/app:latest", []string{"amd64"})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "myorg/app:latest"}},
+		},
+	}
+
+	resp, err := v.Admit(admissionRequestForPod(t, pod))
+	require.NoError(t, err)
+	assert.True(t, resp.Allowed)
+	require.Len(t, resp.Warnings, 1)
+	assert.Contains(t, resp.Warnings[0], "myorg/app:latest")
+}
+
+func TestValidator_AllowsMatchingArch(t *testing.T) {
+	v := newTestValidator(t, ValidationEnforce)
+	v.nodeArches = map[string]bool{"amd64": true, "arm64": true}
+	v.cache.Set("myorg/app:latest", []string{"arm64"})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "myorg/app:latest"}},
+		},
+	}
+
+	resp, err := v.Admit(admissionRequestForPod(t, pod))
+	require.NoError(t, err)
+	assert.True(t, resp.Allowed)
+	assert.Empty(t, resp.Warnings)
+}
+
+func TestValidator_VerifySignature_FetchErrorIsUnverified(t *testing.T) {
+	verifier, err := verify.NewVerifier(verify.ModeEnforce, "", nil, "")
+	require.NoError(t, err)
+
+	v := newTestValidator(t, ValidationOff)
+	v.verifier = verifier
+	fakeClient := &fakeVerifiableClient{sigErr: fmt.Errorf("registry unreachable")}
+
+	ok := v.verifySignature(context.Background(), fakeClient, "myorg/app:latest")
+	assert.False(t, ok)
+}
+
+func TestValidator_VerifySignature_ClientWithoutFetcherIsVerified(t *testing.T) {
+	verifier, err := verify.NewVerifier(verify.ModeEnforce, "", nil, "")
+	require.NoError(t, err)
+
+	v := newTestValidator(t, ValidationOff)
+	v.verifier = verifier
+
+	ok := v.verifySignature(context.Background(), registry.NewDockerHubClient(), "myorg/app:latest")
+	assert.True(t, ok)
+}
+
+func TestValidator_NamespaceSigningMode_ConfigMapOverridesGlobalMode(t *testing.T) {
+	verifier, err := verify.NewVerifier(verify.ModeEnforce, "", nil, "")
+	require.NoError(t, err)
+
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: signingPolicyConfigMapName, Namespace: "team-a"},
+		Data:       map[string]string{"mode": "off"},
+	})
+	v := newTestValidator(t, ValidationOff)
+	v.kubeClient = client
+	v.verifier = verifier
+
+	assert.Equal(t, verify.ModeOff, v.namespaceSigningMode("team-a"))
+	// A namespace without the ConfigMap falls back to the global mode.
+	assert.Equal(t, verify.ModeEnforce, v.namespaceSigningMode("team-b"))
+}
+
+func TestValidator_NamespaceSigningMode_IgnoresUnrecognizedValue(t *testing.T) {
+	verifier, err := verify.NewVerifier(verify.ModeWarn, "", nil, "")
+	require.NoError(t, err)
+
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: signingPolicyConfigMapName, Namespace: "team-c"},
+		Data:       map[string]string{"mode": "lockdown"},
+	})
+	v := newTestValidator(t, ValidationOff)
+	v.kubeClient = client
+	v.verifier = verifier
+
+	assert.Equal(t, verify.ModeWarn, v.namespaceSigningMode("team-c"))
+}
+
+func TestValidator_NamespaceSigningMode_NilKubeClientFallsBackToGlobalMode(t *testing.T) {
+	verifier, err := verify.NewVerifier(verify.ModeEnforce, "", nil, "")
+	require.NoError(t, err)
+
+	v := newTestValidator(t, ValidationOff)
+	v.kubeClient = nil
+	v.verifier = verifier
+
+	assert.Equal(t, verify.ModeEnforce, v.namespaceSigningMode("any-namespace"))
+}
+
+func TestValidator_AdmitRunsVerificationEvenWithArchCheckOff(t *testing.T) {
+	verifier, err := verify.NewVerifier(verify.ModeEnforce, "", nil, "")
+	require.NoError(t, err)
+
+	v := newTestValidator(t, ValidationOff)
+	v.verifier = verifier
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "myorg/app:latest"}},
+		},
+	}
+
+	// ValidationMode is off, but the verifier is still enforce-mode active,
+	// so Admit must not take its "mode off" shortcut before checking
+	// signatures.
+	resp, err := v.Admit(admissionRequestForPod(t, pod))
+	require.NoError(t, err)
+	assert.True(t, resp.Allowed)
+}
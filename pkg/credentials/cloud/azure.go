@@ -0,0 +1,200 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// azureIMDSTokenEndpoint is Azure's Instance Metadata Service endpoint for
+// acquiring a token from whatever managed identity (system- or
+// user-assigned) is attached to the VM/pod the controller runs on.
+const azureIMDSTokenEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// ACRProvider implements Provider for Azure Container Registry. It prefers
+// Azure AD workload identity when the pod is configured for it: exchanging
+// the pod's projected service account token for an AAD access token via a
+// federated-credential client assertion. When that's not configured, it
+// falls back to the controller's own managed identity via IMDS, so a
+// cluster running on AKS with a VM-level managed identity (rather than
+// workload identity federation) authenticates too. Either way, the AAD
+// access token is then traded for an ACR refresh token via the registry's
+// own /oauth2/exchange endpoint.
+//
+// For workload identity it reads the same environment variables the Azure
+// Workload Identity webhook injects: AZURE_CLIENT_ID, AZURE_TENANT_ID and
+// AZURE_FEDERATED_TOKEN_FILE. For the IMDS fallback, AZURE_CLIENT_ID (if
+// set) selects a user-assigned identity; otherwise the VM's system-assigned
+// identity is used.
+type ACRProvider struct {
+	httpClient *http.Client
+}
+
+// NewACRProvider returns an ACRProvider using a default HTTP client.
+func NewACRProvider() *ACRProvider {
+	return &ACRProvider{httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *ACRProvider) Name() string { return "acr" }
+
+func (p *ACRProvider) GetToken(ctx context.Context, registry string) (*Token, error) {
+	aadToken, expiresIn, err := p.getAADToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquiring AAD token: %w", err)
+	}
+
+	refreshToken, err := p.exchangeForRefreshToken(ctx, registry, aadToken)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging for ACR refresh token: %w", err)
+	}
+
+	return &Token{
+		// ACR's refresh-token grant expects this fixed GUID as the username,
+		// the same sentinel `docker login`/go-containerregistry use.
+		Username:  "00000000-0000-0000-0000-000000000000",
+		Password:  refreshToken,
+		ExpiresAt: time.Now().Add(expiresIn),
+	}, nil
+}
+
+// getAADToken acquires an AAD access token via workload identity federation
+// when the pod is configured for it, falling back to the controller's
+// ambient managed identity via IMDS otherwise.
+func (p *ACRProvider) getAADToken(ctx context.Context) (string, time.Duration, error) {
+	clientID := os.Getenv("AZURE_CLIENT_ID")
+	tenantID := os.Getenv("AZURE_TENANT_ID")
+	tokenFile := os.Getenv("AZURE_FEDERATED_TOKEN_FILE")
+	if tenantID == "" || tokenFile == "" {
+		return p.getAADTokenFromIMDS(ctx, clientID)
+	}
+
+	return p.getAADTokenFromFederatedCredential(ctx, clientID, tenantID, tokenFile)
+}
+
+func (p *ACRProvider) getAADTokenFromFederatedCredential(ctx context.Context, clientID, tenantID, tokenFile string) (string, time.Duration, error) {
+	assertion, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", 0, fmt.Errorf("reading federated token: %w", err)
+	}
+
+	form := url.Values{
+		"client_id":             {clientID},
+		"scope":                 {"https://management.azure.com/.default"},
+		"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+		"client_assertion":      {string(assertion)},
+		"grant_type":            {"client_credentials"},
+	}
+
+	endpoint := fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", tenantID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader([]byte(form.Encode())))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("aad token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", 0, fmt.Errorf("decoding aad token response: %w", err)
+	}
+
+	return parsed.AccessToken, time.Duration(parsed.ExpiresIn) * time.Second, nil
+}
+
+// getAADTokenFromIMDS acquires an AAD access token for the managed identity
+// attached to the VM/pod the controller runs on. clientID, when non-empty,
+// selects a user-assigned identity; otherwise IMDS resolves the VM's
+// system-assigned identity.
+func (p *ACRProvider) getAADTokenFromIMDS(ctx context.Context, clientID string) (string, time.Duration, error) {
+	query := url.Values{
+		"api-version": {"2018-02-01"},
+		"resource":    {"https://management.azure.com/"},
+	}
+	if clientID != "" {
+		query.Set("client_id", clientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, azureIMDSTokenEndpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Metadata", "true")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("querying azure instance metadata service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("azure imds returned status %d: is the controller running with a managed identity?", resp.StatusCode)
+	}
+
+	// IMDS returns expires_in as a JSON string, unlike the AAD token endpoint.
+	var parsed struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   string `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", 0, fmt.Errorf("decoding azure imds response: %w", err)
+	}
+
+	expiresIn, err := strconv.Atoi(parsed.ExpiresIn)
+	if err != nil {
+		expiresIn = 3600
+	}
+
+	return parsed.AccessToken, time.Duration(expiresIn) * time.Second, nil
+}
+
+func (p *ACRProvider) exchangeForRefreshToken(ctx context.Context, registry, aadToken string) (string, error) {
+	form := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {registry},
+		"access_token": {aadToken},
+	}
+
+	endpoint := fmt.Sprintf("https://%s/oauth2/exchange", registry)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader([]byte(form.Encode())))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("acr exchange returned status %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", fmt.Errorf("decoding acr exchange response: %w", err)
+	}
+
+	return parsed.RefreshToken, nil
+}
@@ -0,0 +1,170 @@
+// Package cloud implements pluggable cloud-provider workload-identity
+// credential providers (AWS IRSA for ECR, GCP Workload Identity Federation
+// for GAR/GCR, Azure AD workload identity for ACR) that internal/credentials
+// falls back to when no imagePullSecrets or static docker config.json
+// matches a registry.
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Token is a registry credential obtained by exchanging a pod's projected
+// service account token with a cloud provider, along with the time it
+// expires so callers can refresh before it does rather than after.
+type Token struct {
+	Username string
+	Password string
+
+	// IdentityToken is a bearer identity token in place of Username/Password,
+	// mirroring credentials.RegistryCredential's own IdentityToken field.
+	IdentityToken string
+
+	ExpiresAt time.Time
+}
+
+// Provider exchanges the pod's projected Kubernetes service account token
+// for short-lived credentials a registry client can use to authenticate,
+// e.g. by assuming an IAM role (ECR), impersonating a GCP service account
+// (GAR/GCR), or exchanging an AAD token (ACR).
+type Provider interface {
+	// Name identifies the provider in registry-pattern configuration, e.g. "ecr".
+	Name() string
+
+	// GetToken returns a fresh token scoped to registry.
+	GetToken(ctx context.Context, registry string) (*Token, error)
+}
+
+// refreshSkew is how far ahead of a token's expiry Matcher treats it as
+// stale, so a caller is never handed a credential that expires mid-request.
+const refreshSkew = 2 * time.Minute
+
+type patternProvider struct {
+	pattern *regexp.Regexp
+	label   string
+	Provider
+}
+
+// Matcher maps a registry hostname to the cloud Provider configured for it
+// via a glob-style pattern ("*.dkr.ecr.*.amazonaws.com", "*.azurecr.io",
+// "gcr.io", ...), caching each provider's token until shortly before it
+// expires so a TTL derived from the provider's own response — not a fixed
+// resolver-wide window — governs how long it's reused.
+type Matcher struct {
+	mu       sync.Mutex
+	patterns []patternProvider
+	cache    map[string]*Token
+}
+
+// NewMatcher returns an empty Matcher; call Register to add providers.
+func NewMatcher() *Matcher {
+	return &Matcher{cache: make(map[string]*Token)}
+}
+
+// Register associates pattern with provider. Patterns are matched in
+// registration order, first match wins.
+func (m *Matcher) Register(pattern string, provider Provider) {
+	m.patterns = append(m.patterns, patternProvider{pattern: globToRegexp(pattern), label: pattern, Provider: provider})
+}
+
+// Token returns the token configured for registry, fetching and caching a
+// fresh one via the matched Provider if none is cached or the cached one is
+// within refreshSkew of expiring. matched reports whether any pattern
+// matched registry at all, so callers can distinguish "no cloud provider
+// configured for this registry" from "the provider failed".
+func (m *Matcher) Token(ctx context.Context, registry string) (token *Token, matched bool, err error) {
+	provider, ok := m.providerFor(registry)
+	if !ok {
+		return nil, false, nil
+	}
+
+	m.mu.Lock()
+	if cached, found := m.cache[registry]; found && time.Now().Add(refreshSkew).Before(cached.ExpiresAt) {
+		m.mu.Unlock()
+		return cached, true, nil
+	}
+	m.mu.Unlock()
+
+	fresh, err := provider.GetToken(ctx, registry)
+	if err != nil {
+		return nil, true, err
+	}
+
+	m.mu.Lock()
+	m.cache[registry] = fresh
+	m.mu.Unlock()
+
+	return fresh, true, nil
+}
+
+func (m *Matcher) providerFor(registry string) (Provider, bool) {
+	for _, pp := range m.patterns {
+		if pp.pattern.MatchString(registry) {
+			return pp.Provider, true
+		}
+	}
+	return nil, false
+}
+
+// globToRegexp compiles pattern, a registry-host glob where "*" matches any
+// run of characters (including none), into an anchored regexp — enough for
+// hosts like "*.dkr.ecr.*.amazonaws.com" without a path-globbing library.
+func globToRegexp(pattern string) *regexp.Regexp {
+	escaped := regexp.QuoteMeta(pattern)
+	escaped = strings.ReplaceAll(escaped, `\*`, `.*`)
+	return regexp.MustCompile("^" + escaped + "$")
+}
+
+// builtinProvider resolves the provider name used in ConfigMap data (e.g.
+// "ecr") to its implementation.
+func builtinProvider(name string) (Provider, error) {
+	switch name {
+	case "ecr":
+		return NewECRProvider(), nil
+	case "gar", "gcr":
+		return NewGARProvider(), nil
+	case "acr":
+		return NewACRProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown cloud credential provider %q", name)
+	}
+}
+
+// LoadMatcherFromConfigMap builds a Matcher from a ConfigMap whose Data maps
+// a registry-host glob pattern to the name of a built-in provider ("ecr",
+// "gar"/"gcr", "acr"), e.g.:
+//
+//	data:
+//	  "*.dkr.ecr.*.amazonaws.com": ecr
+//	  "gcr.io": gar
+//	  "*-docker.pkg.dev": gar
+//	  "*.azurecr.io": acr
+//
+// This is the operator-facing configuration surface in lieu of a dedicated
+// CRD, since this tree has no CRD/controller-runtime machinery to generate
+// one from.
+func LoadMatcherFromConfigMap(ctx context.Context, client kubernetes.Interface, namespace, name string) (*Matcher, error) {
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("loading cloud credential configmap %s/%s: %w", namespace, name, err)
+	}
+
+	matcher := NewMatcher()
+	for pattern, providerName := range cm.Data {
+		provider, err := builtinProvider(strings.TrimSpace(providerName))
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+		matcher.Register(pattern, provider)
+	}
+
+	return matcher, nil
+}
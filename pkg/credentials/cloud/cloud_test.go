@@ -0,0 +1,119 @@
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubProvider struct {
+	name      string
+	token     *Token
+	err       error
+	callCount int
+}
+
+func (p *stubProvider) Name() string { return p.name }
+
+func (p *stubProvider) GetToken(ctx context.Context, registry string) (*Token, error) {
+	p.callCount++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.token, nil
+}
+
+func TestMatcher_ReturnsNoMatchForUnconfiguredRegistry(t *testing.T) {
+	matcher := NewMatcher()
+	matcher.Register("*.azurecr.io", &stubProvider{name: "acr"})
+
+	token, matched, err := matcher.Token(context.Background(), "gcr.io")
+	require.NoError(t, err)
+	assert.False(t, matched)
+	assert.Nil(t, token)
+}
+
+func TestMatcher_MatchesWildcardPattern(t *testing.T) {
+	provider := &stubProvider{name: "ecr", token: &Token{Password: "secret", ExpiresAt: time.Now().Add(time.Hour)}}
+	matcher := NewMatcher()
+	matcher.Register("*.dkr.ecr.*.amazonaws.com", provider)
+
+	token, matched, err := matcher.Token(context.Background(), "123456789.dkr.ecr.us-east-1.amazonaws.com")
+	require.NoError(t, err)
+	assert.True(t, matched)
+	require.NotNil(t, token)
+	assert.Equal(t, "secret", token.Password)
+}
+
+func TestMatcher_CachesUntilNearExpiry(t *testing.T) {
+	provider := &stubProvider{name: "gar", token: &Token{Password: "first", ExpiresAt: time.Now().Add(time.Hour)}}
+	matcher := NewMatcher()
+	matcher.Register("gcr.io", provider)
+
+	_, _, err := matcher.Token(context.Background(), "gcr.io")
+	require.NoError(t, err)
+	_, _, err = matcher.Token(context.Background(), "gcr.io")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, provider.callCount)
+}
+
+func TestMatcher_RefreshesWhenNearExpiry(t *testing.T) {
+	provider := &stubProvider{name: "gar", token: &Token{Password: "first", ExpiresAt: time.Now().Add(refreshSkew / 2)}}
+	matcher := NewMatcher()
+	matcher.Register("gcr.io", provider)
+
+	_, _, err := matcher.Token(context.Background(), "gcr.io")
+	require.NoError(t, err)
+	_, _, err = matcher.Token(context.Background(), "gcr.io")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, provider.callCount)
+}
+
+func TestMatcher_FirstRegisteredPatternWins(t *testing.T) {
+	first := &stubProvider{name: "first", token: &Token{Password: "first-secret", ExpiresAt: time.Now().Add(time.Hour)}}
+	second := &stubProvider{name: "second", token: &Token{Password: "second-secret", ExpiresAt: time.Now().Add(time.Hour)}}
+
+	matcher := NewMatcher()
+	matcher.Register("*.azurecr.io", first)
+	matcher.Register("registry.azurecr.io", second)
+
+	token, matched, err := matcher.Token(context.Background(), "registry.azurecr.io")
+	require.NoError(t, err)
+	assert.True(t, matched)
+	assert.Equal(t, "first-secret", token.Password)
+}
+
+func TestSignSigV4_SetsAuthorizationHeader(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "https://ecr.us-east-1.amazonaws.com/", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken")
+
+	creds := &stsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", SessionToken: "session"}
+	err = signSigV4(req, []byte("{}"), creds, "us-east-1", "ecr")
+	require.NoError(t, err)
+
+	auth := req.Header.Get("Authorization")
+	assert.Contains(t, auth, "Credential=AKIDEXAMPLE/")
+	assert.Contains(t, auth, "/us-east-1/ecr/aws4_request")
+	assert.Contains(t, auth, "SignedHeaders=")
+	assert.NotEmpty(t, req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "session", req.Header.Get("X-Amz-Security-Token"))
+}
+
+func TestEcrRegion_ParsesRegionFromHost(t *testing.T) {
+	region, err := ecrRegion("123456789.dkr.ecr.eu-west-1.amazonaws.com")
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", region)
+}
+
+func TestEcrRegion_RejectsNonECRHost(t *testing.T) {
+	_, err := ecrRegion("gcr.io")
+	assert.Error(t, err)
+}
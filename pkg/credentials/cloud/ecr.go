@@ -0,0 +1,305 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ec2IMDSTokenEndpoint and ec2IMDSCredentialsEndpoint are EC2's Instance
+// Metadata Service v2 endpoints used to resolve the credentials of the IAM
+// role attached to the instance/task the controller runs on, when no IRSA
+// web identity is configured.
+const (
+	ec2IMDSTokenEndpoint       = "http://169.254.169.254/latest/api/token"
+	ec2IMDSCredentialsEndpoint = "http://169.254.169.254/latest/meta-data/iam/security-credentials/"
+)
+
+// ECRProvider implements Provider for Amazon ECR. It prefers IRSA (IAM Roles
+// for Service Accounts) when the pod is configured for it: exchanging the
+// pod's projected service account token for temporary IAM credentials via
+// STS AssumeRoleWithWebIdentity. When that's not configured, it falls back
+// to the same default credential chain the AWS SDKs use: static credentials
+// from AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY, then the IAM role attached
+// to the instance/task the controller runs on via IMDSv2. Either way, the
+// resulting credentials call ecr:GetAuthorizationToken, SigV4-signed, to get
+// a short-lived registry password.
+//
+// For IRSA it reads the same environment variables the EKS pod identity
+// webhook injects: AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE.
+type ECRProvider struct {
+	httpClient *http.Client
+}
+
+// NewECRProvider returns an ECRProvider using a default HTTP client.
+func NewECRProvider() *ECRProvider {
+	return &ECRProvider{httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *ECRProvider) Name() string { return "ecr" }
+
+func (p *ECRProvider) GetToken(ctx context.Context, registry string) (*Token, error) {
+	region, err := ecrRegion(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := p.resolveCredentials(ctx, region)
+	if err != nil {
+		return nil, fmt.Errorf("resolving AWS credentials: %w", err)
+	}
+
+	return p.getAuthorizationToken(ctx, region, creds)
+}
+
+// resolveCredentials assumes the IRSA role when the pod is configured for
+// it, falling back to the AWS SDK's own default credential chain (static
+// environment credentials, then the controller's ambient instance/task
+// role) otherwise.
+func (p *ECRProvider) resolveCredentials(ctx context.Context, region string) (*stsCredentials, error) {
+	roleARN := os.Getenv("AWS_ROLE_ARN")
+	tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	if roleARN == "" || tokenFile == "" {
+		return p.defaultCredentials(ctx)
+	}
+
+	return p.assumeRoleWithWebIdentity(ctx, region)
+}
+
+// defaultCredentials mirrors the AWS SDK default credential provider chain:
+// static environment credentials first, then the IAM role attached to the
+// instance/task the controller runs on via IMDSv2.
+func (p *ECRProvider) defaultCredentials(ctx context.Context) (*stsCredentials, error) {
+	if accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID"); accessKeyID != "" {
+		if secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY"); secretAccessKey != "" {
+			return &stsCredentials{
+				AccessKeyID:     accessKeyID,
+				SecretAccessKey: secretAccessKey,
+				SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			}, nil
+		}
+	}
+
+	return p.instanceProfileCredentials(ctx)
+}
+
+// instanceProfileCredentials resolves credentials for the IAM instance/task
+// role attached to wherever the controller runs, via EC2's IMDSv2: a
+// session token, the attached role's name, then that role's credentials.
+func (p *ECRProvider) instanceProfileCredentials(ctx context.Context) (*stsCredentials, error) {
+	token, err := p.imdsSessionToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching imdsv2 session token: %w", err)
+	}
+
+	roleName, err := p.imdsGet(ctx, ec2IMDSCredentialsEndpoint, token)
+	if err != nil {
+		return nil, fmt.Errorf("listing instance profile role: %w", err)
+	}
+
+	credsJSON, err := p.imdsGet(ctx, ec2IMDSCredentialsEndpoint+strings.TrimSpace(roleName), token)
+	if err != nil {
+		return nil, fmt.Errorf("fetching instance profile credentials: %w", err)
+	}
+
+	var parsed struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		Token           string `json:"Token"`
+	}
+	if err := json.Unmarshal([]byte(credsJSON), &parsed); err != nil {
+		return nil, fmt.Errorf("decoding instance profile credentials: %w", err)
+	}
+
+	return &stsCredentials{
+		AccessKeyID:     parsed.AccessKeyID,
+		SecretAccessKey: parsed.SecretAccessKey,
+		SessionToken:    parsed.Token,
+	}, nil
+}
+
+func (p *ECRProvider) imdsSessionToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, ec2IMDSTokenEndpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("querying ec2 instance metadata service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("imdsv2 token endpoint returned status %d: is the controller running on EC2/ECS?", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func (p *ECRProvider) imdsGet(ctx context.Context, endpoint, sessionToken string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", sessionToken)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("imds returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// ecrRegion extracts the region from a "<account>.dkr.ecr.<region>.amazonaws.com" host.
+func ecrRegion(registry string) (string, error) {
+	parts := strings.Split(registry, ".")
+	for i, part := range parts {
+		if part == "ecr" && i+1 < len(parts) {
+			return parts[i+1], nil
+		}
+	}
+	return "", fmt.Errorf("registry %q is not an ECR host", registry)
+}
+
+type stsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+func (p *ECRProvider) assumeRoleWithWebIdentity(ctx context.Context, region string) (*stsCredentials, error) {
+	roleARN := os.Getenv("AWS_ROLE_ARN")
+	tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	if roleARN == "" || tokenFile == "" {
+		return nil, fmt.Errorf("AWS_ROLE_ARN/AWS_WEB_IDENTITY_TOKEN_FILE not set: is the pod annotated for IRSA?")
+	}
+
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading projected token: %w", err)
+	}
+
+	query := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {"archy-webhook"},
+		"WebIdentityToken": {string(token)},
+		"DurationSeconds":  {"3600"},
+	}
+
+	endpoint := fmt.Sprintf("https://sts.%s.amazonaws.com/?%s", region, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sts returned status %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		Result struct {
+			Credentials struct {
+				AccessKeyId     string `xml:"AccessKeyId"`
+				SecretAccessKey string `xml:"SecretAccessKey"`
+				SessionToken    string `xml:"SessionToken"`
+			} `xml:"Credentials"`
+		} `xml:"AssumeRoleWithWebIdentityResult"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("decoding sts response: %w", err)
+	}
+
+	return &stsCredentials{
+		AccessKeyID:     parsed.Result.Credentials.AccessKeyId,
+		SecretAccessKey: parsed.Result.Credentials.SecretAccessKey,
+		SessionToken:    parsed.Result.Credentials.SessionToken,
+	}, nil
+}
+
+func (p *ECRProvider) getAuthorizationToken(ctx context.Context, region string, creds *stsCredentials) (*Token, error) {
+	endpoint := fmt.Sprintf("https://ecr.%s.amazonaws.com/", region)
+	body := []byte("{}")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken")
+
+	if err := signSigV4(req, body, creds, region, "ecr"); err != nil {
+		return nil, fmt.Errorf("signing request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ecr returned status %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		AuthorizationData []struct {
+			AuthorizationToken string  `json:"authorizationToken"`
+			ExpiresAt          float64 `json:"expiresAt"`
+		} `json:"authorizationData"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("decoding ecr response: %w", err)
+	}
+	if len(parsed.AuthorizationData) == 0 {
+		return nil, fmt.Errorf("ecr returned no authorization data")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(parsed.AuthorizationData[0].AuthorizationToken)
+	if err != nil {
+		return nil, fmt.Errorf("decoding authorization token: %w", err)
+	}
+
+	userPass := strings.SplitN(string(decoded), ":", 2)
+	if len(userPass) != 2 {
+		return nil, fmt.Errorf("malformed ecr authorization token")
+	}
+
+	return &Token{
+		Username:  userPass[0],
+		Password:  userPass[1],
+		ExpiresAt: time.Unix(int64(parsed.AuthorizationData[0].ExpiresAt), 0),
+	}, nil
+}
@@ -0,0 +1,190 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// gcpMetadataTokenEndpoint is the GCE metadata server endpoint for the
+// access token of whichever service account is attached to the instance the
+// controller runs on.
+const gcpMetadataTokenEndpoint = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// GARProvider implements Provider for Google Artifact Registry / Container
+// Registry. It prefers GCP Workload Identity Federation when the pod is
+// configured for it: exchanging the pod's projected service account token
+// for a federated GCP access token via GCP STS. When that's not configured,
+// it falls back to the controller's own ambient identity via the GCE
+// metadata server (the same mechanism google.FindDefaultCredentials uses on
+// GKE/GCE). Either way, when GCP_SERVICE_ACCOUNT_EMAIL is set, the resulting
+// token is exchanged for an impersonated service account token via IAM
+// Credentials generateAccessToken. The final token is used as the password
+// for the "oauth2accesstoken" registry user.
+//
+// For workload identity federation it reads GCP_WORKLOAD_IDENTITY_PROVIDER
+// (the full resource name of the workload identity pool provider) and
+// GCP_TOKEN_FILE (the path to the projected service account token).
+type GARProvider struct {
+	httpClient *http.Client
+}
+
+// NewGARProvider returns a GARProvider using a default HTTP client.
+func NewGARProvider() *GARProvider {
+	return &GARProvider{httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *GARProvider) Name() string { return "gar" }
+
+func (p *GARProvider) GetToken(ctx context.Context, registry string) (*Token, error) {
+	accessToken, expiresIn, err := p.accessToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquiring gcp access token: %w", err)
+	}
+
+	if serviceAccount := os.Getenv("GCP_SERVICE_ACCOUNT_EMAIL"); serviceAccount != "" {
+		accessToken, expiresIn, err = p.impersonate(ctx, accessToken, serviceAccount)
+		if err != nil {
+			return nil, fmt.Errorf("impersonating %s: %w", serviceAccount, err)
+		}
+	}
+
+	return &Token{
+		Username:  "oauth2accesstoken",
+		Password:  accessToken,
+		ExpiresAt: time.Now().Add(expiresIn),
+	}, nil
+}
+
+// accessToken exchanges the pod's workload identity federation token when
+// configured, falling back to the controller's ambient GCE metadata server
+// identity otherwise.
+func (p *GARProvider) accessToken(ctx context.Context) (string, time.Duration, error) {
+	provider := os.Getenv("GCP_WORKLOAD_IDENTITY_PROVIDER")
+	tokenFile := os.Getenv("GCP_TOKEN_FILE")
+	if provider == "" || tokenFile == "" {
+		return p.metadataServerToken(ctx)
+	}
+
+	return p.exchangeToken(ctx, provider, tokenFile)
+}
+
+func (p *GARProvider) metadataServerToken(ctx context.Context) (string, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataTokenEndpoint, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("querying gcp metadata server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("gcp metadata server returned status %d: is the controller running with a service account attached?", resp.StatusCode)
+	}
+
+	var parsed struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", 0, fmt.Errorf("decoding gcp metadata server response: %w", err)
+	}
+
+	return parsed.AccessToken, time.Duration(parsed.ExpiresIn) * time.Second, nil
+}
+
+func (p *GARProvider) exchangeToken(ctx context.Context, provider, tokenFile string) (string, time.Duration, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", 0, fmt.Errorf("reading projected token: %w", err)
+	}
+
+	reqBody, err := json.Marshal(map[string]string{
+		"audience":           provider,
+		"grantType":          "urn:ietf:params:oauth:grant-type:token-exchange",
+		"requestedTokenType": "urn:ietf:params:oauth:token-type:access_token",
+		"subjectTokenType":   "urn:ietf:params:oauth:token-type:jwt",
+		"subjectToken":       string(token),
+		"scope":              "https://www.googleapis.com/auth/cloud-platform",
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://sts.googleapis.com/v1/token", bytes.NewReader(reqBody))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("gcp sts returned status %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", 0, fmt.Errorf("decoding gcp sts response: %w", err)
+	}
+
+	return parsed.AccessToken, time.Duration(parsed.ExpiresIn) * time.Second, nil
+}
+
+func (p *GARProvider) impersonate(ctx context.Context, federatedToken, serviceAccount string) (string, time.Duration, error) {
+	endpoint := fmt.Sprintf("https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:generateAccessToken", serviceAccount)
+
+	reqBody, err := json.Marshal(map[string][]string{
+		"scope": {"https://www.googleapis.com/auth/cloud-platform"},
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+federatedToken)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("iam credentials returned status %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		AccessToken string `json:"accessToken"`
+		ExpireTime  string `json:"expireTime"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", 0, fmt.Errorf("decoding iam credentials response: %w", err)
+	}
+
+	expiry, err := time.Parse(time.RFC3339, parsed.ExpireTime)
+	if err != nil {
+		return parsed.AccessToken, time.Hour, nil
+	}
+
+	return parsed.AccessToken, time.Until(expiry), nil
+}
@@ -0,0 +1,222 @@
+package inspector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lsdopen/archy/pkg/types"
+)
+
+// LocalClient implements types.RegistryClient by asking the container
+// runtime already running on this node whether it has image, instead of
+// pulling a manifest from a remote registry. It supports two runtime
+// endpoints:
+//
+//   - a Docker daemon UNIX socket ("unix:///var/run/dockershim.sock"),
+//     queried directly over the daemon's HTTP API, and
+//   - a containerd CRI image service UNIX socket
+//     ("unix:///run/containerd/containerd.sock"), queried via the crictl
+//     CLI, since this tree does not vendor a CRI gRPC client.
+//
+// Because the runtime only reports the image it already has on disk,
+// GetSupportedArchitectures here returns at most the single architecture of
+// the node it's running on, never the full set a remote manifest list
+// would advertise. A miss (the image hasn't been pulled here, or the
+// runtime can't be reached) returns an error so callers can fall back to a
+// remote types.RegistryClient, e.g. via ChainedClient.
+type LocalClient struct {
+	endpoint   string
+	runtime    string // "docker" or "cri"
+	httpClient *http.Client
+	crictlPath string
+	runCommand func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// NewLocalClient creates a LocalClient for endpoint, a UNIX socket URL such
+// as "unix:///run/containerd/containerd.sock" or
+// "unix:///var/run/dockershim.sock". The runtime kind is inferred from the
+// socket's file name.
+func NewLocalClient(endpoint string) (*LocalClient, error) {
+	socketPath, err := socketPathFromEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, err := runtimeKind(socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &LocalClient{
+		endpoint:   endpoint,
+		runtime:    kind,
+		crictlPath: "crictl",
+		runCommand: runCommandOutput,
+	}
+
+	if kind == "docker" {
+		c.httpClient = &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		}
+	}
+
+	return c, nil
+}
+
+func socketPathFromEndpoint(endpoint string) (string, error) {
+	const prefix = "unix://"
+	if !strings.HasPrefix(endpoint, prefix) {
+		return "", fmt.Errorf("unsupported runtime endpoint %q: expected a unix:// socket", endpoint)
+	}
+	return strings.TrimPrefix(endpoint, prefix), nil
+}
+
+func runtimeKind(socketPath string) (string, error) {
+	base := filepath.Base(socketPath)
+	switch {
+	case strings.Contains(base, "dockershim") || strings.Contains(base, "docker"):
+		return "docker", nil
+	case strings.Contains(base, "containerd") || strings.Contains(base, "cri"):
+		return "cri", nil
+	default:
+		return "", fmt.Errorf("cannot determine runtime kind for socket %q", socketPath)
+	}
+}
+
+// GetSupportedArchitectures reports the architecture of image as already
+// present in the local runtime's image store.
+func (c *LocalClient) GetSupportedArchitectures(ctx context.Context, image string) ([]string, error) {
+	switch c.runtime {
+	case "docker":
+		return c.inspectDocker(ctx, image)
+	case "cri":
+		return c.inspectCRI(ctx, image)
+	default:
+		return nil, fmt.Errorf("unsupported runtime kind %q", c.runtime)
+	}
+}
+
+// dockerImageInspect is the subset of the Docker Engine API's
+// "GET /images/{name}/json" response this client needs.
+type dockerImageInspect struct {
+	Architecture string `json:"Architecture"`
+}
+
+func (c *LocalClient) inspectDocker(ctx context.Context, image string) ([]string, error) {
+	url := fmt.Sprintf("http://unix/images/%s/json", image)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building docker inspect request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("querying docker daemon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("image %q not present on this node", image)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("docker daemon returned status %d", resp.StatusCode)
+	}
+
+	var inspect dockerImageInspect
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return nil, fmt.Errorf("decoding docker inspect response: %w", err)
+	}
+	if inspect.Architecture == "" {
+		return nil, fmt.Errorf("docker inspect response for %q did not include an architecture", image)
+	}
+
+	return []string{inspect.Architecture}, nil
+}
+
+// crictlInspect is the subset of `crictl -r <endpoint> inspecti -o json
+// <image>` this client needs: the image's architecture, as reported by the
+// containerd CRI image service.
+type crictlInspect struct {
+	Status struct {
+		Spec struct {
+			Architecture string `json:"architecture"`
+		} `json:"spec"`
+	} `json:"status"`
+}
+
+func (c *LocalClient) inspectCRI(ctx context.Context, image string) ([]string, error) {
+	out, err := c.runCommand(ctx, c.crictlPath, "-r", c.endpoint, "inspecti", "-o", "json", image)
+	if err != nil {
+		return nil, fmt.Errorf("querying containerd via crictl: %w", err)
+	}
+
+	var inspect crictlInspect
+	if err := json.Unmarshal(out, &inspect); err != nil {
+		return nil, fmt.Errorf("decoding crictl output: %w", err)
+	}
+	if inspect.Status.Spec.Architecture == "" {
+		return nil, fmt.Errorf("image %q not present on this node", image)
+	}
+
+	return []string{inspect.Status.Spec.Architecture}, nil
+}
+
+func runCommandOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// ChainedClient tries a sequence of types.RegistryClient sources in order
+// and returns the first one that reports at least one supported
+// architecture. This lets operators put a fast local runtime lookup ahead
+// of a remote registry pull without the webhook needing to know which
+// sources are configured.
+type ChainedClient struct {
+	sources []types.RegistryClient
+}
+
+// NewChainedClient creates a ChainedClient that tries sources in the given
+// order, stopping at the first one that succeeds.
+func NewChainedClient(sources ...types.RegistryClient) *ChainedClient {
+	return &ChainedClient{sources: sources}
+}
+
+// GetSupportedArchitectures queries each source in order, returning the
+// first non-empty result. If every source errors or returns no
+// architectures, it returns the last source's error.
+func (c *ChainedClient) GetSupportedArchitectures(ctx context.Context, image string) ([]string, error) {
+	var lastErr error
+	for _, source := range c.sources {
+		archs, err := source.GetSupportedArchitectures(ctx, image)
+		if err == nil && len(archs) > 0 {
+			return archs, nil
+		}
+		if err != nil {
+			lastErr = err
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no source reported supported architectures for %q", image)
+	}
+	return nil, lastErr
+}
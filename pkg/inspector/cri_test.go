@@ -0,0 +1,85 @@
+package inspector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRegistryClient struct {
+	archs []string
+	err   error
+}
+
+func (s *stubRegistryClient) GetSupportedArchitectures(ctx context.Context, image string) ([]string, error) {
+	return s.archs, s.err
+}
+
+func TestNewLocalClient_InfersRuntimeKindFromSocketName(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		wantKind string
+		wantErr  bool
+	}{
+		{"containerd socket", "unix:///run/containerd/containerd.sock", "cri", false},
+		{"dockershim socket", "unix:///var/run/dockershim.sock", "docker", false},
+		{"non-unix endpoint", "tcp://127.0.0.1:1234", "", true},
+		{"unrecognized socket name", "unix:///run/mystery.sock", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewLocalClient(tt.endpoint)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKind, client.runtime)
+		})
+	}
+}
+
+func TestChainedClient_ReturnsFirstNonEmptyResult(t *testing.T) {
+	local := &stubRegistryClient{err: errors.New("image not present on this node")}
+	remote := &stubRegistryClient{archs: []string{"amd64", "arm64"}}
+
+	chain := NewChainedClient(local, remote)
+
+	archs, err := chain.GetSupportedArchitectures(context.Background(), "nginx:latest")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"amd64", "arm64"}, archs)
+}
+
+func TestChainedClient_PrefersEarlierSourceOnSuccess(t *testing.T) {
+	local := &stubRegistryClient{archs: []string{"arm64"}}
+	remote := &stubRegistryClient{archs: []string{"amd64", "arm64"}}
+
+	chain := NewChainedClient(local, remote)
+
+	archs, err := chain.GetSupportedArchitectures(context.Background(), "nginx:latest")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arm64"}, archs)
+}
+
+func TestChainedClient_ReturnsLastErrorWhenAllSourcesFail(t *testing.T) {
+	first := &stubRegistryClient{err: errors.New("local runtime unreachable")}
+	second := &stubRegistryClient{err: errors.New("registry unreachable")}
+
+	chain := NewChainedClient(first, second)
+
+	_, err := chain.GetSupportedArchitectures(context.Background(), "nginx:latest")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "registry unreachable")
+}
+
+func TestChainedClient_NoSourcesReportsArchitectures(t *testing.T) {
+	chain := NewChainedClient()
+
+	_, err := chain.GetSupportedArchitectures(context.Background(), "nginx:latest")
+	require.Error(t, err)
+}
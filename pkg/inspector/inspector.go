@@ -3,14 +3,109 @@ package inspector
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
 
-// Platform represents a supported OS/Architecture pair
+// Platform represents an OCI image-index platform descriptor: the full
+// OS/architecture/variant combination a manifest targets, per
+// https://github.com/opencontainers/image-spec/blob/main/image-index.md.
 type Platform struct {
 	Architecture string
+	OS           string
+	OSVersion    string
+	OSFeatures   []string
+	Variant      string
+}
+
+// Matches reports whether p is compatible with a node's reported
+// kubernetes.io/arch, kubernetes.io/os, kubernetes.io/arch-variant,
+// node.kubernetes.io/windows-build and node.kubernetes.io/os-features
+// labels (nodeSelector), following the OCI platform matching rules:
+// architecture and OS must match exactly, variant matches when either side
+// is empty or they're equal, OS version matches on a Windows
+// major.minor.build prefix, and p's OS features must all be present in the
+// node's feature set. A label absent from nodeSelector is treated as "don't
+// care" rather than a mismatch.
+func (p Platform) Matches(nodeSelector map[string]string) bool {
+	if arch := nodeSelector["kubernetes.io/arch"]; arch != "" && arch != p.Architecture {
+		return false
+	}
+	if os := nodeSelector["kubernetes.io/os"]; os != "" && os != p.OS {
+		return false
+	}
+
+	if variant := nodeSelector["kubernetes.io/arch-variant"]; variant != "" && p.Variant != "" && variant != p.Variant {
+		return false
+	}
+
+	if build := nodeSelector["node.kubernetes.io/windows-build"]; build != "" && p.OSVersion != "" {
+		if !strings.HasPrefix(p.OSVersion, build) && !strings.HasPrefix(build, p.OSVersion) {
+			return false
+		}
+	}
+
+	if len(p.OSFeatures) > 0 {
+		nodeFeatures := make(map[string]bool)
+		for _, f := range strings.Split(nodeSelector["node.kubernetes.io/os-features"], ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				nodeFeatures[f] = true
+			}
+		}
+		for _, f := range p.OSFeatures {
+			if !nodeFeatures[f] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// CompatibleWith reports whether p and other describe platforms a single
+// container image could run on, following the same OCI platform matching
+// rules as Matches: architecture and OS must match exactly, variant matches
+// when either side is empty or they're equal, OS version matches on a
+// Windows major.minor.build prefix, and OS features required by either side
+// must be present on the other.
+func (p Platform) CompatibleWith(other Platform) bool {
+	if p.Architecture != other.Architecture {
+		return false
+	}
+	if p.OS != other.OS {
+		return false
+	}
+	if p.Variant != "" && other.Variant != "" && p.Variant != other.Variant {
+		return false
+	}
+	if p.OSVersion != "" && other.OSVersion != "" {
+		if !strings.HasPrefix(p.OSVersion, other.OSVersion) && !strings.HasPrefix(other.OSVersion, p.OSVersion) {
+			return false
+		}
+	}
+
+	pFeatures := make(map[string]bool, len(p.OSFeatures))
+	for _, f := range p.OSFeatures {
+		pFeatures[f] = true
+	}
+	for _, f := range other.OSFeatures {
+		if !pFeatures[f] {
+			return false
+		}
+	}
+	otherFeatures := make(map[string]bool, len(other.OSFeatures))
+	for _, f := range other.OSFeatures {
+		otherFeatures[f] = true
+	}
+	for _, f := range p.OSFeatures {
+		if !otherFeatures[f] {
+			return false
+		}
+	}
+
+	return true
 }
 
 // Inspector defines the interface for inspecting images
@@ -61,13 +156,18 @@ func (r *RegistryInspector) GetSupportedPlatforms(ctx context.Context, imageRef
 			if descriptor.Platform != nil {
 				platforms = append(platforms, Platform{
 					Architecture: descriptor.Platform.Architecture,
+					OS:           descriptor.Platform.OS,
+					OSVersion:    descriptor.Platform.OSVersion,
+					OSFeatures:   descriptor.Platform.OSFeatures,
+					Variant:      descriptor.Platform.Variant,
 				})
 			}
 		}
 		return platforms, nil
 	}
 
-	// If it's a single image manifest
+	// If it's a single image manifest, there's no index entry describing its
+	// platform, so read the same fields from the image's config file instead.
 	if desc.MediaType.IsImage() {
 		img, err := desc.Image()
 		if err != nil {
@@ -79,6 +179,10 @@ func (r *RegistryInspector) GetSupportedPlatforms(ctx context.Context, imageRef
 		}
 		return []Platform{{
 			Architecture: cfg.Architecture,
+			OS:           cfg.OS,
+			OSVersion:    cfg.OSVersion,
+			OSFeatures:   cfg.OSFeatures,
+			Variant:      cfg.Variant,
 		}}, nil
 	}
 
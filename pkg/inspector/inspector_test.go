@@ -0,0 +1,159 @@
+package inspector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatform_Matches(t *testing.T) {
+	tests := []struct {
+		name         string
+		platform     Platform
+		nodeSelector map[string]string
+		want         bool
+	}{
+		{
+			name:         "exact arch and os match",
+			platform:     Platform{Architecture: "arm64", OS: "linux"},
+			nodeSelector: map[string]string{"kubernetes.io/arch": "arm64", "kubernetes.io/os": "linux"},
+			want:         true,
+		},
+		{
+			name:         "arch mismatch",
+			platform:     Platform{Architecture: "amd64", OS: "linux"},
+			nodeSelector: map[string]string{"kubernetes.io/arch": "arm64", "kubernetes.io/os": "linux"},
+			want:         false,
+		},
+		{
+			name:         "os mismatch",
+			platform:     Platform{Architecture: "amd64", OS: "linux"},
+			nodeSelector: map[string]string{"kubernetes.io/arch": "amd64", "kubernetes.io/os": "windows"},
+			want:         false,
+		},
+		{
+			name:         "empty nodeSelector matches anything",
+			platform:     Platform{Architecture: "arm64", OS: "linux", Variant: "v7"},
+			nodeSelector: map[string]string{},
+			want:         true,
+		},
+		{
+			name:         "variant matches when platform variant is empty",
+			platform:     Platform{Architecture: "arm", OS: "linux"},
+			nodeSelector: map[string]string{"kubernetes.io/arch": "arm", "kubernetes.io/arch-variant": "v7"},
+			want:         true,
+		},
+		{
+			name:         "variant mismatch when both sides set",
+			platform:     Platform{Architecture: "arm", OS: "linux", Variant: "v6"},
+			nodeSelector: map[string]string{"kubernetes.io/arch": "arm", "kubernetes.io/arch-variant": "v7"},
+			want:         false,
+		},
+		{
+			name:         "variant match when both sides set and equal",
+			platform:     Platform{Architecture: "arm", OS: "linux", Variant: "v7"},
+			nodeSelector: map[string]string{"kubernetes.io/arch": "arm", "kubernetes.io/arch-variant": "v7"},
+			want:         true,
+		},
+		{
+			name:         "windows build prefix match",
+			platform:     Platform{Architecture: "amd64", OS: "windows", OSVersion: "10.0.17763.1"},
+			nodeSelector: map[string]string{"kubernetes.io/os": "windows", "node.kubernetes.io/windows-build": "10.0.17763"},
+			want:         true,
+		},
+		{
+			name:         "windows build mismatch",
+			platform:     Platform{Architecture: "amd64", OS: "windows", OSVersion: "10.0.17763.1"},
+			nodeSelector: map[string]string{"kubernetes.io/os": "windows", "node.kubernetes.io/windows-build": "10.0.14393"},
+			want:         false,
+		},
+		{
+			name:         "os features subset satisfied",
+			platform:     Platform{Architecture: "amd64", OS: "linux", OSFeatures: []string{"sse4"}},
+			nodeSelector: map[string]string{"node.kubernetes.io/os-features": "sse4,avx2"},
+			want:         true,
+		},
+		{
+			name:         "os features missing on node",
+			platform:     Platform{Architecture: "amd64", OS: "linux", OSFeatures: []string{"avx512"}},
+			nodeSelector: map[string]string{"node.kubernetes.io/os-features": "sse4,avx2"},
+			want:         false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.platform.Matches(tt.nodeSelector))
+		})
+	}
+}
+
+func TestPlatform_CompatibleWith(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Platform
+		b    Platform
+		want bool
+	}{
+		{
+			name: "exact arch and os match",
+			a:    Platform{Architecture: "arm64", OS: "linux"},
+			b:    Platform{Architecture: "arm64", OS: "linux"},
+			want: true,
+		},
+		{
+			name: "arch mismatch",
+			a:    Platform{Architecture: "amd64", OS: "linux"},
+			b:    Platform{Architecture: "arm64", OS: "linux"},
+			want: false,
+		},
+		{
+			name: "os mismatch",
+			a:    Platform{Architecture: "amd64", OS: "linux"},
+			b:    Platform{Architecture: "amd64", OS: "windows"},
+			want: false,
+		},
+		{
+			name: "variant matches when either side empty",
+			a:    Platform{Architecture: "arm", OS: "linux", Variant: "v7"},
+			b:    Platform{Architecture: "arm", OS: "linux"},
+			want: true,
+		},
+		{
+			name: "variant mismatch when both sides set",
+			a:    Platform{Architecture: "arm", OS: "linux", Variant: "v6"},
+			b:    Platform{Architecture: "arm", OS: "linux", Variant: "v7"},
+			want: false,
+		},
+		{
+			name: "windows build prefix match",
+			a:    Platform{Architecture: "amd64", OS: "windows", OSVersion: "10.0.17763.1"},
+			b:    Platform{Architecture: "amd64", OS: "windows", OSVersion: "10.0.17763"},
+			want: true,
+		},
+		{
+			name: "windows build mismatch",
+			a:    Platform{Architecture: "amd64", OS: "windows", OSVersion: "10.0.17763.1"},
+			b:    Platform{Architecture: "amd64", OS: "windows", OSVersion: "10.0.14393"},
+			want: false,
+		},
+		{
+			name: "os features must match on both sides",
+			a:    Platform{Architecture: "amd64", OS: "linux", OSFeatures: []string{"sse4"}},
+			b:    Platform{Architecture: "amd64", OS: "linux", OSFeatures: []string{"sse4", "avx2"}},
+			want: false,
+		},
+		{
+			name: "identical os features",
+			a:    Platform{Architecture: "amd64", OS: "linux", OSFeatures: []string{"sse4"}},
+			b:    Platform{Architecture: "amd64", OS: "linux", OSFeatures: []string{"sse4"}},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.a.CompatibleWith(tt.b))
+		})
+	}
+}
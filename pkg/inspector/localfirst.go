@@ -0,0 +1,42 @@
+package inspector
+
+import (
+	"context"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/lsdopen/archy/pkg/types"
+)
+
+// LocalFirstInspector checks a local container runtime for an image before
+// falling back to remote, so an image the node has already pulled doesn't
+// need a registry round-trip just to learn its architecture. local is
+// typically a ChainedClient wrapping one or more LocalClient endpoints.
+//
+// Because a runtime only reports the architecture of the image it already
+// has on disk (never a full OCI platform descriptor), a local hit is
+// reported as a single Platform per architecture with OS "linux" assumed;
+// anything the runtime can't answer for falls through to remote's full
+// platform list.
+type LocalFirstInspector struct {
+	local  types.RegistryClient
+	remote Inspector
+}
+
+// NewLocalFirstInspector creates a LocalFirstInspector that tries local
+// first and falls back to remote for any image local can't answer for.
+func NewLocalFirstInspector(local types.RegistryClient, remote Inspector) *LocalFirstInspector {
+	return &LocalFirstInspector{local: local, remote: remote}
+}
+
+// GetSupportedPlatforms implements Inspector.
+func (i *LocalFirstInspector) GetSupportedPlatforms(ctx context.Context, imageRef string, opts ...remote.Option) ([]Platform, error) {
+	if archs, err := i.local.GetSupportedArchitectures(ctx, imageRef); err == nil && len(archs) > 0 {
+		platforms := make([]Platform, len(archs))
+		for idx, arch := range archs {
+			platforms[idx] = Platform{Architecture: arch, OS: "linux"}
+		}
+		return platforms, nil
+	}
+
+	return i.remote.GetSupportedPlatforms(ctx, imageRef, opts...)
+}
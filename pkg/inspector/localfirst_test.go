@@ -0,0 +1,52 @@
+package inspector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubInspector struct {
+	platforms []Platform
+	err       error
+}
+
+func (s *stubInspector) GetSupportedPlatforms(ctx context.Context, imageRef string, opts ...remote.Option) ([]Platform, error) {
+	return s.platforms, s.err
+}
+
+func TestLocalFirstInspector_PrefersLocalHit(t *testing.T) {
+	local := &stubRegistryClient{archs: []string{"arm64"}}
+	remoteInspector := &stubInspector{err: errors.New("remote should not be called")}
+
+	i := NewLocalFirstInspector(local, remoteInspector)
+	platforms, err := i.GetSupportedPlatforms(context.Background(), "example.com/image:tag")
+	require.NoError(t, err)
+	assert.Equal(t, []Platform{{Architecture: "arm64", OS: "linux"}}, platforms)
+}
+
+func TestLocalFirstInspector_FallsBackToRemoteOnLocalMiss(t *testing.T) {
+	local := &stubRegistryClient{err: errors.New("not present on this node")}
+	want := []Platform{{Architecture: "amd64", OS: "linux"}, {Architecture: "arm64", OS: "linux"}}
+	remoteInspector := &stubInspector{platforms: want}
+
+	i := NewLocalFirstInspector(local, remoteInspector)
+	platforms, err := i.GetSupportedPlatforms(context.Background(), "example.com/image:tag")
+	require.NoError(t, err)
+	assert.Equal(t, want, platforms)
+}
+
+func TestLocalFirstInspector_FallsBackToRemoteOnEmptyLocalResult(t *testing.T) {
+	local := &stubRegistryClient{archs: nil}
+	want := []Platform{{Architecture: "amd64", OS: "linux"}}
+	remoteInspector := &stubInspector{platforms: want}
+
+	i := NewLocalFirstInspector(local, remoteInspector)
+	platforms, err := i.GetSupportedPlatforms(context.Background(), "example.com/image:tag")
+	require.NoError(t, err)
+	assert.Equal(t, want, platforms)
+}
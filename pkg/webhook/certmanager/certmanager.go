@@ -0,0 +1,524 @@
+// Package certmanager bootstraps and rotates the TLS certificate the
+// admission webhook serves, and keeps a MutatingWebhookConfiguration's
+// caBundle in sync with it, so a fresh cluster doesn't require an operator
+// to pre-provision certFile/keyFile or caBundle by hand. It can either
+// generate and rotate a self-signed CA/leaf pair itself, or delegate to an
+// installed cert-manager by creating a Certificate resource and watching
+// the Secret it populates.
+package certmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// defaultCAValidity and defaultLeafValidity size the self-signed
+// certificate chain Manager generates when no external cert-manager issuer
+// is configured: a long-lived CA the caBundle points at, and a leaf rotated
+// well before either it or the CA expires.
+const (
+	defaultCAValidity   = 365 * 24 * time.Hour
+	defaultLeafValidity = 90 * 24 * time.Hour
+)
+
+// rotateAtFraction is how far into a leaf certificate's validity window
+// Manager schedules its replacement, mirroring the 2/3-lifetime convention
+// Kubernetes' own certificate rotation follows.
+const rotateAtFraction = 2.0 / 3.0
+
+const (
+	certManagerSecretPollInterval = 2 * time.Second
+	certManagerSecretWaitTimeout  = 2 * time.Minute
+	certManagerWatchInterval      = 30 * time.Second
+)
+
+// certificatesGVR identifies cert-manager.io's Certificate CRD. A dynamic
+// client is used against it instead of cert-manager's own generated
+// clientset, so this tree doesn't need cert-manager as a compile-time
+// dependency to talk to one installed in the cluster.
+var certificatesGVR = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+
+// Config configures Manager.
+type Config struct {
+	// ServiceName and Namespace identify the Service fronting the webhook;
+	// they're used to compute both the SANs self-signed leaf certs need
+	// (<service>.<namespace>.svc and <service>.<namespace>.svc.cluster.local)
+	// and the name of the cert-manager Certificate/Secret.
+	ServiceName string
+	Namespace   string
+
+	// WebhookConfigName is the MutatingWebhookConfiguration whose
+	// webhooks[].clientConfig.caBundle is kept in sync with the CA
+	// certificate.
+	WebhookConfigName string
+
+	// CertDir is where tls.crt/tls.key are written. It should be the same
+	// path NewServer's certPath/keyPath point at, so the dynamic reloader
+	// already in internal/webhook.Server picks up every rotation.
+	CertDir string
+
+	// Issuer, when set, names a cert-manager.io Issuer: a Certificate
+	// resource is created referencing it and the resulting Secret is
+	// watched, instead of self-signing.
+	Issuer string
+
+	// CAValidity and LeafValidity override the self-signed defaults; zero
+	// values fall back to defaultCAValidity/defaultLeafValidity. Unused
+	// when Issuer is set, since cert-manager owns the issued validity.
+	CAValidity   time.Duration
+	LeafValidity time.Duration
+}
+
+// Manager bootstraps the webhook's serving certificate and keeps it, and
+// the MutatingWebhookConfiguration's caBundle, up to date for as long as it
+// runs. Call Start once at startup and Stop on shutdown.
+type Manager struct {
+	client  kubernetes.Interface
+	dynamic dynamic.Interface
+	cfg     Config
+
+	mu           sync.Mutex
+	ca           *x509.Certificate
+	caKey        *rsa.PrivateKey
+	nextRotation time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewManager returns a Manager that bootstraps and rotates the webhook
+// certificate per cfg. dynamicClient is only consulted when cfg.Issuer is
+// set, to create and read the cert-manager.io Certificate and its Secret.
+func NewManager(client kubernetes.Interface, dynamicClient dynamic.Interface, cfg Config) *Manager {
+	if cfg.CAValidity == 0 {
+		cfg.CAValidity = defaultCAValidity
+	}
+	if cfg.LeafValidity == 0 {
+		cfg.LeafValidity = defaultLeafValidity
+	}
+
+	return &Manager{
+		client:  client,
+		dynamic: dynamicClient,
+		cfg:     cfg,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start bootstraps the certificate — generating and writing a self-signed
+// chain, or creating/waiting on a cert-manager Certificate — patches the
+// MutatingWebhookConfiguration's caBundle, and launches a background
+// goroutine that keeps both current: rotating the self-signed chain on
+// schedule, or polling the cert-manager Secret for the renewals
+// cert-manager itself performs. It returns once the initial certificate is
+// in place.
+func (m *Manager) Start(ctx context.Context) error {
+	if m.cfg.Issuer != "" {
+		return m.startCertManager(ctx)
+	}
+	return m.startSelfSigned(ctx)
+}
+
+// Stop halts the background goroutine started by Start and waits for it to
+// exit.
+func (m *Manager) Stop() {
+	select {
+	case <-m.stop:
+		// already stopped
+	default:
+		close(m.stop)
+	}
+	<-m.done
+}
+
+func serviceSANs(serviceName, namespace string) []string {
+	return []string{
+		fmt.Sprintf("%s.%s.svc", serviceName, namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", serviceName, namespace),
+	}
+}
+
+func writeCertFiles(dir string, certPEM, keyPEM []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating cert directory %s: %w", dir, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tls.crt"), certPEM, 0o644); err != nil {
+		return fmt.Errorf("writing tls.crt: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tls.key"), keyPEM, 0o600); err != nil {
+		return fmt.Errorf("writing tls.key: %w", err)
+	}
+	return nil
+}
+
+// patchCABundle sets caPEM as the caBundle on every webhook entry of
+// cfg.WebhookConfigName, following the read-modify-write pattern the rest
+// of this tree uses for Kubernetes API updates.
+func (m *Manager) patchCABundle(ctx context.Context, caPEM []byte) error {
+	webhookConfigs := m.client.AdmissionregistrationV1().MutatingWebhookConfigurations()
+
+	whc, err := webhookConfigs.Get(ctx, m.cfg.WebhookConfigName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting MutatingWebhookConfiguration %s: %w", m.cfg.WebhookConfigName, err)
+	}
+
+	for i := range whc.Webhooks {
+		whc.Webhooks[i].ClientConfig.CABundle = caPEM
+	}
+
+	if _, err := webhookConfigs.Update(ctx, whc, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("updating caBundle on %s: %w", m.cfg.WebhookConfigName, err)
+	}
+
+	return nil
+}
+
+// --- self-signed path ---
+
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generating certificate serial: %w", err)
+	}
+	return serial, nil
+}
+
+func generateCA(validity time.Duration) (*x509.Certificate, *rsa.PrivateKey, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("generating CA key: %w", err)
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "archy-webhook-ca", Organization: []string{"archy"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("creating CA certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parsing CA certificate: %w", err)
+	}
+
+	return cert, key, der, nil
+}
+
+func generateLeaf(ca *x509.Certificate, caKey *rsa.PrivateKey, sans []string, validity time.Duration) (certPEM, keyPEM []byte, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating leaf key: %w", err)
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: sans[0]},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validity),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     sans,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating leaf certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshaling leaf key: %w", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+func (m *Manager) startSelfSigned(ctx context.Context) error {
+	caPEM, err := m.bootstrapSelfSigned()
+	if err != nil {
+		return err
+	}
+	if err := m.patchCABundle(ctx, caPEM); err != nil {
+		return err
+	}
+
+	go m.rotateSelfSigned(ctx)
+	return nil
+}
+
+func (m *Manager) bootstrapSelfSigned() ([]byte, error) {
+	ca, caKey, caDER, err := generateCA(m.cfg.CAValidity)
+	if err != nil {
+		return nil, err
+	}
+
+	certPEM, keyPEM, err := generateLeaf(ca, caKey, serviceSANs(m.cfg.ServiceName, m.cfg.Namespace), m.cfg.LeafValidity)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeCertFiles(m.cfg.CertDir, certPEM, keyPEM); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.ca = ca
+	m.caKey = caKey
+	m.nextRotation = time.Now().Add(time.Duration(float64(m.cfg.LeafValidity) * rotateAtFraction))
+	m.mu.Unlock()
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), nil
+}
+
+func (m *Manager) rotateSelfSigned(ctx context.Context) {
+	defer close(m.done)
+
+	for {
+		m.mu.Lock()
+		wait := time.Until(m.nextRotation)
+		m.mu.Unlock()
+		if wait < 0 {
+			wait = 0
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-m.stop:
+			timer.Stop()
+			return
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := m.rotateLeaf(ctx); err != nil {
+			log.Printf("certmanager: certificate rotation failed, retrying in a minute: %v", err)
+			m.mu.Lock()
+			m.nextRotation = time.Now().Add(time.Minute)
+			m.mu.Unlock()
+		}
+	}
+}
+
+// rotateLeaf issues a new leaf certificate, regenerating the CA first (and
+// re-patching the caBundle) if it's within one leaf lifetime of its own
+// expiry.
+func (m *Manager) rotateLeaf(ctx context.Context) error {
+	m.mu.Lock()
+	ca, caKey := m.ca, m.caKey
+	m.mu.Unlock()
+
+	var rotatedCAPEM []byte
+	if time.Until(ca.NotAfter) < m.cfg.LeafValidity {
+		newCA, newCAKey, caDER, err := generateCA(m.cfg.CAValidity)
+		if err != nil {
+			return err
+		}
+		ca, caKey = newCA, newCAKey
+		rotatedCAPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	}
+
+	certPEM, keyPEM, err := generateLeaf(ca, caKey, serviceSANs(m.cfg.ServiceName, m.cfg.Namespace), m.cfg.LeafValidity)
+	if err != nil {
+		return err
+	}
+	if err := writeCertFiles(m.cfg.CertDir, certPEM, keyPEM); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.ca = ca
+	m.caKey = caKey
+	m.nextRotation = time.Now().Add(time.Duration(float64(m.cfg.LeafValidity) * rotateAtFraction))
+	m.mu.Unlock()
+
+	if rotatedCAPEM != nil {
+		if err := m.patchCABundle(ctx, rotatedCAPEM); err != nil {
+			return fmt.Errorf("patching rotated CA into caBundle: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// --- cert-manager path ---
+
+func (m *Manager) startCertManager(ctx context.Context) error {
+	secretName := certManagerSecretName(m.cfg.ServiceName)
+
+	if err := m.ensureCertificate(ctx, secretName); err != nil {
+		return fmt.Errorf("creating cert-manager Certificate: %w", err)
+	}
+
+	caPEM, err := m.waitForCertManagerSecret(ctx, secretName)
+	if err != nil {
+		return err
+	}
+	if err := m.patchCABundle(ctx, caPEM); err != nil {
+		return err
+	}
+
+	go m.watchCertManagerSecret(ctx, secretName)
+	return nil
+}
+
+func certManagerSecretName(serviceName string) string {
+	return serviceName + "-webhook-tls"
+}
+
+func (m *Manager) ensureCertificate(ctx context.Context, secretName string) error {
+	sans := serviceSANs(m.cfg.ServiceName, m.cfg.Namespace)
+	dnsNames := make([]interface{}, len(sans))
+	for i, san := range sans {
+		dnsNames[i] = san
+	}
+
+	cert := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "cert-manager.io/v1",
+			"kind":       "Certificate",
+			"metadata": map[string]interface{}{
+				"name":      m.cfg.ServiceName + "-webhook",
+				"namespace": m.cfg.Namespace,
+			},
+			"spec": map[string]interface{}{
+				"secretName": secretName,
+				"dnsNames":   dnsNames,
+				"issuerRef": map[string]interface{}{
+					"name": m.cfg.Issuer,
+					"kind": "Issuer",
+				},
+			},
+		},
+	}
+
+	_, err := m.dynamic.Resource(certificatesGVR).Namespace(m.cfg.Namespace).Create(ctx, cert, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// waitForCertManagerSecret polls for secretName to appear with populated
+// TLS data, since cert-manager issues the certificate asynchronously after
+// the Certificate resource is created.
+func (m *Manager) waitForCertManagerSecret(ctx context.Context, secretName string) ([]byte, error) {
+	deadline := time.Now().Add(certManagerSecretWaitTimeout)
+
+	for {
+		secret, err := m.client.CoreV1().Secrets(m.cfg.Namespace).Get(ctx, secretName, metav1.GetOptions{})
+		if err == nil {
+			if caPEM, certPEM, keyPEM, ok := certManagerSecretData(secret); ok {
+				if err := writeCertFiles(m.cfg.CertDir, certPEM, keyPEM); err != nil {
+					return nil, err
+				}
+				return caPEM, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for cert-manager Secret %s/%s", m.cfg.Namespace, secretName)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(certManagerSecretPollInterval):
+		}
+	}
+}
+
+// watchCertManagerSecret polls secretName for the renewals cert-manager
+// performs on its own schedule, rewriting tls.crt/tls.key and re-patching
+// the caBundle whenever the certificate data changes.
+func (m *Manager) watchCertManagerSecret(ctx context.Context, secretName string) {
+	defer close(m.done)
+
+	var lastCert []byte
+	ticker := time.NewTicker(certManagerWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		secret, err := m.client.CoreV1().Secrets(m.cfg.Namespace).Get(ctx, secretName, metav1.GetOptions{})
+		if err != nil {
+			log.Printf("certmanager: polling Secret %s/%s: %v", m.cfg.Namespace, secretName, err)
+			continue
+		}
+
+		caPEM, certPEM, keyPEM, ok := certManagerSecretData(secret)
+		if !ok || bytes.Equal(certPEM, lastCert) {
+			continue
+		}
+
+		if err := writeCertFiles(m.cfg.CertDir, certPEM, keyPEM); err != nil {
+			log.Printf("certmanager: writing renewed certificate: %v", err)
+			continue
+		}
+		if err := m.patchCABundle(ctx, caPEM); err != nil {
+			log.Printf("certmanager: patching renewed caBundle: %v", err)
+			continue
+		}
+
+		lastCert = certPEM
+	}
+}
+
+func certManagerSecretData(secret *corev1.Secret) (caPEM, certPEM, keyPEM []byte, ok bool) {
+	certPEM, hasCert := secret.Data[corev1.TLSCertKey]
+	keyPEM, hasKey := secret.Data[corev1.TLSPrivateKeyKey]
+	caPEM = secret.Data["ca.crt"]
+	if !hasCert || !hasKey || len(caPEM) == 0 {
+		return nil, nil, nil, false
+	}
+	return caPEM, certPEM, keyPEM, true
+}
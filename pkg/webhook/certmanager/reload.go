@@ -0,0 +1,158 @@
+package certmanager
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadInterval is the fallback period on which CertReloader re-reads its
+// certificate/key files even without a filesystem notification, so rotation
+// set in motion by Manager still takes effect if fsnotify misses an event
+// (e.g. an NFS mount that doesn't deliver inotify events, or a watch lost
+// across a container restart of a sidecar that writes the files).
+const reloadInterval = 60 * time.Second
+
+// CertReloader serves the current TLS certificate for a server's
+// tls.Config.GetCertificate, keeping it in sync with whatever Manager
+// rotates onto disk at certPath/keyPath. Without it, a server configured
+// with a static certFile/keyFile pair never notices a rotation and keeps
+// presenting an expiring (or already-expired) leaf until every handshake
+// fails.
+type CertReloader struct {
+	certPath string
+	keyPath  string
+
+	current atomic.Value // *tls.Certificate
+
+	watcher *fsnotify.Watcher
+	sighup  chan os.Signal
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewCertReloader loads the certificate at certPath/keyPath and starts a
+// background goroutine that reloads it on filesystem change, SIGHUP, or the
+// reloadInterval fallback timer.
+func NewCertReloader(certPath, keyPath string) (*CertReloader, error) {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS certificate: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("creating certificate watcher: %w", err)
+	}
+	for _, dir := range watchDirs(certPath, keyPath) {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("watching %s for certificate changes: %w", dir, err)
+		}
+	}
+
+	r := &CertReloader{
+		certPath: certPath,
+		keyPath:  keyPath,
+		watcher:  watcher,
+		sighup:   make(chan os.Signal, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	r.current.Store(&cert)
+
+	signal.Notify(r.sighup, syscall.SIGHUP)
+	go r.run()
+
+	return r, nil
+}
+
+// watchDirs returns the distinct parent directories of paths. Certificate
+// rotation (e.g. Manager's writeCertFiles, or a Kubernetes Secret mount)
+// typically replaces files via a directory-level rename/symlink swap, which
+// only a watch on the containing directory reliably observes.
+func watchDirs(paths ...string) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, p := range paths {
+		dir := filepath.Dir(p)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func (r *CertReloader) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-r.sighup:
+			r.Reload()
+		case <-ticker.C:
+			r.Reload()
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if r.matchesWatchedFile(event.Name) {
+				r.Reload()
+			}
+		case _, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+func (r *CertReloader) matchesWatchedFile(name string) bool {
+	name = filepath.Clean(name)
+	return name == filepath.Clean(r.certPath) || name == filepath.Clean(r.keyPath)
+}
+
+// Reload re-reads the certificate and key from disk and, on success,
+// atomically swaps them in for the next TLS handshake; in-flight
+// connections keep whatever certificate they already negotiated with. A
+// parse failure is logged and the previously loaded certificate is kept.
+func (r *CertReloader) Reload() {
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		log.Printf("certmanager: failed to reload TLS certificate from %s/%s: %v; keeping previous certificate", r.certPath, r.keyPath, err)
+		return
+	}
+	r.current.Store(&cert)
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, _ := r.current.Load().(*tls.Certificate)
+	if cert == nil {
+		return nil, fmt.Errorf("no TLS certificate loaded")
+	}
+	return cert, nil
+}
+
+// Close stops the background reloader and releases its watcher/signal
+// registration.
+func (r *CertReloader) Close() {
+	close(r.stop)
+	signal.Stop(r.sighup)
+	r.watcher.Close()
+	<-r.done
+}
@@ -0,0 +1,94 @@
+package certmanager
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+
+	writeTestCertificate(t, certPath, keyPath, "first")
+
+	r, err := NewCertReloader(certPath, keyPath)
+	require.NoError(t, err)
+	defer r.Close()
+
+	initial, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	initialLeaf, err := x509.ParseCertificate(initial.Certificate[0])
+	require.NoError(t, err)
+	require.Equal(t, "first", initialLeaf.Subject.CommonName)
+
+	writeTestCertificate(t, certPath, keyPath, "second")
+	r.Reload()
+
+	reloaded, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	reloadedLeaf, err := x509.ParseCertificate(reloaded.Certificate[0])
+	require.NoError(t, err)
+	require.Equal(t, "second", reloadedLeaf.Subject.CommonName)
+}
+
+func TestCertReloader_ReloadKeepsPreviousCertOnParseFailure(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+
+	writeTestCertificate(t, certPath, keyPath, "first")
+
+	r, err := NewCertReloader(certPath, keyPath)
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.NoError(t, os.WriteFile(certPath, []byte("not a certificate"), 0o644))
+	r.Reload()
+
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	require.Equal(t, "first", leaf.Subject.CommonName)
+}
+
+func writeTestCertificate(t *testing.T, certPath, keyPath, commonName string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	require.NoError(t, os.WriteFile(certPath, certPEM, 0o644))
+	require.NoError(t, os.WriteFile(keyPath, keyPEM, 0o600))
+}
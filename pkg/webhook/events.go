@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"context"
+	"log"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// recordEvent creates a Kubernetes Event on pod so operators can
+// `kubectl describe pod` and see why an architecture was (or wasn't)
+// pinned, instead of having to correlate webhook logs by hand. It is
+// best-effort: a failure to create the event is logged but never changes
+// the admission response, and a nil k8sClient (e.g. in unit tests) is a
+// silent no-op.
+func (h *Handler) recordEvent(ctx context.Context, pod *corev1.Pod, eventType, reason, message string) {
+	if h.k8sClient == nil {
+		return
+	}
+
+	namespace := pod.Namespace
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "archy-" + reason + "-",
+			Namespace:    namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			// pod.UID is deliberately omitted: for the pod-create path this
+			// webhook mutates, the apiserver assigns metadata.uid only after
+			// mutating admission completes, so it's always empty here.
+			// kubectl describe pod resolves events via a field selector that
+			// includes involvedObject.uid, so an event stored with the wrong
+			// (empty) UID would never be found; namespace+name+kind is
+			// enough to resolve the pod uniquely at event-creation time.
+			Kind:      "Pod",
+			Namespace: namespace,
+			Name:      pod.Name,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Source:         corev1.EventSource{Component: "archy-webhook"},
+	}
+
+	if _, err := h.k8sClient.CoreV1().Events(namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		log.Printf("Failed to record %s event for pod %s/%s: %v", reason, namespace, pod.Name, err)
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/lsdopen/archy/internal/credentials"
+	"github.com/lsdopen/archy/internal/metrics"
 	"github.com/lsdopen/archy/pkg/inspector"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -28,18 +32,70 @@ func init() {
 }
 
 type Handler struct {
-	inspector inspector.Inspector
-	k8sClient kubernetes.Interface
+	inspector    inspector.Inspector
+	k8sClient    kubernetes.Interface
+	credResolver *credentials.Resolver
+	policy       Policy
+	metrics      *metrics.Metrics
+
+	nsPolicyMu    sync.RWMutex
+	nsPolicyCache map[string]nsPolicyCacheEntry
+}
+
+// nsPolicyCacheTTL bounds how long effectivePolicy trusts a cached
+// namespacePolicyAnnotation lookup before re-fetching the Namespace, so a
+// burst of pods in the same namespace (the common case, e.g. a Deployment
+// scaling up) costs at most one Kubernetes API call per TTL instead of one
+// per pod admitted.
+const nsPolicyCacheTTL = 30 * time.Second
+
+// nsPolicyCacheEntry is a cached effectivePolicy result for one namespace.
+type nsPolicyCacheEntry struct {
+	policy Policy
+	expiry time.Time
 }
 
 func NewHandler(inspector inspector.Inspector, k8sClient kubernetes.Interface) *Handler {
 	return &Handler{
-		inspector: inspector,
-		k8sClient: k8sClient,
+		inspector:     inspector,
+		k8sClient:     k8sClient,
+		credResolver:  credentials.NewResolver(k8sClient),
+		metrics:       metrics.NewMetrics(),
+		nsPolicyCache: make(map[string]nsPolicyCacheEntry),
 	}
 }
 
+// Metrics returns h's Prometheus registry, e.g. so a caller can serve it on
+// /metrics alongside this Handler's /mutate.
+func (h *Handler) Metrics() *metrics.Metrics {
+	return h.metrics
+}
+
+// NewHandlerWithPolicy creates a Handler that falls back to policy, rather
+// than unconditionally denying the pod, when it can't determine an image's
+// supported platforms.
+func NewHandlerWithPolicy(inspector inspector.Inspector, k8sClient kubernetes.Interface, policy Policy) *Handler {
+	h := NewHandler(inspector, k8sClient)
+	h.policy = policy
+	return h
+}
+
+// NewHandlerWithCredentialResolver creates a Handler that authenticates
+// registry requests via credResolver instead of a bare
+// credentials.NewResolver(k8sClient), so callers can wire in a static docker
+// config, cloud-provider workload identity (cloud.Matcher), and/or
+// registry-pattern Secret references (credentials.SecretCredentialProvider)
+// before any pod is admitted.
+func NewHandlerWithCredentialResolver(inspector inspector.Inspector, k8sClient kubernetes.Interface, policy Policy, credResolver *credentials.Resolver) *Handler {
+	h := NewHandlerWithPolicy(inspector, k8sClient, policy)
+	h.credResolver = credResolver
+	return h
+}
+
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	defer func() { h.metrics.RecordAdmissionDuration(time.Since(start)) }()
+
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -67,6 +123,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Expected v1.AdmissionReview", http.StatusBadRequest)
 			return
 		}
+		h.metrics.RecordAdmissionRequest("v1")
 		responseAdmissionReview := &admissionv1.AdmissionReview{}
 		responseAdmissionReview.SetGroupVersionKind(*gvk)
 		responseAdmissionReview.Response = h.mutate(r.Context(), requestedAdmissionReview)
@@ -88,6 +145,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) mutate(ctx context.Context, ar *admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+	start := time.Now()
 	req := ar.Request
 	var pod corev1.Pod
 	if err := json.Unmarshal(req.Object.Raw, &pod); err != nil {
@@ -97,6 +155,11 @@ func (h *Handler) mutate(ctx context.Context, ar *admissionv1.AdmissionReview) *
 			},
 		}
 	}
+	if pod.Namespace == "" {
+		// Raw pod objects don't always carry metadata.namespace; the
+		// AdmissionRequest always does.
+		pod.Namespace = req.Namespace
+	}
 
 	// 1. Check if nodeSelector is already present
 	if len(pod.Spec.NodeSelector) > 0 {
@@ -108,7 +171,25 @@ func (h *Handler) mutate(ctx context.Context, ar *admissionv1.AdmissionReview) *
 		}
 	}
 
-	// 2. Collect all images
+	// 2. Per-pod overrides take priority over any registry inspection.
+	if pod.Annotations[podSkipAnnotation] == "true" {
+		h.recordEvent(ctx, &pod, corev1.EventTypeNormal, "PlatformPinningSkipped",
+			fmt.Sprintf("Skipped by pod annotation %s=true", podSkipAnnotation))
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+			Result: &metav1.Status{
+				Message: fmt.Sprintf("Skipped by pod annotation %s=true", podSkipAnnotation),
+			},
+		}
+	}
+
+	if arch := pod.Annotations[podArchAnnotation]; arch != "" {
+		h.recordEvent(ctx, &pod, corev1.EventTypeNormal, "PlatformPinningForced",
+			fmt.Sprintf("Pinned to %s by pod annotation %s=%s", arch, podArchAnnotation, arch))
+		return h.patchResponse(arch, time.Since(start))
+	}
+
+	// 3. Collect all images
 	images := []string{}
 	for _, c := range pod.Spec.Containers {
 		images = append(images, c.Image)
@@ -117,20 +198,16 @@ func (h *Handler) mutate(ctx context.Context, ar *admissionv1.AdmissionReview) *
 		images = append(images, c.Image)
 	}
 
-	// 3. Inspect images and find intersection
+	// 4. Inspect images and find intersection
 	// Pass pod authentication details for private registry access
 	commonPlatforms, err := h.getCommonPlatforms(ctx, images, req.Namespace, pod.Spec.ImagePullSecrets, pod.Spec.ServiceAccountName)
 	if err != nil {
-		// Fail open or closed? Usually fail closed if we can't determine arch to be safe,
-		// but for now let's return an error status.
-		return &admissionv1.AdmissionResponse{
-			Result: &metav1.Status{
-				Message: fmt.Sprintf("Failed to inspect images: %v", err),
-			},
-		}
+		return h.handleInspectionError(ctx, &pod, err, time.Since(start))
 	}
 
 	if len(commonPlatforms) == 0 {
+		h.recordEvent(ctx, &pod, corev1.EventTypeWarning, "NoCommonPlatform",
+			"Images have no common supported platform (OS/Arch)")
 		return &admissionv1.AdmissionResponse{
 			Allowed: false,
 			Result: &metav1.Status{
@@ -139,7 +216,7 @@ func (h *Handler) mutate(ctx context.Context, ar *admissionv1.AdmissionReview) *
 		}
 	}
 
-	// 4. If multiple platforms are supported (e.g. Multi-arch), allow scheduler to decide
+	// 5. If multiple platforms are supported (e.g. Multi-arch), allow scheduler to decide
 	if len(commonPlatforms) > 1 {
 		return &admissionv1.AdmissionResponse{
 			Allowed: true,
@@ -149,11 +226,118 @@ func (h *Handler) mutate(ctx context.Context, ar *admissionv1.AdmissionReview) *
 		}
 	}
 
-	// 5. Exactly one common platform -> Patch
+	// 6. Exactly one common platform -> Patch
 	target := commonPlatforms[0]
+	h.recordEvent(ctx, &pod, corev1.EventTypeNormal, "PlatformPinned",
+		fmt.Sprintf("Pinned to %s: the only platform common to all images", target.Architecture))
+	return h.patchResponse(target.Architecture, time.Since(start))
+}
+
+// handleInspectionError turns a getCommonPlatforms failure into an
+// AdmissionResponse according to the effective Policy for pod's namespace,
+// instead of unconditionally denying the pod.
+func (h *Handler) handleInspectionError(ctx context.Context, pod *corev1.Pod, err error, duration time.Duration) *admissionv1.AdmissionResponse {
+	class := classifyError(err)
+	action := h.effectivePolicy(ctx, pod.Namespace).action(class)
+
+	switch action.Kind {
+	case FailOpen:
+		h.recordEvent(ctx, pod, corev1.EventTypeWarning, "RegistryInspectionFailed",
+			fmt.Sprintf("Failed to inspect images (%v); admitting unpatched per fail-open policy", err))
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+			Result: &metav1.Status{
+				Message: fmt.Sprintf("Failed to inspect images: %v; admitted unpatched per fail-open policy", err),
+			},
+		}
+	case Assume:
+		h.recordEvent(ctx, pod, corev1.EventTypeWarning, "RegistryInspectionFailed",
+			fmt.Sprintf("Failed to inspect images (%v); assuming %s per policy", err, action.Arch))
+		return h.patchResponse(action.Arch, duration)
+	default: // FailClosed
+		h.recordEvent(ctx, pod, corev1.EventTypeWarning, "RegistryInspectionFailed",
+			fmt.Sprintf("Failed to inspect images (%v); denying per fail-closed policy", err))
+		return &admissionv1.AdmissionResponse{
+			Result: &metav1.Status{
+				Message: fmt.Sprintf("Failed to inspect images: %v", err),
+			},
+		}
+	}
+}
+
+// effectivePolicy returns h.policy with every field overridden to a single
+// blanket action when namespace carries namespacePolicyAnnotation. Lookup
+// failures (no k8sClient, namespace not found, annotation unset or
+// unparseable) fall back to h.policy unchanged.
+//
+// The resolved policy is cached per namespace for nsPolicyCacheTTL so that a
+// burst of admission requests in the same namespace doesn't turn into a
+// Namespaces().Get per pod: namespace policy annotations change rarely
+// enough that a short TTL is indistinguishable from live lookups to an
+// operator, but saves hammering the API server during a scale-up.
+func (h *Handler) effectivePolicy(ctx context.Context, namespace string) Policy {
+	if h.k8sClient == nil {
+		return h.policy
+	}
+
+	if policy, ok := h.cachedNSPolicy(namespace); ok {
+		return policy
+	}
+
+	policy := h.lookupNSPolicy(ctx, namespace)
+
+	h.nsPolicyMu.Lock()
+	h.nsPolicyCache[namespace] = nsPolicyCacheEntry{policy: policy, expiry: time.Now().Add(nsPolicyCacheTTL)}
+	h.nsPolicyMu.Unlock()
+
+	return policy
+}
+
+func (h *Handler) cachedNSPolicy(namespace string) (Policy, bool) {
+	h.nsPolicyMu.RLock()
+	defer h.nsPolicyMu.RUnlock()
+
+	entry, ok := h.nsPolicyCache[namespace]
+	if !ok || time.Now().After(entry.expiry) {
+		return Policy{}, false
+	}
+	return entry.policy, true
+}
+
+// lookupNSPolicy performs the uncached Namespaces().Get lookup behind
+// effectivePolicy's cache.
+func (h *Handler) lookupNSPolicy(ctx context.Context, namespace string) Policy {
+	ns, err := h.k8sClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return h.policy
+	}
+
+	value := ns.Annotations[namespacePolicyAnnotation]
+	if value == "" {
+		return h.policy
+	}
+
+	action, err := ParsePolicyAction(value)
+	if err != nil {
+		return h.policy
+	}
+
+	return Policy{
+		OnRegistryError: action,
+		OnRateLimit:     action,
+		OnAuthError:     action,
+		OnNotFound:      action,
+	}
+}
+
+// patchResponse builds the JSONPatch AdmissionResponse that constrains a pod
+// to arch via kubernetes.io/arch. Callers must have already confirmed the
+// pod has no nodeSelector of its own, so "add" is always safe.
+func (h *Handler) patchResponse(arch string, duration time.Duration) *admissionv1.AdmissionResponse {
+	h.metrics.RecordMutation("pod", arch, true, duration)
 
 	nodeSelector := map[string]string{
-		"kubernetes.io/arch": target.Architecture,
+		"kubernetes.io/arch": arch,
 	}
 
 	// We need to construct the patch carefully.
@@ -193,35 +377,56 @@ func (h *Handler) getCommonPlatforms(ctx context.Context, images []string, names
 		return nil, nil
 	}
 
-	// Build authentication keychain from pod's imagePullSecrets
-	keychain, err := inspector.BuildKeychain(ctx, h.k8sClient, namespace, imagePullSecrets, serviceAccountName)
-	if err != nil {
-		return nil, fmt.Errorf("building keychain: %w", err)
-	}
-
-	// Create remote options with authentication
-	opts := []remote.Option{remote.WithAuthFromKeychain(keychain)}
-
-	// Get platforms for first image
-	firstImagePlatforms, err := h.inspector.GetSupportedPlatforms(ctx, images[0], opts...)
-	if err != nil {
-		return nil, err
-	}
-
-	common := firstImagePlatforms
+	var common []inspector.Platform
+	for i, img := range images {
+		opts, err := h.authOptions(ctx, namespace, imagePullSecrets, serviceAccountName, img)
+		if err != nil {
+			return nil, err
+		}
 
-	// Intersect with rest
-	for _, img := range images[1:] {
 		platforms, err := h.inspector.GetSupportedPlatforms(ctx, img, opts...)
 		if err != nil {
 			return nil, err
 		}
+
+		if i == 0 {
+			common = platforms
+			continue
+		}
 		common = intersect(common, platforms)
 	}
 
 	return common, nil
 }
 
+// authOptions builds the remote.Option used to authenticate against img's
+// registry. It first asks credResolver to resolve a credential (pod/service
+// account imagePullSecrets, a static config, cloud workload identity, or a
+// registered CredentialProvider); failing that, it falls back to the
+// existing k8schain-based keychain built directly from the pod's
+// imagePullSecrets, so clusters that haven't adopted credResolver's sources
+// keep working unchanged.
+func (h *Handler) authOptions(ctx context.Context, namespace string, imagePullSecrets []corev1.LocalObjectReference, serviceAccountName, img string) ([]remote.Option, error) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		Spec: corev1.PodSpec{
+			ImagePullSecrets:   imagePullSecrets,
+			ServiceAccountName: serviceAccountName,
+		},
+	}
+
+	if cred, err := h.credResolver.ResolveCredentials(pod, img); err == nil && cred != nil {
+		return []remote.Option{remote.WithAuthFromKeychain(credentials.Keychain(cred))}, nil
+	}
+
+	keychain, err := inspector.BuildKeychain(ctx, h.k8sClient, namespace, imagePullSecrets, serviceAccountName)
+	if err != nil {
+		return nil, fmt.Errorf("building keychain: %w", err)
+	}
+
+	return []remote.Option{remote.WithAuthFromKeychain(keychain)}, nil
+}
+
 func intersect(a, b []inspector.Platform) []inspector.Platform {
 	var result []inspector.Platform
 	for _, pa := range a {
@@ -235,5 +440,5 @@ func intersect(a, b []inspector.Platform) []inspector.Platform {
 }
 
 func isCompatible(a, b inspector.Platform) bool {
-	return a.Architecture == b.Architecture
+	return a.CompatibleWith(b)
 }
@@ -3,21 +3,30 @@ package webhook
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/lsdopen/archy/internal/credentials"
 	"github.com/lsdopen/archy/pkg/inspector"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 // MockInspector implements inspector.Inspector for testing
 type MockInspector struct {
 	platforms map[string][]inspector.Platform
+	errs      map[string]error
 }
 
 func (m *MockInspector) GetSupportedPlatforms(ctx context.Context, image string, opts ...remote.Option) ([]inspector.Platform, error) {
+	if err, ok := m.errs[image]; ok {
+		return nil, err
+	}
 	return m.platforms[image], nil
 }
 
@@ -155,6 +164,174 @@ func TestMutate(t *testing.T) {
 	}
 }
 
+func TestMutate_PodAnnotations(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		expectAllowed bool
+		expectPatch   bool
+		expectedPatch string
+	}{
+		{
+			name:          "skip annotation bypasses inspection",
+			annotations:   map[string]string{podSkipAnnotation: "true"},
+			expectAllowed: true,
+			expectPatch:   false,
+		},
+		{
+			name:          "arch annotation forces pin without inspection",
+			annotations:   map[string]string{podArchAnnotation: "arm64"},
+			expectAllowed: true,
+			expectPatch:   true,
+			expectedPatch: `"kubernetes.io/arch":"arm64"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No images and no mock errors configured: if the handler tried
+			// to inspect anything here it would either crash or deny, so a
+			// pass confirms the annotation short-circuited inspection.
+			inspector := &MockInspector{}
+			handler := NewHandler(inspector, nil)
+
+			pod := corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Image: "app:unused"}},
+				},
+			}
+			podBytes, _ := json.Marshal(pod)
+			ar := &admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					UID:    "123",
+					Object: runtime.RawExtension{Raw: podBytes},
+				},
+			}
+
+			resp := handler.mutate(context.Background(), ar)
+
+			if resp.Allowed != tt.expectAllowed {
+				t.Errorf("Expected Allowed=%v, got %v. Message: %v", tt.expectAllowed, resp.Allowed, resp.Result.Message)
+			}
+			if tt.expectPatch && resp.Patch == nil {
+				t.Error("Expected patch, got nil")
+			} else if !tt.expectPatch && resp.Patch != nil {
+				t.Errorf("Expected no patch, got %s", string(resp.Patch))
+			}
+			if tt.expectPatch && tt.expectedPatch != "" && !contains(string(resp.Patch), tt.expectedPatch) {
+				t.Errorf("Expected patch to contain %s, got %s", tt.expectedPatch, string(resp.Patch))
+			}
+		})
+	}
+}
+
+func TestMutate_RegistryErrorPolicy(t *testing.T) {
+	registryErr := &transport.Error{StatusCode: http.StatusTooManyRequests}
+
+	tests := []struct {
+		name          string
+		policy        Policy
+		expectAllowed bool
+		expectPatch   bool
+		expectedPatch string
+	}{
+		{
+			name:          "default policy fails closed",
+			policy:        Policy{},
+			expectAllowed: false,
+		},
+		{
+			name:          "fail-open admits unpatched",
+			policy:        Policy{OnRateLimit: PolicyAction{Kind: FailOpen}},
+			expectAllowed: true,
+			expectPatch:   false,
+		},
+		{
+			name:          "assume pins the configured architecture",
+			policy:        Policy{OnRateLimit: PolicyAction{Kind: Assume, Arch: "arm64"}},
+			expectAllowed: true,
+			expectPatch:   true,
+			expectedPatch: `"kubernetes.io/arch":"arm64"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inspector := &MockInspector{errs: map[string]error{"app:flaky": registryErr}}
+			handler := NewHandlerWithPolicy(inspector, nil, tt.policy)
+
+			pod := corev1.Pod{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "app:flaky"}}},
+			}
+			podBytes, _ := json.Marshal(pod)
+			ar := &admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					UID:    "123",
+					Object: runtime.RawExtension{Raw: podBytes},
+				},
+			}
+
+			resp := handler.mutate(context.Background(), ar)
+
+			if resp.Allowed != tt.expectAllowed {
+				t.Errorf("Expected Allowed=%v, got %v. Message: %v", tt.expectAllowed, resp.Allowed, resp.Result.Message)
+			}
+			if tt.expectPatch && resp.Patch == nil {
+				t.Error("Expected patch, got nil")
+			} else if !tt.expectPatch && resp.Patch != nil {
+				t.Errorf("Expected no patch, got %s", string(resp.Patch))
+			}
+			if tt.expectPatch && tt.expectedPatch != "" && !contains(string(resp.Patch), tt.expectedPatch) {
+				t.Errorf("Expected patch to contain %s, got %s", tt.expectedPatch, string(resp.Patch))
+			}
+		})
+	}
+}
+
+func TestMutate_NamespacePolicyOverride(t *testing.T) {
+	registryErr := &transport.Error{StatusCode: http.StatusUnauthorized}
+
+	k8sClient := fake.NewSimpleClientset(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "team-a",
+			Annotations: map[string]string{namespacePolicyAnnotation: "fail-open"},
+		},
+	})
+
+	inspector := &MockInspector{errs: map[string]error{"app:private": registryErr}}
+	handler := NewHandlerWithPolicy(inspector, k8sClient, Policy{OnAuthError: PolicyAction{Kind: FailClosed}})
+
+	pod := corev1.Pod{
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "app:private"}}},
+	}
+	podBytes, _ := json.Marshal(pod)
+	ar := &admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "123",
+			Namespace: "team-a",
+			Object:    runtime.RawExtension{Raw: podBytes},
+		},
+	}
+
+	resp := handler.mutate(context.Background(), ar)
+
+	if !resp.Allowed {
+		t.Errorf("Expected namespace override to fail open, got denied: %v", resp.Result.Message)
+	}
+}
+
+func TestNewHandlerWithCredentialResolver_UsesProvidedResolver(t *testing.T) {
+	k8sClient := fake.NewSimpleClientset()
+	credResolver := credentials.NewResolverWithProviders(k8sClient)
+
+	handler := NewHandlerWithCredentialResolver(&MockInspector{}, k8sClient, Policy{}, credResolver)
+
+	if handler.credResolver != credResolver {
+		t.Error("Expected handler to use the provided credential resolver")
+	}
+}
+
 func contains(s, substr string) bool {
 	// Simple helper, strings.Contains is fine but just to be explicit
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && (s[0:len(substr)] == substr || contains(s[1:], substr)))
@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// ActionKind is the kind of fallback Handler takes when it cannot establish
+// an image's supported platforms.
+type ActionKind int
+
+const (
+	// FailClosed denies the pod, the same as today's unconditional
+	// "inspect failed" behavior. It's first so ActionKind's zero value
+	// (and therefore the zero Policy/PolicyAction) fails closed, matching
+	// this file's documented zero-Policy behavior below.
+	FailClosed ActionKind = iota
+	// FailOpen allows the pod through unpatched, leaving scheduling to
+	// Kubernetes' default (unconstrained) behavior.
+	FailOpen
+	// Assume pins the pod to PolicyAction.Arch instead of giving up,
+	// trading a possibly-wrong guess for keeping the pod schedulable.
+	Assume
+)
+
+func (k ActionKind) String() string {
+	switch k {
+	case FailOpen:
+		return "fail-open"
+	case FailClosed:
+		return "fail-closed"
+	case Assume:
+		return "assume"
+	default:
+		return "unknown"
+	}
+}
+
+// PolicyAction is one configured entry of a Policy: what Handler should do
+// when a particular class of registry error prevents it from determining a
+// pod's supported platforms.
+type PolicyAction struct {
+	Kind ActionKind
+	// Arch is the architecture to pin when Kind is Assume. Ignored
+	// otherwise.
+	Arch string
+}
+
+// ParsePolicyAction parses a CLI-flag or annotation value into a
+// PolicyAction: "fail-open", "fail-closed", or "assume=<arch>".
+func ParsePolicyAction(s string) (PolicyAction, error) {
+	if arch, ok := cutPrefix(s, "assume="); ok {
+		if arch == "" {
+			return PolicyAction{}, fmt.Errorf("assume policy requires an architecture, e.g. assume=arm64")
+		}
+		return PolicyAction{Kind: Assume, Arch: arch}, nil
+	}
+
+	switch s {
+	case "fail-open":
+		return PolicyAction{Kind: FailOpen}, nil
+	case "fail-closed":
+		return PolicyAction{Kind: FailClosed}, nil
+	default:
+		return PolicyAction{}, fmt.Errorf("unknown policy action %q: want fail-open, fail-closed, or assume=<arch>", s)
+	}
+}
+
+// cutPrefix is strings.CutPrefix, inlined so this file doesn't require a Go
+// version newer than the rest of the module.
+func cutPrefix(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || s[:len(prefix)] != prefix {
+		return "", false
+	}
+	return s[len(prefix):], true
+}
+
+// Policy controls what Handler does when getCommonPlatforms can't determine
+// a pod's supported platforms, broken down by the class of registry error
+// encountered. The zero Policy fails closed on every class, matching the
+// handler's original, unconditional-deny behavior.
+type Policy struct {
+	// OnRegistryError is used for errors that don't match a more specific
+	// class below (network failures, malformed manifests, timeouts, ...).
+	OnRegistryError PolicyAction
+	// OnRateLimit is used when the registry responds 429 Too Many Requests.
+	OnRateLimit PolicyAction
+	// OnAuthError is used when the registry responds 401 Unauthorized or
+	// 403 Forbidden, e.g. a pull secret that can't be reached or has
+	// expired.
+	OnAuthError PolicyAction
+	// OnNotFound is used when the registry responds 404, e.g. a typo'd tag
+	// or an image that was deleted after the pod spec was written.
+	OnNotFound PolicyAction
+}
+
+// namespacePolicyAnnotation, set on a Namespace, overrides every field of
+// Policy for pods admitted in that namespace with a single blanket action:
+// "archy.lsdopen.io/policy=fail-open" or "archy.lsdopen.io/policy=fail-closed".
+const namespacePolicyAnnotation = "archy.lsdopen.io/policy"
+
+// podSkipAnnotation, set on a Pod, opts it out of platform pinning entirely:
+// "archy.lsdopen.io/skip=true".
+const podSkipAnnotation = "archy.lsdopen.io/skip"
+
+// podArchAnnotation, set on a Pod, pins it to the given architecture without
+// inspecting any image, e.g. "archy.lsdopen.io/arch=arm64".
+const podArchAnnotation = "archy.lsdopen.io/arch"
+
+// errorClass classifies the errors getCommonPlatforms can return so Handler
+// can apply the matching Policy field.
+type errorClass int
+
+const (
+	classRegistryError errorClass = iota
+	classRateLimit
+	classAuthError
+	classNotFound
+)
+
+// classifyError inspects err for an HTTP status code from the registry
+// (wrapped in a *transport.Error by go-containerregistry) and buckets it
+// into the error classes Policy distinguishes. Errors without a status code
+// attached, e.g. DNS failures or context deadlines, fall back to
+// classRegistryError.
+func classifyError(err error) errorClass {
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		switch terr.StatusCode {
+		case http.StatusTooManyRequests:
+			return classRateLimit
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return classAuthError
+		case http.StatusNotFound:
+			return classNotFound
+		}
+	}
+	return classRegistryError
+}
+
+// action resolves the PolicyAction p configures for class.
+func (p Policy) action(class errorClass) PolicyAction {
+	switch class {
+	case classRateLimit:
+		return p.OnRateLimit
+	case classAuthError:
+		return p.OnAuthError
+	case classNotFound:
+		return p.OnNotFound
+	default:
+		return p.OnRegistryError
+	}
+}
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+func TestParsePolicyAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    PolicyAction
+		wantErr bool
+	}{
+		{name: "fail-open", input: "fail-open", want: PolicyAction{Kind: FailOpen}},
+		{name: "fail-closed", input: "fail-closed", want: PolicyAction{Kind: FailClosed}},
+		{name: "assume", input: "assume=arm64", want: PolicyAction{Kind: Assume, Arch: "arm64"}},
+		{name: "assume without arch", input: "assume=", wantErr: true},
+		{name: "unknown", input: "bogus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePolicyAction(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePolicyAction(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want errorClass
+	}{
+		{name: "rate limited", err: &transport.Error{StatusCode: http.StatusTooManyRequests}, want: classRateLimit},
+		{name: "unauthorized", err: &transport.Error{StatusCode: http.StatusUnauthorized}, want: classAuthError},
+		{name: "forbidden", err: &transport.Error{StatusCode: http.StatusForbidden}, want: classAuthError},
+		{name: "not found", err: &transport.Error{StatusCode: http.StatusNotFound}, want: classNotFound},
+		{name: "server error falls back to registry error", err: &transport.Error{StatusCode: http.StatusInternalServerError}, want: classRegistryError},
+		{name: "stringified status is not a transport.Error", err: errors.New("fetching image: " + (&transport.Error{StatusCode: http.StatusNotFound}).Error()), want: classRegistryError},
+		{name: "unrelated error", err: errors.New("context deadline exceeded"), want: classRegistryError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}